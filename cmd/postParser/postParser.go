@@ -2,6 +2,10 @@
 package main
 
 import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -10,8 +14,11 @@ import (
 	"github.com/vynovikov/postParser/internal/adapters/application"
 	"github.com/vynovikov/postParser/internal/adapters/driven/rpc"
 	"github.com/vynovikov/postParser/internal/adapters/driven/store"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
 	"github.com/vynovikov/postParser/internal/adapters/driver/tp"
 	"github.com/vynovikov/postParser/internal/adapters/driver/tps"
+	"github.com/vynovikov/postParser/internal/adapters/driver/tpu"
+	"github.com/vynovikov/postParser/internal/config"
 	"github.com/vynovikov/postParser/internal/logger"
 )
 
@@ -19,39 +26,125 @@ var (
 	wgMain sync.WaitGroup
 )
 
+// Receiver is common part of HTTP, HTTPS and Unix socket receivers
+type Receiver interface {
+	Run()
+	Stop(*sync.WaitGroup)
+}
+
 func main() {
+	c, err := config.Load(os.Args[1:])
+	if err != nil {
+		logger.L.Fatalln(err)
+	}
+	if err = run(c); err != nil {
+		logger.L.Fatalln(err)
+	}
+}
+
+// run starts receivers enabled by c and blocks until app is stopped.
+// Returns error if any receiver cannot be started, whatever is started already is stopped then.
+// Things are stopped in reverse order of starting, metrics are served until the very end
+func run(c config.Config) error {
+	auth, err := receiver.NewAuth(c.Auth)
+	if err != nil {
+		return err
+	}
+
+	limits := receiver.NewLimiter(c.Limits)
+	if len(c.MetricsAddr) > 0 {
+		metrics, err := serveMetrics(c.MetricsAddr)
+		if err != nil {
+			return err
+		}
+		defer metrics.Close()
+	}
+
 	t := rpc.NewTransmitter(nil)
+	t.Routes = c.Routes
 	s := store.NewStore()
 
 	app, done := application.NewAppFull(s, t)
 
-	tpR := tp.NewTpReceiver(app)
-	tpsR := tps.NewTpsReceiver(app)
+	rs := make([]Receiver, 0, 3)
+
+	if c.HTTP.Enabled {
+		tpR, err := tp.NewTpReceiver(app, c.HTTP.Addr, c.HTTP.Proxy, c.Timeouts.Durations())
+		if err != nil {
+			stopReceivers(rs)
+			return err
+		}
+		tpR.Routes, tpR.Auth, tpR.Limits, tpR.Upload, tpR.Decode = c.Routes, auth, limits, c.Upload, c.Decode
+		rs = append(rs, tpR)
+	}
+	if c.HTTPS.Enabled {
+		tpsR, err := tps.NewTpsReceiver(app, c.HTTPS, c.Timeouts.Durations())
+		if err != nil {
+			stopReceivers(rs)
+			return err
+		}
+		tpsR.Routes, tpsR.Auth, tpsR.Limits, tpsR.Upload, tpsR.Decode = c.Routes, auth, limits, c.Upload, c.Decode
+		rs = append(rs, tpsR)
+	}
+	if c.Unix.Enabled {
+		tpuR, err := tpu.NewTpuReceiver(app, c.Unix.Path, c.Unix.FileMode(), c.Unix.Proxy, c.Timeouts.Durations())
+		if err != nil {
+			stopReceivers(rs)
+			return err
+		}
+		tpuR.Routes, tpuR.Auth, tpuR.Limits, tpuR.Upload, tpuR.Decode = c.Routes, auth, limits, c.Upload, c.Decode
+		rs = append(rs, tpuR)
+	}
 
-	go SignalListen(tpR, tpsR, app)
+	go SignalListen(rs, app)
 	go app.Start()
-	go tpR.Run()
-	go tpsR.Run()
+	for _, r := range rs {
+		go r.Run()
+	}
 
 	<-done
 	logger.L.Errorln("postParser is interrupted")
+
+	return nil
+}
+
+// serveMetrics serves expvar metrics at addr until returned listener is closed
+func serveMetrics(addr string) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("in main.serveMetrics cannot listen %q: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go http.Serve(l, mux)
+
+	return l, nil
 }
 
 // SignalListen listens for Interrupt signal, when receiving one invokes stop function
-func SignalListen(tpR tp.TpReceiver, tpsR tps.TpsReceiver, app application.Application) {
+func SignalListen(rs []Receiver, app application.Application) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT)
 	<-sigChan
-	Stop(tpR, tpsR, app)
+	Stop(rs, app)
 
 }
 
-// Stop sets stopping flog and invokes stop goroutines
-func Stop(tpR tp.TpReceiver, tpsR tps.TpsReceiver, app application.Application) {
+// Stop sets stopping flog and invokes stop goroutines.
+// Receivers started after app are stopped before it, so nothing is fed to app when its chanIn is closed
+func Stop(rs []Receiver, app application.Application) {
 	app.SetStopping()
-	wgMain.Add(2)
-	go tpR.Stop(&wgMain)
-	go tpsR.Stop(&wgMain)
+	stopReceivers(rs)
+	app.ChainInClose() // receivers leave chanIn open, all of them are stopped already
+	app.Stop()         // closes done in the end
+}
+
+// stopReceivers stops rs and waits for them to finish
+func stopReceivers(rs []Receiver) {
+	wgMain.Add(len(rs))
+	for _, r := range rs {
+		go r.Stop(&wgMain)
+	}
 	wgMain.Wait()
-	app.Stop() // closes done in the end
 }
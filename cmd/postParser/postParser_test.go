@@ -1,11 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
+	"math/big"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,6 +30,7 @@ import (
 	tologger "github.com/vynovikov/postParser/internal/adapters/driven/rpc/tologger/pb"
 	"github.com/vynovikov/postParser/internal/adapters/driven/rpc/tosaver/pb"
 	tosaver "github.com/vynovikov/postParser/internal/adapters/driven/rpc/tosaver/pb"
+	"github.com/vynovikov/postParser/internal/config"
 	"github.com/vynovikov/postParser/internal/logger"
 	"github.com/vynovikov/postParser/internal/repo"
 
@@ -44,8 +57,13 @@ var (
 	baseServer2 *grpc.Server
 	gReqs       []repo.GRequest
 	gotLast     int
+	tlsDir      string // directory of x509 pair HTTPS receiver loads
 )
 
+// preamble takes place of the request header at the beginning of the body.
+// Keeps chunk edges at the same offsets they had when the header was fed to application along with the body
+const preamble = "This is the preamble. It is to be ignored, though it is a handy place for composition agents to include an explanatory note to non-MIME conformant readers. Pads the header length\r\n"
+
 type SaverServer struct {
 	tosaver.SaverServer
 }
@@ -55,7 +73,7 @@ type LogServer struct {
 
 func (s *mainSuite) SetupTest() {
 	numChan = make(chan int)
-	reqChan = make(chan repo.GRequest)
+	reqChan = make(chan repo.GRequest, 100)
 	resChan = make(chan []repo.GRequest, 5)
 	sigChan = make(chan os.Signal, 1)
 
@@ -81,62 +99,48 @@ func (s *mainSuite) SetupTest() {
 	go baseServer1.Serve(lisSaver)
 	go baseServer2.Serve(lisLogger)
 
-	// copying tls folder from root folder to cmd/postParser folder
-
-	srcDir := "../../tls"
-	dstDir := "tls"
-
-	if _, err := os.Stat(dstDir); os.IsNotExist(err) {
+	// x509 pair of HTTPS receiver is generated for every test
 
-		err = os.Mkdir(dstDir, 0777)
-		if err != nil {
-			logger.L.Errorf("in main.SetupTest failed create \"%s\" subfolder inside test folder: %v\n", dstDir, err)
-		}
+	tlsDir = s.T().TempDir()
+	if err := writePair(tlsDir); err != nil {
+		logger.L.Errorf("in main.SetupTest failed to write x509 pair: %v\n", err)
 	}
+}
 
-	entries, err := os.ReadDir(srcDir)
+// writePair writes self-signed x509 pair into cert.pem and key.pem files of dir
+func writePair(dir string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		logger.L.Errorf("in main.SetupTest failed to read \"../../tls\": %v", err)
-	}
-	for _, entry := range entries {
-		sourcePath := filepath.Join(srcDir, entry.Name())
-		destPath := filepath.Join(dstDir, entry.Name())
-		err = Copy(sourcePath, destPath)
+		return err
 	}
-}
-func (s *mainSuite) TearDownTest() {
-	err := os.RemoveAll("tls")
-	if err != nil {
-		logger.L.Errorf("in main.TeatdownTest failed to remove \"tls\" folder: %v\n", err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 	}
-}
-
-func Copy(s, d string) error {
-	dstFile, err := os.Create(d)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
-
-	srcFile, err := os.Open(s)
+	k, err := x509.MarshalECPrivateKey(key)
 	if err != nil {
 		return err
 	}
-	defer srcFile.Close()
-
-	_, err = io.Copy(dstFile, srcFile)
-	if err != nil {
+	if err = os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
 		return err
 	}
-
-	return nil
+	return os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: k}), 0600)
 }
 
 // Should be tested in docker container if OS is Windows
 func (s *mainSuite) TestBetterGracefulShutdown() {
 	var tMean, tDeviation float64
 	dt := make([]time.Duration, 0)
-	go main()
+	go runDefault()
 	go compose(numChan, reqChan, resChan)
 
 	tt := []struct {
@@ -147,7 +151,7 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 		dialErrorString       string
 		req                   []byte
 		resB                  []byte
-		res                   string
+		res                   repo.Result
 		readFromConnErrString string
 		wantGReqs             []repo.GRequest
 	}{
@@ -161,9 +165,10 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 5429\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"leon\"; filename=\"long.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -236,9 +241,10 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 5429\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"leon\"; filename=\"long.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -299,7 +305,11 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 					"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
 					"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 					"--------------------------c61fd8e07a9d3f9b--\r\n"),
-			res: "HTTP/1.1 200 OK\r\nContent-Length: 6\r\nContent-Type: text/html\r\n\r\n200 OK",
+			res: repo.Result{
+				Fields: []repo.ResultField{},
+				Files:  []repo.ResultFile{{Field: "leon", Name: "filename", Size: 100}},
+				Status: 200,
+			},
 			wantGReqs: []repo.GRequest{
 				{
 					RType:     repo.S,
@@ -422,9 +432,10 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 5429\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"leon\"; filename=\"long.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -485,7 +496,11 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 					"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
 					"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 					"--------------------------c61fd8e07a9d3f9b--\r\n"),
-			res: "HTTP/1.1 200 OK\r\nContent-Length: 6\r\nContent-Type: text/html\r\n\r\n200 OK",
+			res: repo.Result{
+				Fields: []repo.ResultField{},
+				Files:  []repo.ResultFile{{Field: "leon", Name: "filename", Size: 100}},
+				Status: 200,
+			},
 			wantGReqs: []repo.GRequest{
 				{
 					RType:     repo.S,
@@ -631,6 +646,16 @@ func (s *mainSuite) TestBetterGracefulShutdown() {
 	}
 }
 
+// runDefault runs app with default configuration having x509 pair of tlsDir. Listeners may be busy with app started by previous test
+func runDefault() {
+	c := config.New()
+	c.HTTPS.Cert, c.HTTPS.Key = filepath.Join(tlsDir, "cert.pem"), filepath.Join(tlsDir, "key.pem")
+
+	if err := run(c); err != nil {
+		logger.L.Errorln(err)
+	}
+}
+
 func castSIGINT(t float64) {
 	td := time.Duration(t)
 	timer := time.NewTimer(td)
@@ -642,7 +667,7 @@ func castSIGINT(t float64) {
 	p.Signal(syscall.SIGINT)
 }
 
-func jobFull(s *mainSuite, initialSleep, postSleep time.Duration, req []byte, name, res, readErrString string, wantGReqs []repo.GRequest, numChan chan int, resChan chan []repo.GRequest) time.Duration {
+func jobFull(s *mainSuite, initialSleep, postSleep time.Duration, req []byte, name string, res repo.Result, readErrString string, wantGReqs []repo.GRequest, numChan chan int, resChan chan []repo.GRequest) time.Duration {
 	var t time.Duration
 	resB := make([]byte, 0)
 	time.Sleep(initialSleep)
@@ -657,16 +682,24 @@ func jobFull(s *mainSuite, initialSleep, postSleep time.Duration, req []byte, na
 		return t
 	}
 	numChan <- len(wantGReqs)
-	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 25))
-	resB, err = ioutil.ReadAll(conn)
+	result := <-resChan
+
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 100)) // response is sent after transmission is finished
+	buf := &bytes.Buffer{}
+	hres, err := http.ReadResponse(bufio.NewReader(io.TeeReader(conn, buf)), nil)
+	if err == nil {
+		_, err = io.Copy(io.Discard, hres.Body)
+	}
 	if err != nil {
 		s.True(strings.Contains(err.Error(), readErrString))
 	}
-	result := <-resChan
+	resB = buf.Bytes()
 
+	// time is taken when response is read. Connection is closed after response if server is interrupted and is kept alive otherwise,
+	// so reading it till the end would take different time
 	t = time.Since(t0)
 
-	s.Equal(res, string(resB))
+	s.Equal(res, decodeResult(s, resB))
 
 	s.True(repo.IsOk(name, wantGReqs, result))
 
@@ -718,12 +751,52 @@ func jobBrief(s *mainSuite, name string, req []byte, wantGReqs []repo.GRequest,
 	numChan <- n
 	result := <-resChan
 	s.True(repo.IsOk(name, wantGReqs, result))
+
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 100))
+	resB, _ := ioutil.ReadAll(conn) // connection is kept alive, reading stops by deadline
+	got := decodeResult(s, resB)
+	s.Equal(http.StatusOK, got.Status)
+
+	wantFields, wantFiles, gotFields, gotFiles := make(map[string]bool), make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	for _, r := range wantGReqs {
+		if len(r.FileName) == 0 && !r.FileInfo && !r.FileData {
+			wantFields[r.FieldName] = true
+			continue
+		}
+		wantFiles[r.FieldName] = true
+	}
+	for _, f := range got.Fields {
+		gotFields[f.Name] = true
+	}
+	for _, f := range got.Files {
+		gotFiles[f.Field] = true
+	}
+	s.Equal(wantFields, gotFields)
+	s.Equal(wantFiles, gotFiles)
+}
+
+// decodeResult returns result from HTTP response. TS is checked and removed since it is unpredictable
+func decodeResult(s *mainSuite, resB []byte) repo.Result {
+	res := repo.Result{}
+
+	hres, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(resB)), nil)
+	if !s.NoError(err) {
+		return res
+	}
+	defer hres.Body.Close()
+
+	s.NoError(json.NewDecoder(hres.Body).Decode(&res))
+	s.NotEmpty(res.TS)
+
+	res.TS, res.Status = "", hres.StatusCode
+
+	return res
 }
 
 // TestWorkflow performs end to end testing of whole app
 func (s *mainSuite) TestWorkflow() {
 
-	go main()
+	go runDefault()
 	go compose(numChan, reqChan, resChan)
 
 	tt := []struct {
@@ -739,9 +812,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 325\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -758,13 +832,14 @@ func (s *mainSuite) TestWorkflow() {
 		{
 			name: "unary 2",
 			req: []byte(
-				"2POST / HTTP/1.1\r\n" +
+				"POST / HTTP/1.1\r\n" +
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 423\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -786,16 +861,63 @@ func (s *mainSuite) TestWorkflow() {
 			},
 		},
 
+		{
+			name: "urlencoded unary 2",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 23\r\n" +
+					"Content-Type: application/x-www-form-urlencoded\r\n" +
+					"\r\n" +
+					"alice=azaza&bob=bz%20bz"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "bob",
+					ByteChunk: []byte("bz bz"),
+				},
+			},
+		},
+
+		{
+			name: "JSON unary 2",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 44\r\n" +
+					"Content-Type: application/json\r\n" +
+					"\r\n" +
+					`{"alice": "azaza", "bob": 42, "tags": ["x"]}`),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "bob",
+					ByteChunk: []byte("42"),
+				},
+			},
+		},
+
 		{
 			name: "unary 3",
 			req: []byte(
-				"2POST / HTTP/1.1\r\n" +
+				"POST / HTTP/1.1\r\n" +
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 526\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -828,13 +950,14 @@ func (s *mainSuite) TestWorkflow() {
 		{
 			name: "unary 7",
 			req: []byte(
-				"2POST / HTTP/1.1\r\n" +
+				"POST / HTTP/1.1\r\n" +
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 933\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -903,9 +1026,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 568\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"short.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -924,6 +1048,362 @@ func (s *mainSuite) TestWorkflow() {
 			},
 		},
 
+		{
+			name: "PUT short file",
+			req: []byte(
+				"PUT /upload/short.txt HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 200\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+					"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "file",
+					FileName:  "short.txt",
+					ByteChunk: []byte(
+						"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+							"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111"),
+				},
+			},
+		},
+
+		{
+			name: "PUT unary 2",
+			req: []byte(
+				"PUT /upload HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 423\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					preamble +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"\r\n" +
+					"azaza\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "bob",
+					ByteChunk: []byte("bzbzbz"),
+				},
+			},
+		},
+
+		{
+			name: "PUT stream 2 part file",
+			req: []byte(
+				"PUT /upload/md_digits.txt HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 1010\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+					"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+					"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+					"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
+					"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
+					"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+					"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+					"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+					"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+					"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "file",
+					FileName:  "md_digits.txt",
+					FileInfo:  true,
+					IsFirst:   true,
+				},
+				{
+					FileData:  true,
+					FieldName: "file",
+					ByteChunk: []byte(
+						"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+							"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+							"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+							"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
+							"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
+							"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+							"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+							"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+							"8888888888888888888888888888888888888888888888888888888888888888888888888888888"),
+				},
+				{
+					FileData:  true,
+					IsLast:    true,
+					FieldName: "file",
+					ByteChunk: []byte(
+						"88888888888888888888\r\n" +
+							"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n"),
+				},
+			},
+		},
+
+		{
+			name: "nested files unary 3",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 489\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"\r\n" +
+					"azaza\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"files\"\r\n" +
+					"Content-Type: multipart/mixed; boundary=BbC04y\r\n" +
+					"\r\n" +
+					"--BbC04y\r\n" +
+					"Content-Disposition: file; filename=\"file1.txt\"\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--BbC04y\r\n" +
+					"Content-Disposition: file; filename=\"file2.txt\"\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"czczczcz\r\n" +
+					"--BbC04y--\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "files",
+					FileName:  "file1.txt",
+					ByteChunk: []byte("bzbzbz"),
+				},
+				{
+					FieldName: "files",
+					FileName:  "file2.txt",
+					ByteChunk: []byte("czczczcz"),
+				},
+			},
+		},
+
+		{
+			name: "unary 2 files having UTF-8 names",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 398\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"; filename=\"отчёт \\\"2024\\\" 😀.txt\"\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"azaza\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"; filename=\"file.txt\"; filename*=UTF-8''%E6%96%87%E4%BB%B6.txt\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					FileName:  "отчёт \"2024\" 😀.txt",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "bob",
+					FileName:  "文件.txt",
+					ByteChunk: []byte("bzbzbz"),
+				},
+			},
+		},
+
+		{
+			name: "unary 2 parts having part headers with parameters and extra fields",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 362\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"Content-Type: text/plain; charset=utf-8\r\n" +
+					"\r\n" +
+					"azaza\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"; filename=\"b.txt\"\r\n" +
+					"Content-Type: text/plain; charset=utf-8\r\n" +
+					"X-Origin: scanner\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("azaza"),
+				},
+				{
+					FieldName: "bob",
+					FileName:  "b.txt",
+					ByteChunk: []byte("bzbzbz"),
+				},
+			},
+		},
+
+		{
+			name: "unary 2 parts having Content-Transfer-Encoding",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 382\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"Content-Transfer-Encoding: quoted-printable\r\n" +
+					"\r\n" +
+					"caf=C3=A9 =\r\nazaza\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"; filename=\"b.txt\"\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"Content-Transfer-Encoding: base64\r\n" +
+					"\r\n" +
+					"YnpiemJ6\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte("café azaza"),
+				},
+				{
+					FieldName: "bob",
+					FileName:  "b.txt",
+					ByteChunk: []byte("bzbzbz"),
+				},
+			},
+		},
+
+		{
+			name: "header split in file name",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 1223\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"\r\n" +
+					strings.Repeat("a", 824) + "\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"; filename=\"мой файл 😀.txt\"\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"cindy\"\r\n" +
+					"\r\n" +
+					"czcz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte(strings.Repeat("a", 824)),
+				},
+				{
+					FieldName: "bob",
+					FileName:  "мой файл 😀.txt",
+					FileInfo:  true,
+					IsFirst:   true,
+				},
+				{
+					FileData:  true,
+					IsLast:    true,
+					FieldName: "bob",
+					ByteChunk: []byte("bzbzbz"),
+				},
+				{
+					FieldName: "cindy",
+					ByteChunk: []byte("czcz"),
+				},
+			},
+		},
+
+		{
+			name: "header split in extended file name",
+			req: []byte(
+				"POST / HTTP/1.1\r\n" +
+					"Host: localhost\r\n" +
+					"User-Agent: curl/7.75.0\r\n" +
+					"Accept: */*\r\n" +
+					"Content-Length: 1220\r\n" +
+					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+					"\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"alice\"\r\n" +
+					"\r\n" +
+					strings.Repeat("a", 817) + "\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"bob\"; filename*=UTF-8''%E6%96%87%E4%BB%B6.txt\r\n" +
+					"Content-Type: text/plain\r\n" +
+					"\r\n" +
+					"bzbzbz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b\r\n" +
+					"Content-Disposition: form-data; name=\"cindy\"\r\n" +
+					"\r\n" +
+					"czcz\r\n" +
+					"--------------------------c61fd8e07a9d3f9b--\r\n"),
+			wantGReqs: []repo.GRequest{
+				{
+					FieldName: "alice",
+					ByteChunk: []byte(strings.Repeat("a", 817)),
+				},
+				{
+					FieldName: "bob",
+					FileName:  "文件.txt",
+					FileInfo:  true,
+					IsFirst:   true,
+				},
+				{
+					FileData:  true,
+					IsLast:    true,
+					FieldName: "bob",
+					ByteChunk: []byte("bzbzbz"),
+				},
+				{
+					FieldName: "cindy",
+					ByteChunk: []byte("czcz"),
+				},
+			},
+		},
+
 		{
 			name: "unary short file + unary textfield",
 			req: []byte(
@@ -931,9 +1411,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 666\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"short.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -967,9 +1448,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1382\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"madelyn\"; filename=\"md_digits.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1023,9 +1505,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 2539\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"madelyn\"; filename=\"md_digits.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1126,9 +1609,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1481\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"madelyn\"; filename=\"md_digits.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1193,9 +1677,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1578\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"madelyn\"; filename=\"md_digits.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1269,9 +1754,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1578\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -1346,9 +1832,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 2638\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -1457,9 +1944,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 2735\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
@@ -1577,9 +2065,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 5429\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"leon\"; filename=\"long.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1758,9 +2247,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1059\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"short.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1802,9 +2292,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1157\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"short.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1857,9 +2348,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 2207\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"file1.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -1947,9 +2439,10 @@ func (s *mainSuite) TestWorkflow() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 1464\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"; filename=\"fake_boundary.txt\"\r\n" +
 					"Content-Type: text/plain\r\n" +
@@ -2188,8 +2681,72 @@ func add(res []repo.GRequest, r repo.GRequest) []repo.GRequest {
 	return res
 }
 
+// TestSaverDown tests that request routed to unreachable saver gets 502 and postParser keeps running
+func (s *mainSuite) TestSaverDown() {
+	c := config.New()
+	c.HTTP.Addr, c.HTTPS.Enabled = ":3010", false
+	c.Routes = repo.Routes{{Name: "down", Path: "/down", Saver: "127.0.0.1:1"}}
+	go func() {
+		if err := run(c); err != nil {
+			logger.L.Errorln(err)
+		}
+	}()
+
+	body := "--bRoot\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--bRoot\r\n" +
+		"Content-Disposition: form-data; name=\"bob\"; filename=\"short.txt\"\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"bzbzbz\r\n" +
+		"--bRoot--\r\n"
+	req := "POST /down HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=bRoot\r\n" +
+		"\r\n" +
+		body
+
+	for i := 0; i < 2; i++ { // the second request shows postParser survived the first one
+		time.Sleep(time.Millisecond * 200)
+		conn, err := net.Dial("tcp", ":3010")
+		if !s.NoError(err) {
+			return
+		}
+		_, err = conn.Write([]byte(req))
+		s.NoError(err)
+
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if s.NoError(err) {
+			s.Equal(http.StatusBadGateway, res.StatusCode)
+			res.Body.Close()
+		}
+		conn.Close()
+	}
+}
+
+// TestRunFailure tests that metrics listener is closed when receiver cannot be started
+func (s *mainSuite) TestRunFailure() {
+	busy, err := net.Listen("tcp", ":3012")
+	s.Require().NoError(err)
+	defer busy.Close()
+
+	c := config.New()
+	c.HTTP.Addr, c.HTTPS.Enabled, c.MetricsAddr = ":3012", false, ":3013"
+
+	s.Error(run(c))
+
+	l, err := net.Listen("tcp", ":3013")
+	if s.NoError(err) {
+		l.Close()
+	}
+}
+
 func (s *mainSuite) TestToLogger() {
-	go main()
+	go runDefault()
 	tt := []struct {
 		name string
 		req  []byte
@@ -2201,9 +2758,10 @@ func (s *mainSuite) TestToLogger() {
 					"Host: localhost\r\n" +
 					"User-Agent: curl/7.75.0\r\n" +
 					"Accept: */*\r\n" +
-					"Content-Length: 5250\r\n" +
+					"Content-Length: 325\r\n" +
 					"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 					"\r\n" +
+					preamble +
 					"--------------------------c61fd8e07a9d3f9b\r\n" +
 					"Content-Disposition: form-data; name=\"alice\"\r\n" +
 					"\r\n" +
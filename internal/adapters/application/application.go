@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/vynovikov/postParser/internal/adapters/driven/rpc"
 	"github.com/vynovikov/postParser/internal/adapters/driven/store"
@@ -22,6 +23,7 @@ type AppService struct {
 
 	W repo.WaitGroups
 	C repo.Channels
+	R *results
 }
 
 type PieceKey struct {
@@ -37,7 +39,7 @@ type Logger interface {
 // All adapters combined
 type App struct {
 	T rpc.Transmitter
-	A AppService
+	A *AppService
 	S store.Store
 	L Logger // Logger interface is testDouble spy for testing Handle method
 }
@@ -61,8 +63,8 @@ func NewAppEmpty() *App {
 	return App
 }
 
-func NewAppService(done chan struct{}) AppService {
-	return AppService{
+func NewAppService(done chan struct{}) *AppService {
+	return &AppService{
 		W: repo.WaitGroups{
 			M: make(map[repo.AppStoreKeyGeneral]*sync.WaitGroup),
 		},
@@ -72,6 +74,7 @@ func NewAppService(done chan struct{}) AppService {
 			ChanLog: make(chan string, 10),
 			Done:    done,
 		},
+		R: newResults(),
 	}
 }
 
@@ -82,6 +85,9 @@ func (a *App) MountLogger(l Logger) {
 type Application interface {
 	Start()
 	AddToFeeder(repo.ReceiverUnit)
+	Await(string, time.Duration) repo.Result
+	Forget(string)
+	Abort(string)
 	HandleBuffer(repo.AppStoreKeyGeneral, repo.Boundary) ([]repo.AppDistributorUnit, []error)
 	SetStopping()
 	Stopping() bool
@@ -109,6 +115,7 @@ func (a *App) toChanOut(adu repo.AppDistributorUnit) {
 	if a.L != nil {
 		a.L.LogStuff(adu)
 	}
+	a.A.R.count(adu.TS())
 	a.A.C.ChanOut <- adu
 
 }
@@ -124,16 +131,26 @@ func (a *App) Work(i int) {
 	logger.L.Infof("in postparser.application.Work worker %d started\n", i)
 
 	for afu := range a.A.C.ChanIn {
-		if len(afu.R.B.B) == 0 {
+		askg := repo.NewAppStoreKeyGeneralFromFeeder(afu)
+		w := afu.W
+
+		if len(afu.R.B.B) == 0 && !afu.R.H.Unblock {
+			w.Done()
 			continue
 		}
-		askg := repo.NewAppStoreKeyGeneralFromFeeder(afu)
-		w := a.A.W.M[askg]
 
-		// Reading feederUnit bytes chunk, finding to boundary appearance and slicing it into dataPieces
-		b, m, e := repo.Slicer(afu.R.B.B, afu.R.H.Bou)
+		var (
+			b repo.AppPieceUnit
+			m []repo.AppPieceUnit
+			e repo.AppSub
+		)
+		// Reading feederUnit bytes chunk, finding to boundary appearance and slicing it into dataPieces.
+		// Empty unblocking chunk appears when request is cut right at the chunk edge
+		if len(afu.R.B.B) > 0 {
+			b, m, e = repo.Slicer(afu.R.B.B, afu.R.H.Bou)
+		}
 
-		if !cmp.Equal(b, repo.AppPieceUnit{}) { // Beginning piece is empty only in zero part, leaving it
+		if afu.R.H.Part > 0 && !cmp.Equal(b, repo.AppPieceUnit{}) { // Beginning piece of zero part is preamble, leaving it
 
 			b.APH.SetPart(afu.R.H.Part)
 			b.APH.SetTS(afu.R.H.TS)
@@ -163,6 +180,8 @@ func (a *App) Work(i int) {
 
 		}
 
+		w.Done() // all pieces of the unit are being handled already
+
 		if afu.R.H.Unblock {
 			w.Wait()
 			a.S.Unblock(askg)
@@ -175,6 +194,9 @@ func (a *App) Work(i int) {
 				a.toChanLog(fmt.Sprintf("in application.Work extracted from buffer adu header: %v, body %q", v.H, v.B.B))
 				a.toChanOut(v)
 			}
+			if a.A.R.drain(afu.R.H.TS) {
+				a.abort(afu.R.H.TS)
+			}
 		}
 
 	}
@@ -190,21 +212,64 @@ func (a *App) AddToFeeder(in repo.ReceiverUnit) {
 
 	A := repo.NewAppFeederUnit(in)
 
-	askg := repo.NewAppStoreKeyGeneralFromFeeder(A)
-
-	if _, ok := a.A.W.M[askg]; !ok {
-		a.A.W.M[askg] = &sync.WaitGroup{}
+	if in.H.Part == 0 {
+		a.A.R.add(in.H)
 	}
 
+	A.W = a.feeding(repo.NewAppStoreKeyGeneralFromFeeder(A))
+
 	a.A.C.ChanIn <- A
 }
 
+// feeding adds unit to wait group of request askg and returns the group.
+// Unblocking unit should not be handled before previous units are sliced
+func (a *App) feeding(askg repo.AppStoreKeyGeneral) *sync.WaitGroup {
+	a.A.appRWLock.Lock()
+	defer a.A.appRWLock.Unlock()
+
+	w, ok := a.A.W.M[askg]
+	if !ok {
+		w = &sync.WaitGroup{}
+		a.A.W.M[askg] = w
+	}
+	w.Add(1)
+
+	return w
+}
+
+// fed drops wait group of request with given TS when nothing is fed anymore, units being handled keep it
+func (a *App) fed(ts string) {
+	a.A.appRWLock.Lock()
+	defer a.A.appRWLock.Unlock()
+
+	delete(a.A.W.M, repo.AppStoreKeyGeneral{TS: ts})
+}
+
 // Send is running as gourutine. Initiates transmission for any data got from chanOut
 func (a *App) Send() {
 
 	for adu := range a.A.C.ChanOut {
 		a.A.transmitterLock.Lock()
-		go a.T.Transmit(adu, &a.A.transmitterLock)
+		if a.A.R.aborted(adu.TS()) { // nothing is sent for aborted request
+			if a.A.R.record(repo.NewTransmitResult(adu)) {
+				a.T.Abort(adu.TS())
+			}
+			a.A.transmitterLock.Unlock()
+			continue
+		}
+		if adu.H.U.M.PostAction == repo.Finish || adu.H.S.M.PostAction == repo.Finish { // finishing unit should not outrun previous ones
+			a.A.W.Sending.Wait()
+		}
+		a.A.W.Sending.Add(1)
+		from := a.A.R.from(adu.TS())
+		adu.H.Client, adu.H.Route, adu.H.Parts = from.Client, from.Route, from.Parts
+		go func(adu repo.AppDistributorUnit) {
+			abort := a.A.R.record(a.T.Transmit(adu, &a.A.transmitterLock))
+			a.A.W.Sending.Done()
+			if abort {
+				a.abort(adu.TS())
+			}
+		}(adu)
 	}
 	a.A.W.Sender.Done()
 }
@@ -400,17 +465,16 @@ func CalcBody(d repo.DataPiece, bou repo.Boundary) (repo.AppDistributorBody, []b
 
 // CalcHeader creates header of unit to be transfered. Tested in application_test.go
 func CalcHeader(d repo.DataPiece, sc repo.StoreChange, o repo.Order) repo.AppDistributorHeader {
-	aduh, askd, fo, fi, f, b, pre, post := repo.AppDistributorHeader{}, repo.NewAppStoreKeyDetailed(d), "", "", repo.FiFo{}, repo.BeginningData{}, repo.None, repo.None
+	aduh, askd, f, b, pre, post := repo.AppDistributorHeader{}, repo.NewAppStoreKeyDetailed(d), repo.FiFo{}, repo.BeginningData{}, repo.None, repo.None
 	if d.IsSub() {
 		return aduh
 	}
 	askd = askd.IncPart()
 	for i := range sc.To {
-		fo, fi = sc.To[i][false].D.FormName, sc.To[i][false].D.FileName
+		f = sc.To[i][false].D.FiFo()
 		b = sc.To[i][false].B
 		break
 	}
-	f = repo.NewFiFo(fo, fi)
 
 	if sc.A == repo.Change && d.B() == repo.False && o == repo.First {
 
@@ -460,6 +524,8 @@ func (a *App) Stopping() bool {
 	return a.A.stopping
 }
 func (a *App) ChainInClose() {
+	a.A.appRWLock.Lock()
+	defer a.A.appRWLock.Unlock()
 
 	if !a.A.chanInClosed {
 		a.A.chanInClosed = true
@@ -468,6 +534,37 @@ func (a *App) ChainInClose() {
 	}
 }
 
+// Await returns result of request with given TS when its handling is finished.
+// Result with timeout error is returned if handling takes longer than d
+func (a *App) Await(ts string, d time.Duration) repo.Result {
+	defer a.fed(ts)
+
+	return a.A.R.await(ts, d)
+}
+
+// Forget drops result of request with given TS, it will not be awaited
+func (a *App) Forget(ts string) {
+	a.fed(ts)
+	a.A.R.forget(ts)
+}
+
+// Abort drops request with given TS, nobody awaits its result.
+// When all its data is handled, saver is told to abort files of the request which are not finished
+func (a *App) Abort(ts string) {
+	a.fed(ts)
+	if a.A.R.abort(ts) {
+		a.abort(ts)
+	}
+}
+
+// abort tells saver to abort files of request with given TS which are not finished
+func (a *App) abort(ts string) {
+	a.A.transmitterLock.Lock()
+	defer a.A.transmitterLock.Unlock()
+
+	a.T.Abort(ts)
+}
+
 func (a *App) Stop() {
 
 	a.A.W.Workers.Wait()
@@ -477,3 +574,170 @@ func (a *App) Stop() {
 	a.A.W.Sender.Wait()
 	close(a.A.C.Done)
 }
+
+// results keeps results of requests until receivers take them
+type results struct {
+	mu sync.Mutex
+	m  map[string]*result
+}
+
+type result struct {
+	r        repo.Result
+	from     repo.ReceiverHeader // header of the first unit, tells who sent request and where it goes
+	sent     int                 // ADUs sent to transmitter
+	recorded int                 // ADUs transmitted
+	drained  bool                // no more ADUs will be sent
+	final    bool                // all ADUs are transmitted
+	aborted  bool                // result is not awaited, ADUs are not transmitted
+	done     chan struct{}       // closed when result becomes final
+}
+
+func newResults() *results {
+	return &results{
+		m: make(map[string]*result),
+	}
+}
+
+// add starts collecting result of request having header h
+func (rs *results) add(h repo.ReceiverHeader) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, ok := rs.m[h.TS]; !ok {
+		rs.m[h.TS] = &result{
+			r:    repo.NewResult(h.TS),
+			from: h,
+			done: make(chan struct{}),
+		}
+	}
+}
+
+// from returns header of the first unit of request with given TS
+func (rs *results) from(ts string) repo.ReceiverHeader {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if r, ok := rs.m[ts]; ok {
+		return r.from
+	}
+	return repo.ReceiverHeader{}
+}
+
+// count increments number of ADUs sent to transmitter
+func (rs *results) count(ts string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if r, ok := rs.m[ts]; ok {
+		r.sent++
+	}
+}
+
+// drain notes that all ADUs of request are sent to transmitter.
+// Request without ADUs fails since no part is found in its body.
+// Returns true if request is aborted and it is finished now
+func (rs *results) drain(ts string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.m[ts]
+	if !ok || r.final {
+		return false
+	}
+	r.drained = true
+	if r.sent == 0 {
+		err := fmt.Errorf("in application.drain %w: no parts found", repo.ErrBodyMalformed)
+		r.r.Status, r.r.Error = repo.StatusOf(err), err.Error()
+	}
+	return rs.finalize(r)
+}
+
+// record adds transmission result. Result becomes final after the last ADU is transmitted.
+// Returns true if request is aborted and it is finished now
+func (rs *results) record(tr repo.TransmitResult) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.m[tr.TS]
+	if !ok || r.final {
+		return false
+	}
+	r.r.Add(tr)
+	r.recorded++
+	return rs.finalize(r)
+}
+
+// finalize makes r final if all its ADUs are transmitted. Aborted result is dropped then and true is returned
+func (rs *results) finalize(r *result) bool {
+	if !r.drained || r.recorded != r.sent {
+		return false
+	}
+	r.final = true
+	close(r.done)
+
+	if r.aborted {
+		delete(rs.m, r.r.TS)
+	}
+	return r.aborted
+}
+
+// abort marks result of request with given TS as aborted.
+// Returns true if request is finished already, result is dropped then
+func (rs *results) abort(ts string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.m[ts]
+	if !ok {
+		return false
+	}
+	r.aborted = true
+	if r.final {
+		delete(rs.m, ts)
+	}
+	return r.final
+}
+
+// aborted returns true if request with given TS is aborted
+func (rs *results) aborted(ts string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.m[ts]
+	return ok && r.aborted
+}
+
+// await waits for result to be final and drops it
+// Tested in application_test.go
+func (rs *results) await(ts string, d time.Duration) repo.Result {
+	rs.mu.Lock()
+	r, ok := rs.m[ts]
+	rs.mu.Unlock()
+	if !ok {
+		return repo.NewResultErr(ts, fmt.Errorf("in application.await %w: no result for %q", repo.ErrBodyMalformed, ts))
+	}
+	defer rs.forget(ts)
+
+	var timeout <-chan time.Time
+	if d > 0 {
+		timeout = time.After(d)
+	}
+	select {
+	case <-r.done:
+	case <-timeout:
+		return repo.NewResultErr(ts, fmt.Errorf("in application.await %w: %v", repo.ErrTimeout, d))
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return r.r
+}
+
+// forget drops result of request with given TS
+func (rs *results) forget(ts string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	delete(rs.m, ts)
+}
@@ -2,8 +2,10 @@ package application
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/vynovikov/postParser/internal/adapters/driven/store"
 	"github.com/vynovikov/postParser/internal/repo"
@@ -12,7 +14,7 @@ import (
 )
 
 var (
-	a AppService
+	a *AppService
 )
 
 type applicationSuite struct {
@@ -30,12 +32,15 @@ func (s *applicationSuite) SetupTest() {
 }
 
 func (s *applicationSuite) TestHandle() {
+	out, logs, stop := a.C.ChanOut, a.C.ChanLog, make(chan struct{}) // a is replaced by SetupTest of the next test
+	defer close(stop)
 	go func() {
 		for {
 			select {
-			case <-a.C.ChanOut:
-			case <-a.C.ChanLog:
-
+			case <-out:
+			case <-logs:
+			case <-stop:
+				return
 			}
 		}
 	}()
@@ -44,7 +49,6 @@ func (s *applicationSuite) TestHandle() {
 		a       *App
 		d       repo.DataPiece
 		bou     repo.Boundary
-		wg      sync.WaitGroup
 		wantA   *App
 		wantErr []error
 	}{
@@ -2566,15 +2570,264 @@ func (s *applicationSuite) TestHandle() {
 	}
 	for _, v := range tt {
 		s.Run(v.name, func() {
-			v.wg.Add(1)
-			go v.a.Handle(v.d, v.bou, &v.wg, 0)
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			go v.a.Handle(v.d, v.bou, wg, 0)
 			//logger.L.Infoln("in application.TestHandle waiting...")
-			v.wg.Wait()
+			wg.Wait()
 
 			s.Equal(v.wantA, v.a)
 		})
 	}
 }
+func (s *applicationSuite) TestAwait() {
+	tt := []struct {
+		name string
+		do   func(*results)
+		d    time.Duration
+		want repo.Result
+	}{
+		{
+			name: "all ADUs are transmitted",
+			do: func(rs *results) {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.count("qqq")
+				rs.count("qqq")
+				rs.record(repo.TransmitResult{TS: "qqq", Fields: []repo.ResultField{{Name: "alice", Size: 5}}})
+				rs.drain("qqq")
+				rs.record(repo.TransmitResult{TS: "qqq", Files: []repo.ResultFile{{Field: "bob", Name: "short.txt", Size: 10}}})
+			},
+			want: repo.Result{
+				TS:     "qqq",
+				Fields: []repo.ResultField{{Name: "alice", Size: 5}},
+				Files:  []repo.ResultFile{{Field: "bob", Name: "short.txt", Size: 10}},
+				Status: 200,
+			},
+		},
+
+		{
+			name: "no parts found",
+			do: func(rs *results) {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.drain("qqq")
+			},
+			want: repo.NewResultErr("qqq", fmt.Errorf("in application.drain %w: no parts found", repo.ErrBodyMalformed)),
+		},
+
+		{
+			name: "handling is not finished in time",
+			do: func(rs *results) {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.count("qqq")
+				rs.count("qqq")
+				rs.record(repo.TransmitResult{TS: "qqq", Fields: []repo.ResultField{{Name: "alice", Size: 5}}})
+				rs.drain("qqq")
+			},
+			d:    time.Millisecond * 10,
+			want: repo.NewResultErr("qqq", fmt.Errorf("in application.await %w: %v", repo.ErrTimeout, time.Millisecond*10)),
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			rs := newResults()
+			v.do(rs)
+
+			s.Equal(v.want, rs.await("qqq", v.d))
+			s.Empty(rs.m)
+		})
+	}
+}
+
+func (s *applicationSuite) TestResultsAbort() {
+	tt := []struct {
+		name      string
+		do        func(*results) []bool
+		wantAbort []bool // whether saver should be told to abort after every step
+	}{
+		{
+			name: "aborted in progress, the last transmission finishes it",
+			do: func(rs *results) []bool {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.count("qqq")
+				rs.count("qqq")
+				return []bool{
+					rs.abort("qqq"),
+					rs.record(repo.TransmitResult{TS: "qqq"}),
+					rs.drain("qqq"),
+					rs.record(repo.TransmitResult{TS: "qqq"}),
+				}
+			},
+			wantAbort: []bool{false, false, false, true},
+		},
+
+		{
+			name: "aborted in progress, draining finishes it",
+			do: func(rs *results) []bool {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.count("qqq")
+				return []bool{
+					rs.abort("qqq"),
+					rs.record(repo.TransmitResult{TS: "qqq"}),
+					rs.drain("qqq"),
+				}
+			},
+			wantAbort: []bool{false, false, true},
+		},
+
+		{
+			name: "aborted when finished already",
+			do: func(rs *results) []bool {
+				rs.add(repo.ReceiverHeader{TS: "qqq"})
+				rs.count("qqq")
+				rs.record(repo.TransmitResult{TS: "qqq"})
+				rs.drain("qqq")
+				return []bool{rs.abort("qqq")}
+			},
+			wantAbort: []bool{true},
+		},
+
+		{
+			name: "unknown request",
+			do: func(rs *results) []bool {
+				return []bool{rs.abort("qqq"), rs.aborted("qqq")}
+			},
+			wantAbort: []bool{false, false},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			rs := newResults()
+
+			s.Equal(v.wantAbort, v.do(rs))
+			s.Empty(rs.m)
+		})
+	}
+}
+
+// spyTransmitter records ADUs transmitted and requests aborted
+type spyTransmitter struct {
+	mu      sync.Mutex
+	sent    []repo.AppDistributorUnit
+	aborted []string
+}
+
+func (t *spyTransmitter) Transmit(adu repo.AppDistributorUnit, mu *sync.Mutex) repo.TransmitResult {
+	mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sent = append(t.sent, adu)
+	return repo.NewTransmitResult(adu)
+}
+
+func (t *spyTransmitter) Abort(ts string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.aborted = append(t.aborted, ts)
+}
+
+func (t *spyTransmitter) Log(string) error { return nil }
+
+// TestSendAborted tests that ADUs of aborted request are not transmitted and saver is told to abort request in the end
+func (s *applicationSuite) TestSendAborted() {
+	t := &spyTransmitter{}
+	app := &App{A: NewAppService(make(chan struct{})), T: t}
+	unit := func(ts string) repo.AppDistributorUnit {
+		return repo.AppDistributorUnit{H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: ts, Part: 1}, F: repo.FiFo{FormName: "alice"}}}, B: repo.AppDistributorBody{B: []byte("azaza")}}
+	}
+	for _, ts := range []string{"qqq", "www"} {
+		app.A.R.add(repo.ReceiverHeader{TS: ts})
+		app.toChanOut(unit(ts))
+	}
+	app.Abort("qqq")
+	s.False(app.A.R.drain("qqq"))
+
+	app.A.W.Sender.Add(1)
+	go app.Send()
+	close(app.A.C.ChanOut)
+	app.A.W.Sender.Wait()
+	app.A.W.Sending.Wait()
+
+	s.Equal([]repo.AppDistributorUnit{unit("www")}, t.sent)
+	s.Equal([]string{"qqq"}, t.aborted)
+	s.False(app.A.R.aborted("qqq"))
+}
+
+// TestSendAbortedConcurrently tests that requests may be aborted while their ADUs are being transmitted
+func (s *applicationSuite) TestSendAbortedConcurrently() {
+	t := &spyTransmitter{}
+	app := &App{A: NewAppService(make(chan struct{})), T: t}
+	unit := func(ts string, part int) repo.AppDistributorUnit {
+		return repo.AppDistributorUnit{H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: ts, Part: part}, F: repo.FiFo{FormName: "alice"}}}, B: repo.AppDistributorBody{B: []byte("azaza")}}
+	}
+
+	app.A.W.Sender.Add(1)
+	go app.Send()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		ts := fmt.Sprintf("ts%d", i)
+		app.A.R.add(repo.ReceiverHeader{TS: ts})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for p := 0; p < 5; p++ {
+				app.toChanOut(unit(ts, p))
+			}
+			if app.A.R.drain(ts) {
+				app.abort(ts)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			app.Abort(ts)
+		}()
+	}
+	wg.Wait()
+	close(app.A.C.ChanOut)
+	app.A.W.Sender.Wait()
+	app.A.W.Sending.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.Len(t.aborted, 20) // saver is told to abort every request once
+}
+
+// TestAddToFeeder tests that requests are fed concurrently and their wait groups are dropped when nothing is fed anymore
+func (s *applicationSuite) TestAddToFeeder() {
+	app := NewAppEmpty()
+	go func() {
+		for afu := range app.A.C.ChanIn {
+			afu.W.Done()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ts := fmt.Sprintf("ts%d", i)
+			for p := 0; p < 5; p++ {
+				app.AddToFeeder(repo.ReceiverUnit{H: repo.ReceiverHeader{TS: ts, Part: p}})
+			}
+			switch i % 3 {
+			case 0:
+				app.Abort(ts)
+			case 1:
+				app.Forget(ts)
+			default:
+				app.Await(ts, time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+	app.ChainInClose()
+
+	s.Empty(app.A.W.M)
+}
+
 func (s *applicationSuite) TestCalcBody() {
 	tt := []struct {
 		name string
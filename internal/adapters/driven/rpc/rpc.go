@@ -17,7 +17,9 @@ import (
 
 	errs "github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -28,7 +30,8 @@ var (
 )
 
 type Transmitter interface {
-	Transmit(repo.AppDistributorUnit, *sync.Mutex)
+	Transmit(repo.AppDistributorUnit, *sync.Mutex) repo.TransmitResult
+	Abort(string)
 	Log(string) error
 }
 
@@ -40,6 +43,12 @@ type TransmitAdapter struct {
 	CurSK        repo.StreamKey
 	CurReq       *tosaver.FileUploadReq
 	lock         sync.Mutex
+	fifos        map[tosaver.Saver_MultiPartClient]repo.FiFo          // form and file names of open streams
+	cancels      map[tosaver.Saver_MultiPartClient]context.CancelFunc // cancel open streams
+	fifosLock    sync.Mutex
+	Routes       repo.Routes                    // routes may have own savers and tags
+	savers       map[string]tosaver.SaverClient // savers of routes by address
+	saversLock   sync.Mutex
 }
 
 func NewTransmitter(lis *bufconn.Listener) *TransmitAdapter {
@@ -77,15 +86,61 @@ func NewTransmitter(lis *bufconn.Listener) *TransmitAdapter {
 	}
 }
 
-func (t *TransmitAdapter) Transmit(adu repo.AppDistributorUnit, mu *sync.Mutex) {
+// Transmit sends adu to saver and returns what saver has got
+func (t *TransmitAdapter) Transmit(adu repo.AppDistributorUnit, mu *sync.Mutex) repo.TransmitResult {
+	var errs []error
+	res := repo.NewTransmitResult(adu)
+
+	c, err := t.saver(adu.H.Route)
+	if err != nil {
+		mu.Unlock()
+		res.Errs = append(res.Errs, SaverError(err))
+		return res
+	}
 
 	switch adu.H.T {
 	case repo.Unary:
-		t.transmitUnary(t.saverClient, adu, mu)
+		errs = t.transmitUnary(c, adu, mu, &res)
 	case repo.ClientStream:
-		t.transmitStream(t.saverClient, adu, mu)
+		errs = t.transmitStream(c, adu, mu, &res)
+	}
+	for _, err := range errs {
+		res.Errs = append(res.Errs, SaverError(err))
+	}
+	return res
+}
+
+// saver returns client of saver route with given name is bound to, connection is established on first use
+func (t *TransmitAdapter) saver(route string) (tosaver.SaverClient, error) {
+	addr := t.Routes.Get(route).Saver
+	if len(addr) == 0 {
+		return t.saverClient, nil
+	}
+
+	t.saversLock.Lock()
+	defer t.saversLock.Unlock()
+
+	if c, ok := t.savers[addr]; ok {
+		return c, nil
+	}
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("in rpc.saver cannot dial saver %q of route %q: %w", addr, route, err)
 	}
+	if t.savers == nil {
+		t.savers = make(map[string]tosaver.SaverClient)
+	}
+	t.savers[addr] = tosaver.NewSaverClient(conn)
+
+	return t.savers[addr], nil
+}
 
+// SaverError wraps error got from saver with corresponding repo error
+func SaverError(err error) error {
+	if status.Code(err) == codes.ResourceExhausted {
+		return fmt.Errorf("in rpc.Transmit %w: %v", repo.ErrTooLarge, err)
+	}
+	return fmt.Errorf("in rpc.Transmit %w: %v", repo.ErrSaver, err)
 }
 func (t *TransmitAdapter) Log(s string) error {
 	req := &tologger.LogReq{}
@@ -100,7 +155,7 @@ func (t *TransmitAdapter) Log(s string) error {
 }
 
 // transmitUnary handles unary-type ADUs
-func (t *TransmitAdapter) transmitUnary(c tosaver.SaverClient, aduOne repo.AppDistributorUnit, mu *sync.Mutex) []error {
+func (t *TransmitAdapter) transmitUnary(c tosaver.SaverClient, aduOne repo.AppDistributorUnit, mu *sync.Mutex, res *repo.TransmitResult) []error {
 	errs, streamKyes := make([]error, 0), make([]repo.StreamKey, 0)
 	if aduOne.H.U.M.PreAction == repo.Start {
 		defer mu.Unlock()
@@ -109,12 +164,12 @@ func (t *TransmitAdapter) transmitUnary(c tosaver.SaverClient, aduOne repo.AppDi
 
 	if aduOne.H.U.M.PostAction == repo.Finish {
 
-		streamKyes, errs = t.Register(aduOne.H)
+		streamKyes, _ = t.Register(aduOne.H)
 
 		for _, v := range streamKyes {
 
 			if aduOne.H.U.M.PreAction == repo.StopLast {
-				_, err := t.M[v].CloseAndRecv()
+				err := t.closeStream(t.M[v], res)
 				if err != nil {
 					errs = append(errs, err)
 				}
@@ -136,7 +191,7 @@ func (t *TransmitAdapter) transmitUnary(c tosaver.SaverClient, aduOne repo.AppDi
 
 // transmitStream handles stream-type ADUs
 // Updates t.M each time
-func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppDistributorUnit, mu *sync.Mutex) []error {
+func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppDistributorUnit, mu *sync.Mutex, res *repo.TransmitResult) []error {
 	var (
 		stream tosaver.Saver_MultiPartClient
 		err    error
@@ -147,19 +202,22 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 		defer mu.Unlock()
 	}
 	req := t.NewReqStream(aduOne)
-	streamKeyes, errs := t.Register(aduOne.H)
+	streamKeyes, _ := t.Register(aduOne.H)
 
 	switch pre := aduOne.H.S.M.PreAction; {
 	case pre == repo.Start || pre == repo.Open:
 
 		if pre == repo.Start {
-			stream, err = t.NewStream(aduOne.H.S.SK.TS, aduOne.H.S.F.FormName, aduOne.H.S.F.FileName, true)
+			stream, err = t.NewStream(c, aduOne.H, true)
 		} else {
-			stream, err = t.NewStream(aduOne.H.S.SK.TS, aduOne.H.S.F.FormName, aduOne.H.S.F.FileName, false)
+			stream, err = t.NewStream(c, aduOne.H, false)
 		}
 		if err != nil {
 			logger.L.Errorf("in grpc.transmitStream error: %v\n", err)
-			errs = append(errs, err)
+			if pre != repo.Start {
+				mu.Unlock()
+			}
+			return append(errs, err)
 		}
 		t.M[streamKeyes[0]] = stream
 
@@ -177,9 +235,10 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 		stream, ok := t.M[streamKeyes[0]]
 
 		if !ok {
-			stream, err = t.NewStream(aduOne.H.S.SK.TS, aduOne.H.S.F.FormName, aduOne.H.S.F.FileName, false)
+			stream, err = t.NewStream(c, aduOne.H, false)
 			if err != nil {
-				errs = append(errs, err)
+				mu.Unlock()
+				return append(errs, err)
 			}
 			t.M[streamKeyes[0]] = stream
 		}
@@ -200,7 +259,7 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 				}
 			}
 
-			_, err = stream.CloseAndRecv()
+			err = t.closeStream(stream, res)
 			if err != nil {
 				errs = append(errs, err)
 			}
@@ -218,10 +277,12 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 			delete(t.M, streamKeyes[0])
 		} else {
 
-			stream, err = t.NewStream(aduOne.H.S.SK.TS, aduOne.H.S.F.FormName, aduOne.H.S.F.FileName, false)
-
+			stream, err = t.NewStream(c, aduOne.H, false)
 			if err != nil {
-				errs = append(errs, err)
+				if aduOne.H.S.M.PreAction == repo.StopLast || aduOne.H.S.M.PreAction == repo.Continue {
+					mu.Unlock()
+				}
+				return append(errs, err)
 			}
 			err = stream.Send(req)
 			if aduOne.H.S.M.PreAction == repo.StopLast || aduOne.H.S.M.PreAction == repo.Continue {
@@ -238,7 +299,7 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 	case repo.Close:
 
 		if stream, ok := t.M[streamKeyes[0]]; ok {
-			_, err := stream.CloseAndRecv()
+			err := t.closeStream(stream, res)
 			if err != nil {
 				errs = append(errs, err)
 			}
@@ -249,7 +310,7 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 		t.lock.Lock()
 		for i := range t.M {
 			if i.TS == aduOne.H.S.SK.TS {
-				_, err := t.M[i].CloseAndRecv()
+				err := t.closeStream(t.M[i], res)
 				if err != nil {
 					errs = append(errs, err)
 				}
@@ -261,6 +322,54 @@ func (t *TransmitAdapter) transmitStream(c tosaver.SaverClient, aduOne repo.AppD
 	return errs
 
 }
+
+// closeStream closes stream and adds file saved through it to res
+func (t *TransmitAdapter) closeStream(stream tosaver.Saver_MultiPartClient, res *repo.TransmitResult) error {
+	fur, err := stream.CloseAndRecv()
+
+	t.fifosLock.Lock()
+	fifo, cancel := t.fifos[stream], t.cancels[stream]
+	delete(t.fifos, stream)
+	delete(t.cancels, stream)
+	t.fifosLock.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	if err != nil {
+		return err
+	}
+	res.Files = append(res.Files, repo.ResultFile{Field: fifo.FormName, Name: fur.GetFileName(), Size: fur.GetFileSize()})
+
+	return nil
+}
+
+// Abort cancels open streams of request with given TS, saver gets them canceled and drops files being saved.
+// Tested in rpc_test.go
+func (t *TransmitAdapter) Abort(ts string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for sk, stream := range t.M {
+		if sk.TS != ts {
+			continue
+		}
+		delete(t.M, sk)
+		if stream == nil {
+			continue
+		}
+
+		t.fifosLock.Lock()
+		cancel := t.cancels[stream]
+		delete(t.fifos, stream)
+		delete(t.cancels, stream)
+		t.fifosLock.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
 func (t *TransmitAdapter) Delete(streamKey repo.StreamKey) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -358,25 +467,48 @@ func (t *TransmitAdapter) Register(aduHeader repo.AppDistributorHeader) ([]repo.
 
 }
 
-func (t *TransmitAdapter) NewStream(ts, fo, fi string, f bool) (tosaver.Saver_MultiPartClient, error) {
+// NewStream opens stream to saver c for file described by h, f is true if file is the first part of request
+func (t *TransmitAdapter) NewStream(c tosaver.SaverClient, h repo.AppDistributorHeader, f bool) (tosaver.Saver_MultiPartClient, error) {
+	fo, fi, ph := h.S.F.FormName, h.S.F.FileName, h.Parts.Get(h.S.F)
 	reqInit := &tosaver.FileUploadReq{
 		Info: &tosaver.FileUploadReq_FileInfo{
 			FileInfo: &tosaver.FileInfo{
-				Ts:        ts,
-				IsFirst:   f,
-				FieldName: fo,
-				FileName:  fi,
+				Ts:          h.S.SK.TS,
+				IsFirst:     f,
+				FieldName:   fo,
+				FileName:    fi,
+				ClientAddr:  h.Client.Addr,
+				ClientCert:  NewClientCert(h.Client.Cert),
+				ServerName:  h.Client.ServerName,
+				Route:       h.Route,
+				Tags:        t.Routes.Get(h.Route).Tags,
+				Principal:   h.Client.Principal,
+				ContentType: ph.ContentType,
+				Charset:     ph.Charset,
+				Headers:     ph.Fields,
 			},
 		},
 	}
-	newStream, err := t.saverClient.MultiPart(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	newStream, err := c.MultiPart(ctx)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	err = newStream.Send(reqInit)
-	if err != nil {
+	if err = newStream.Send(reqInit); err != nil {
+		cancel()
 		return nil, err
 	}
+
+	t.fifosLock.Lock()
+	if t.fifos == nil {
+		t.fifos = make(map[tosaver.Saver_MultiPartClient]repo.FiFo)
+		t.cancels = make(map[tosaver.Saver_MultiPartClient]context.CancelFunc)
+	}
+	t.fifos[newStream] = repo.NewFiFo(fo, fi)
+	t.cancels[newStream] = cancel
+	t.fifosLock.Unlock()
+
 	return newStream, nil
 }
 
@@ -388,6 +520,15 @@ func (t *TransmitAdapter) NewReqUnary(aduOne repo.AppDistributorUnit) *tosaver.T
 	req.Ts = aduOne.H.U.UK.TS
 	req.Name = aduOne.H.U.F.FormName
 	req.ByteChunk = aduOne.B.B
+	req.ClientAddr = aduOne.H.Client.Addr
+	req.ClientCert = NewClientCert(aduOne.H.Client.Cert)
+	req.ServerName = aduOne.H.Client.ServerName
+	req.Principal = aduOne.H.Client.Principal
+	req.Route = aduOne.H.Route
+	req.Tags = t.Routes.Get(aduOne.H.Route).Tags
+
+	ph := aduOne.H.Parts.Get(aduOne.H.U.F)
+	req.ContentType, req.Charset, req.Headers = ph.ContentType, ph.Charset, ph.Fields
 
 	if aduOne.H.U.F.FileName != "" {
 		req.Filename = aduOne.H.U.F.FileName
@@ -423,6 +564,18 @@ func (t *TransmitAdapter) NewReqStream(aduOne repo.AppDistributorUnit) *tosaver.
 	return req
 }
 
+// NewClientCert returns client certificate identity for saver, nil if client has no verified certificate
+func NewClientCert(c repo.ClientCert) *tosaver.ClientCert {
+	if len(c.Fingerprint) == 0 {
+		return nil
+	}
+	return &tosaver.ClientCert{
+		Cn:          c.CN,
+		Sans:        c.SANs,
+		Fingerprint: c.Fingerprint,
+	}
+}
+
 func DecodeUnaryReq(r *tosaver.TextFieldReq) repo.GRequest {
 	res := repo.GRequest{}
 
@@ -1,14 +1,18 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/vynovikov/postParser/internal/adapters/driven/rpc/tosaver/pb"
 	"github.com/vynovikov/postParser/internal/repo"
 
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
 )
 
 var stream pb.Saver_MultiPartClient
@@ -27,21 +31,21 @@ func (s *rpcSuite) SetupTest() {
 func (s *rpcSuite) TestRegister() {
 	tt := []struct {
 		name    string
-		T       TransmitAdapter
+		T       *TransmitAdapter
 		H       repo.AppDistributorHeader
-		wantT   TransmitAdapter
+		wantT   *TransmitAdapter
 		wantErr []error
 		wantSK  []repo.StreamKey
 	}{
 		{
 			name: "unary stopLast",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 0}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.StopLast, PostAction: repo.None}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{},
 			},
 			wantErr: []error{},
@@ -50,13 +54,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "unary error stream not found",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.StopLast}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -69,13 +73,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.Open, postAction = repo.Continue, t.M countains adu's part stream => returning keys to open and continue",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.Open, PostAction: repo.Continue}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
@@ -89,13 +93,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.Start, postAction = repo.Continue => returning keys to open and continue",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.Open, PostAction: repo.Continue}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -109,13 +113,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.Continue, postAction = repo.Continue => returning keys to open and continue",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.Continue, PostAction: repo.Continue}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
@@ -129,13 +133,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.StopLast, postAction = repo.Continue",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 2, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 2}, M: repo.Message{PreAction: repo.StopLast, PostAction: repo.Continue}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 2, N: false}: stream,
 				},
@@ -149,13 +153,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.StopLast, postAction = repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.StopLast, PostAction: repo.Finish}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
@@ -169,13 +173,13 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.Continue, postAction = repo.Close",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.Continue, PostAction: repo.Close}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 				},
@@ -189,14 +193,14 @@ func (s *rpcSuite) TestRegister() {
 
 		{
 			name: "clientStream prevAction == repo.Continue, postAction = repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 					{TS: "www", Part: 0, N: false}: stream,
 				},
 			},
 			H: repo.AppDistributorHeader{T: repo.ClientStream, S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, M: repo.Message{PreAction: repo.Continue, PostAction: repo.Finish}}},
-			wantT: TransmitAdapter{
+			wantT: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1, N: false}: stream,
 					{TS: "www", Part: 0, N: false}: stream,
@@ -222,13 +226,13 @@ func (s *rpcSuite) TestRegister() {
 func (s *rpcSuite) TestNewReqStream() {
 	tt := []struct {
 		name    string
-		T       TransmitAdapter
+		T       *TransmitAdapter
 		aduOne  repo.AppDistributorUnit
 		wantReq *pb.FileUploadReq
 	}{
 		{
 			name: "preAction: repo.Start postAction: repo.Continue, S.SK.Part - S.B.Part = 0",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{},
 			},
 			aduOne: repo.AppDistributorUnit{
@@ -246,7 +250,7 @@ func (s *rpcSuite) TestNewReqStream() {
 		},
 		{
 			name: "preAction: repo.Start postAction: repo.Continue, S.SK.Part - S.B.Part = 1",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{},
 			},
 			aduOne: repo.AppDistributorUnit{
@@ -266,7 +270,7 @@ func (s *rpcSuite) TestNewReqStream() {
 
 		{
 			name: "preAction: repo.Continue postAction: repo.Continue, stream exists",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -288,7 +292,7 @@ func (s *rpcSuite) TestNewReqStream() {
 
 		{
 			name: "preAction: repo.Open postAction: repo.Continue, stream not exists",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{},
 			},
 			aduOne: repo.AppDistributorUnit{
@@ -307,7 +311,7 @@ func (s *rpcSuite) TestNewReqStream() {
 		},
 		{
 			name: "preAction: repo.Continue postAction: repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -330,7 +334,7 @@ func (s *rpcSuite) TestNewReqStream() {
 
 		{
 			name: "preAction: repo.StopLast postAction: repo.Continue",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -351,7 +355,7 @@ func (s *rpcSuite) TestNewReqStream() {
 
 		{
 			name: "preAction: repo.StopLast postAction: repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 				},
@@ -383,14 +387,14 @@ func (s *rpcSuite) TestNewReqStream() {
 func (s *rpcSuite) TestNewReqUnary() {
 	tt := []struct {
 		name    string
-		T       TransmitAdapter
+		T       *TransmitAdapter
 		aduOne  repo.AppDistributorUnit
 		wantReq *pb.TextFieldReq
 	}{
 
 		{
 			name: "preAction: repo.None postAction: repo.None no filename",
-			T:    TransmitAdapter{},
+			T:    &TransmitAdapter{},
 			aduOne: repo.AppDistributorUnit{
 				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}}}, B: repo.AppDistributorBody{B: []byte("azaza")},
 			},
@@ -403,7 +407,7 @@ func (s *rpcSuite) TestNewReqUnary() {
 
 		{
 			name: "preAction: repo.None postAction: repo.None filename",
-			T:    TransmitAdapter{},
+			T:    &TransmitAdapter{},
 			aduOne: repo.AppDistributorUnit{
 				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice", FileName: "short.txt"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}}}, B: repo.AppDistributorBody{B: []byte("azaza")},
 			},
@@ -415,9 +419,87 @@ func (s *rpcSuite) TestNewReqUnary() {
 			},
 		},
 
+		{
+			name: "client address, server name and principal",
+			T:    &TransmitAdapter{},
+			aduOne: repo.AppDistributorUnit{
+				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}}, Client: repo.Client{Addr: "192.0.2.1:56324", ServerName: "a.example.com", Principal: "partner"}}, B: repo.AppDistributorBody{B: []byte("azaza")},
+			},
+			wantReq: &pb.TextFieldReq{
+				Ts:         "qqq",
+				Name:       "alice",
+				ByteChunk:  []byte("azaza"),
+				ClientAddr: "192.0.2.1:56324",
+				ServerName: "a.example.com",
+				Principal:  "partner",
+			},
+		},
+
+		{
+			name: "client certificate",
+			T:    &TransmitAdapter{},
+			aduOne: repo.AppDistributorUnit{
+				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}}, Client: repo.Client{Cert: repo.ClientCert{CN: "partner", SANs: []string{"partner.example.com"}, Fingerprint: "d743"}}}, B: repo.AppDistributorBody{B: []byte("azaza")},
+			},
+			wantReq: &pb.TextFieldReq{
+				Ts:         "qqq",
+				Name:       "alice",
+				ByteChunk:  []byte("azaza"),
+				ClientCert: &pb.ClientCert{Cn: "partner", Sans: []string{"partner.example.com"}, Fingerprint: "d743"},
+			},
+		},
+
+		{
+			name: "route and its tags",
+			T:    &TransmitAdapter{Routes: repo.Routes{{Name: "avatars", Path: "/avatars", Tags: map[string]string{"kind": "image"}}}},
+			aduOne: repo.AppDistributorUnit{
+				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}}, Route: "avatars"}, B: repo.AppDistributorBody{B: []byte("azaza")},
+			},
+			wantReq: &pb.TextFieldReq{
+				Ts:        "qqq",
+				Name:      "alice",
+				ByteChunk: []byte("azaza"),
+				Route:     "avatars",
+				Tags:      map[string]string{"kind": "image"},
+			},
+		},
+
+		{
+			name: "part header",
+			T:    &TransmitAdapter{},
+			aduOne: repo.AppDistributorUnit{
+				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice"}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}},
+					Parts: partsOf("--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\nContent-Type: text/plain; charset=UTF-8\r\nX-Origin: scanner\r\n\r\nazaza\r\n--azaza--\r\n")}, B: repo.AppDistributorBody{B: []byte("azaza")},
+			},
+			wantReq: &pb.TextFieldReq{
+				Ts:          "qqq",
+				Name:        "alice",
+				ByteChunk:   []byte("azaza"),
+				ContentType: "text/plain",
+				Charset:     "utf-8",
+				Headers:     map[string]string{"Content-Disposition": "form-data; name=\"alice\"", "Content-Type": "text/plain; charset=UTF-8", "X-Origin": "scanner"},
+			},
+		},
+
+		{
+			name: "part header found by index",
+			T:    &TransmitAdapter{},
+			aduOne: repo.AppDistributorUnit{
+				H: repo.AppDistributorHeader{T: repo.Unary, U: repo.UnaryData{UK: repo.UnaryKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "alice", Index: 2}, M: repo.Message{PreAction: repo.None, PostAction: repo.None}},
+					Parts: partsOf("--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\nazaza\r\n--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\nContent-Type: text/csv\r\n\r\nazaza\r\n--azaza--\r\n")}, B: repo.AppDistributorBody{B: []byte("azaza")},
+			},
+			wantReq: &pb.TextFieldReq{
+				Ts:          "qqq",
+				Name:        "alice",
+				ByteChunk:   []byte("azaza"),
+				ContentType: "text/csv",
+				Headers:     map[string]string{"Content-Disposition": "form-data; name=\"alice\"", "Content-Type": "text/csv"},
+			},
+		},
+
 		{
 			name: "preAction: repo.Start postAction: repo.None",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 					{TS: "www", Part: 1}: stream,
@@ -437,7 +519,7 @@ func (s *rpcSuite) TestNewReqUnary() {
 
 		{
 			name: "preAction: repo.Start postAction: repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 					{TS: "www", Part: 1}: stream,
@@ -458,7 +540,7 @@ func (s *rpcSuite) TestNewReqUnary() {
 
 		{
 			name: "preAction: repo.Continue postAction: repo.Finish",
-			T: TransmitAdapter{
+			T: &TransmitAdapter{
 				M: map[repo.StreamKey]pb.Saver_MultiPartClient{
 					{TS: "qqq", Part: 1}: stream,
 					{TS: "www", Part: 1}: stream,
@@ -486,3 +568,87 @@ func (s *rpcSuite) TestNewReqUnary() {
 		})
 	}
 }
+
+func (s *rpcSuite) TestNewStream() {
+	parts := partsOf("--azaza\r\nContent-Disposition: form-data; name=\"avatar\"; filename=\"me.png\"\r\nContent-Type: image/png\r\n\r\nazaza\r\n--azaza--\r\n")
+	t := &TransmitAdapter{}
+
+	st, err := t.NewStream(&fakeSaver{}, repo.AppDistributorHeader{S: repo.StreamData{SK: repo.StreamKey{TS: "qqq", Part: 1}, F: repo.FiFo{FormName: "avatar", FileName: "me.png"}}, Parts: parts}, true)
+	s.NoError(err)
+
+	s.Equal(fmt.Sprint(&pb.FileInfo{
+		Ts:          "qqq",
+		FieldName:   "avatar",
+		FileName:    "me.png",
+		IsFirst:     true,
+		ContentType: "image/png",
+		Headers:     map[string]string{"Content-Disposition": "form-data; name=\"avatar\"; filename=\"me.png\"", "Content-Type": "image/png"},
+	}), fmt.Sprint(st.(*fakeStream).sent[0].GetFileInfo()))
+
+	fs := &fakeSaver{err: errors.New("saver is gone")}
+	_, err = t.NewStream(fs, repo.AppDistributorHeader{S: repo.StreamData{SK: repo.StreamKey{TS: "www", Part: 1}, F: repo.FiFo{FormName: "avatar", FileName: "me.png"}}, Parts: parts}, true)
+	s.EqualError(err, "saver is gone")
+	s.Error(fs.last.ctx.Err()) // stream failed to start is canceled and forgotten
+	s.NotContains(t.fifos, pb.Saver_MultiPartClient(fs.last))
+	s.NotContains(t.cancels, pb.Saver_MultiPartClient(fs.last))
+}
+
+// partsOf returns headers of parts of multipart body b framed by boundary "azaza"
+func partsOf(b string) *repo.Parts {
+	p := new(repo.Parts)
+	io.Copy(io.Discard, repo.NewFlatReader(strings.NewReader(b), repo.Boundary{Prefix: []byte("--"), Root: []byte("azaza")}, p, true))
+
+	return p
+}
+
+// fakeSaver is saver client opening fake streams, err is returned by every Send of them
+type fakeSaver struct {
+	pb.SaverClient
+	err  error
+	last *fakeStream // stream opened last
+}
+
+func (f *fakeSaver) MultiPart(ctx context.Context, opts ...grpc.CallOption) (pb.Saver_MultiPartClient, error) {
+	f.last = &fakeStream{ctx: ctx, err: f.err}
+	return f.last, nil
+}
+
+// fakeStream accepts everything sent and keeps it, its context tells whether it is canceled
+type fakeStream struct {
+	pb.Saver_MultiPartClient
+	ctx  context.Context
+	err  error
+	sent []*pb.FileUploadReq
+}
+
+func (f *fakeStream) Send(req *pb.FileUploadReq) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, req)
+	return f.ctx.Err()
+}
+
+func (s *rpcSuite) TestAbort() {
+	t := &TransmitAdapter{M: make(map[repo.StreamKey]pb.Saver_MultiPartClient)}
+	streams := make(map[repo.StreamKey]*fakeStream)
+	for _, sk := range []repo.StreamKey{{TS: "qqq", Part: 1}, {TS: "qqq", Part: 2}, {TS: "www", Part: 1}} {
+		st, err := t.NewStream(&fakeSaver{}, repo.AppDistributorHeader{S: repo.StreamData{SK: sk, F: repo.FiFo{FormName: "alice", FileName: "short.txt"}}}, sk.Part == 1)
+		s.NoError(err)
+		t.M[sk] = st
+		streams[sk] = st.(*fakeStream)
+	}
+
+	t.Abort("qqq")
+
+	s.Len(t.M, 1)
+	s.Len(t.fifos, 1)
+	s.Len(t.cancels, 1)
+	for sk, st := range streams {
+		if sk.TS == "qqq" {
+			s.ErrorIs(st.ctx.Err(), context.Canceled)
+			continue
+		}
+		s.NoError(st.ctx.Err())
+	}
+}
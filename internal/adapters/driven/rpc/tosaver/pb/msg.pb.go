@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.0
+// 	protoc-gen-go v1.30.0
 // 	protoc        v3.20.1
 // source: internal/adapters/driven/rpc/tosaver/proto/msg.proto
 
@@ -20,23 +20,95 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ClientCert struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cn          string   `protobuf:"bytes,1,opt,name=cn,proto3" json:"cn,omitempty"`
+	Sans        []string `protobuf:"bytes,2,rep,name=sans,proto3" json:"sans,omitempty"`
+	Fingerprint string   `protobuf:"bytes,3,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+}
+
+func (x *ClientCert) Reset() {
+	*x = ClientCert{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientCert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientCert) ProtoMessage() {}
+
+func (x *ClientCert) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientCert.ProtoReflect.Descriptor instead.
+func (*ClientCert) Descriptor() ([]byte, []int) {
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClientCert) GetCn() string {
+	if x != nil {
+		return x.Cn
+	}
+	return ""
+}
+
+func (x *ClientCert) GetSans() []string {
+	if x != nil {
+		return x.Sans
+	}
+	return nil
+}
+
+func (x *ClientCert) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
 type TextFieldReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Ts        string `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"`
-	Name      string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Filename  string `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
-	ByteChunk []byte `protobuf:"bytes,4,opt,name=byteChunk,proto3" json:"byteChunk,omitempty"`
-	IsFirst   bool   `protobuf:"varint,5,opt,name=isFirst,proto3" json:"isFirst,omitempty"`
-	IsLast    bool   `protobuf:"varint,6,opt,name=isLast,proto3" json:"isLast,omitempty"`
+	Ts          string            `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"`
+	Name        string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Filename    string            `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	ByteChunk   []byte            `protobuf:"bytes,4,opt,name=byteChunk,proto3" json:"byteChunk,omitempty"`
+	IsFirst     bool              `protobuf:"varint,5,opt,name=isFirst,proto3" json:"isFirst,omitempty"`
+	IsLast      bool              `protobuf:"varint,6,opt,name=isLast,proto3" json:"isLast,omitempty"`
+	ClientAddr  string            `protobuf:"bytes,7,opt,name=clientAddr,proto3" json:"clientAddr,omitempty"`
+	ClientCert  *ClientCert       `protobuf:"bytes,8,opt,name=clientCert,proto3" json:"clientCert,omitempty"`
+	ServerName  string            `protobuf:"bytes,9,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	Route       string            `protobuf:"bytes,10,opt,name=route,proto3" json:"route,omitempty"`
+	Tags        map[string]string `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Principal   string            `protobuf:"bytes,12,opt,name=principal,proto3" json:"principal,omitempty"`
+	ContentType string            `protobuf:"bytes,13,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	Charset     string            `protobuf:"bytes,14,opt,name=charset,proto3" json:"charset,omitempty"`
+	Headers     map[string]string `protobuf:"bytes,15,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *TextFieldReq) Reset() {
 	*x = TextFieldReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[0]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -49,7 +121,7 @@ func (x *TextFieldReq) String() string {
 func (*TextFieldReq) ProtoMessage() {}
 
 func (x *TextFieldReq) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[0]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -62,7 +134,7 @@ func (x *TextFieldReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextFieldReq.ProtoReflect.Descriptor instead.
 func (*TextFieldReq) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{0}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *TextFieldReq) GetTs() string {
@@ -107,6 +179,69 @@ func (x *TextFieldReq) GetIsLast() bool {
 	return false
 }
 
+func (x *TextFieldReq) GetClientAddr() string {
+	if x != nil {
+		return x.ClientAddr
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetClientCert() *ClientCert {
+	if x != nil {
+		return x.ClientCert
+	}
+	return nil
+}
+
+func (x *TextFieldReq) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *TextFieldReq) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetCharset() string {
+	if x != nil {
+		return x.Charset
+	}
+	return ""
+}
+
+func (x *TextFieldReq) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
 type TextFieldRes struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -118,7 +253,7 @@ type TextFieldRes struct {
 func (x *TextFieldRes) Reset() {
 	*x = TextFieldRes{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[1]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -131,7 +266,7 @@ func (x *TextFieldRes) String() string {
 func (*TextFieldRes) ProtoMessage() {}
 
 func (x *TextFieldRes) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[1]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -144,7 +279,7 @@ func (x *TextFieldRes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextFieldRes.ProtoReflect.Descriptor instead.
 func (*TextFieldRes) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{1}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *TextFieldRes) GetResult() bool {
@@ -159,16 +294,25 @@ type FileInfo struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Ts        string `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"`
-	FieldName string `protobuf:"bytes,2,opt,name=fieldName,proto3" json:"fieldName,omitempty"`
-	FileName  string `protobuf:"bytes,3,opt,name=fileName,proto3" json:"fileName,omitempty"`
-	IsFirst   bool   `protobuf:"varint,4,opt,name=isFirst,proto3" json:"isFirst,omitempty"`
+	Ts          string            `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"`
+	FieldName   string            `protobuf:"bytes,2,opt,name=fieldName,proto3" json:"fieldName,omitempty"`
+	FileName    string            `protobuf:"bytes,3,opt,name=fileName,proto3" json:"fileName,omitempty"`
+	IsFirst     bool              `protobuf:"varint,4,opt,name=isFirst,proto3" json:"isFirst,omitempty"`
+	ClientAddr  string            `protobuf:"bytes,5,opt,name=clientAddr,proto3" json:"clientAddr,omitempty"`
+	ClientCert  *ClientCert       `protobuf:"bytes,6,opt,name=clientCert,proto3" json:"clientCert,omitempty"`
+	ServerName  string            `protobuf:"bytes,7,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	Route       string            `protobuf:"bytes,8,opt,name=route,proto3" json:"route,omitempty"`
+	Tags        map[string]string `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Principal   string            `protobuf:"bytes,10,opt,name=principal,proto3" json:"principal,omitempty"`
+	ContentType string            `protobuf:"bytes,11,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	Charset     string            `protobuf:"bytes,12,opt,name=charset,proto3" json:"charset,omitempty"`
+	Headers     map[string]string `protobuf:"bytes,13,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *FileInfo) Reset() {
 	*x = FileInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[2]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -181,7 +325,7 @@ func (x *FileInfo) String() string {
 func (*FileInfo) ProtoMessage() {}
 
 func (x *FileInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[2]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -194,7 +338,7 @@ func (x *FileInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
 func (*FileInfo) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{2}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *FileInfo) GetTs() string {
@@ -225,6 +369,69 @@ func (x *FileInfo) GetIsFirst() bool {
 	return false
 }
 
+func (x *FileInfo) GetClientAddr() string {
+	if x != nil {
+		return x.ClientAddr
+	}
+	return ""
+}
+
+func (x *FileInfo) GetClientCert() *ClientCert {
+	if x != nil {
+		return x.ClientCert
+	}
+	return nil
+}
+
+func (x *FileInfo) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *FileInfo) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *FileInfo) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *FileInfo) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+func (x *FileInfo) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileInfo) GetCharset() string {
+	if x != nil {
+		return x.Charset
+	}
+	return ""
+}
+
+func (x *FileInfo) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
 type FileData struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -240,7 +447,7 @@ type FileData struct {
 func (x *FileData) Reset() {
 	*x = FileData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[3]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -253,7 +460,7 @@ func (x *FileData) String() string {
 func (*FileData) ProtoMessage() {}
 
 func (x *FileData) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[3]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -266,7 +473,7 @@ func (x *FileData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileData.ProtoReflect.Descriptor instead.
 func (*FileData) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{3}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *FileData) GetTs() string {
@@ -318,7 +525,7 @@ type FileUploadReq struct {
 func (x *FileUploadReq) Reset() {
 	*x = FileUploadReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -331,7 +538,7 @@ func (x *FileUploadReq) String() string {
 func (*FileUploadReq) ProtoMessage() {}
 
 func (x *FileUploadReq) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -344,7 +551,7 @@ func (x *FileUploadReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileUploadReq.ProtoReflect.Descriptor instead.
 func (*FileUploadReq) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{4}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{5}
 }
 
 func (m *FileUploadReq) GetInfo() isFileUploadReq_Info {
@@ -396,7 +603,7 @@ type FileUploadRes struct {
 func (x *FileUploadRes) Reset() {
 	*x = FileUploadRes{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5]
+		mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -409,7 +616,7 @@ func (x *FileUploadRes) String() string {
 func (*FileUploadRes) ProtoMessage() {}
 
 func (x *FileUploadRes) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5]
+	mi := &file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -422,7 +629,7 @@ func (x *FileUploadRes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileUploadRes.ProtoReflect.Descriptor instead.
 func (*FileUploadRes) Descriptor() ([]byte, []int) {
-	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{5}
+	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *FileUploadRes) GetFileName() string {
@@ -445,48 +652,109 @@ var file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDesc = []byte{
 	0x0a, 0x34, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x61, 0x64, 0x61, 0x70, 0x74,
 	0x65, 0x72, 0x73, 0x2f, 0x64, 0x72, 0x69, 0x76, 0x65, 0x6e, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x74,
 	0x6f, 0x73, 0x61, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x73, 0x67,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x72, 0x70, 0x63, 0x22, 0x9e, 0x01, 0x0a, 0x0c,
-	0x54, 0x65, 0x78, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02,
-	0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x73, 0x12, 0x12, 0x0a, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09,
-	0x62, 0x79, 0x74, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52,
-	0x09, 0x62, 0x79, 0x74, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x73,
-	0x46, 0x69, 0x72, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x46,
-	0x69, 0x72, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x18, 0x06,
-	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x22, 0x26, 0x0a, 0x0c,
-	0x54, 0x65, 0x78, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06,
-	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x22, 0x6e, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x72, 0x70, 0x63, 0x22, 0x52, 0x0a, 0x0a, 0x43,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x63, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x63, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x61, 0x6e,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x73, 0x61, 0x6e, 0x73, 0x12, 0x20, 0x0a,
+	0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x22,
+	0xdf, 0x04, 0x0a, 0x0c, 0x54, 0x65, 0x78, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x71,
+	0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x73,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x62, 0x79, 0x74, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x79, 0x74, 0x65, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x18,
+	0x0a, 0x07, 0x69, 0x73, 0x46, 0x69, 0x72, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x07, 0x69, 0x73, 0x46, 0x69, 0x72, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x4c, 0x61,
+	0x73, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74,
+	0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72,
+	0x12, 0x2f, 0x0a, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72, 0x74, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x43, 0x65, 0x72, 0x74, 0x52, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72,
+	0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x12, 0x2f, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18,
+	0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x54, 0x65, 0x78, 0x74,
+	0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65, 0x71, 0x2e, 0x54, 0x61, 0x67, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x69, 0x6e,
+	0x63, 0x69, 0x70, 0x61, 0x6c, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x69,
+	0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x54, 0x79, 0x70, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x72,
+	0x73, 0x65, 0x74, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x72, 0x73,
+	0x65, 0x74, 0x12, 0x38, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x0f, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x54, 0x65, 0x78, 0x74, 0x46, 0x69,
+	0x65, 0x6c, 0x64, 0x52, 0x65, 0x71, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x1a, 0x37, 0x0a, 0x09,
+	0x54, 0x61, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3a, 0x0a, 0x0c, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0x26, 0x0a, 0x0c, 0x54, 0x65, 0x78, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x65,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0xa7, 0x04, 0x0a, 0x08, 0x46, 0x69,
+	0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x74, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x69, 0x73, 0x46, 0x69, 0x72, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x69, 0x73, 0x46, 0x69, 0x72, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x41, 0x64, 0x64, 0x72, 0x12, 0x2f, 0x0a, 0x0a, 0x63, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72, 0x74, 0x52,
+	0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x65, 0x72, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x72,
+	0x6f, 0x75, 0x74, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x74,
+	0x65, 0x12, 0x2b, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x2e, 0x54,
+	0x61, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x70, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x70, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x12, 0x20, 0x0a, 0x0b,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x68, 0x61, 0x72, 0x73, 0x65, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x68, 0x61, 0x72, 0x73, 0x65, 0x74, 0x12, 0x34, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x72, 0x70, 0x63, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x1a, 0x37,
+	0x0a, 0x09, 0x54, 0x61, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3a, 0x0a, 0x0c, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0x86, 0x01, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61,
 	0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x73,
 	0x12, 0x1c, 0x0a, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a,
-	0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x73,
-	0x46, 0x69, 0x72, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x46,
-	0x69, 0x72, 0x73, 0x74, 0x22, 0x86, 0x01, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x61, 0x74,
-	0x61, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74,
-	0x73, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12,
-	0x16, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
-	0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x79, 0x74, 0x65, 0x43,
-	0x68, 0x75, 0x6e, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x79, 0x74, 0x65,
-	0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x22, 0x71, 0x0a,
-	0x0d, 0x46, 0x69, 0x6c, 0x65, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x12, 0x2b,
-	0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x48,
-	0x00, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x2b, 0x0a, 0x08, 0x66,
-	0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
-	0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x48, 0x00, 0x52, 0x08,
-	0x66, 0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x42, 0x06, 0x0a, 0x04, 0x69, 0x6e, 0x66, 0x6f,
-	0x22, 0x47, 0x0a, 0x0d, 0x46, 0x69, 0x6c, 0x65, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65,
-	0x73, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a,
-	0x08, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
-	0x08, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70,
-	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x79, 0x74, 0x65, 0x43, 0x68,
+	0x75, 0x6e, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x79, 0x74, 0x65, 0x43,
+	0x68, 0x75, 0x6e, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x4c, 0x61, 0x73, 0x74, 0x22, 0x71, 0x0a, 0x0d,
+	0x46, 0x69, 0x6c, 0x65, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x12, 0x2b, 0x0a,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x48, 0x00,
+	0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x2b, 0x0a, 0x08, 0x66, 0x69,
+	0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x48, 0x00, 0x52, 0x08, 0x66,
+	0x69, 0x6c, 0x65, 0x44, 0x61, 0x74, 0x61, 0x42, 0x06, 0x0a, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x22,
+	0x47, 0x0a, 0x0d, 0x46, 0x69, 0x6c, 0x65, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73,
+	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08,
+	0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -501,23 +769,34 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescGZIP() []b
 	return file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDescData
 }
 
-var file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_goTypes = []interface{}{
-	(*TextFieldReq)(nil),  // 0: rpc.TextFieldReq
-	(*TextFieldRes)(nil),  // 1: rpc.TextFieldRes
-	(*FileInfo)(nil),      // 2: rpc.FileInfo
-	(*FileData)(nil),      // 3: rpc.FileData
-	(*FileUploadReq)(nil), // 4: rpc.FileUploadReq
-	(*FileUploadRes)(nil), // 5: rpc.FileUploadRes
+	(*ClientCert)(nil),    // 0: rpc.ClientCert
+	(*TextFieldReq)(nil),  // 1: rpc.TextFieldReq
+	(*TextFieldRes)(nil),  // 2: rpc.TextFieldRes
+	(*FileInfo)(nil),      // 3: rpc.FileInfo
+	(*FileData)(nil),      // 4: rpc.FileData
+	(*FileUploadReq)(nil), // 5: rpc.FileUploadReq
+	(*FileUploadRes)(nil), // 6: rpc.FileUploadRes
+	nil,                   // 7: rpc.TextFieldReq.TagsEntry
+	nil,                   // 8: rpc.TextFieldReq.HeadersEntry
+	nil,                   // 9: rpc.FileInfo.TagsEntry
+	nil,                   // 10: rpc.FileInfo.HeadersEntry
 }
 var file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_depIdxs = []int32{
-	2, // 0: rpc.FileUploadReq.fileInfo:type_name -> rpc.FileInfo
-	3, // 1: rpc.FileUploadReq.fileData:type_name -> rpc.FileData
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: rpc.TextFieldReq.clientCert:type_name -> rpc.ClientCert
+	7,  // 1: rpc.TextFieldReq.tags:type_name -> rpc.TextFieldReq.TagsEntry
+	8,  // 2: rpc.TextFieldReq.headers:type_name -> rpc.TextFieldReq.HeadersEntry
+	0,  // 3: rpc.FileInfo.clientCert:type_name -> rpc.ClientCert
+	9,  // 4: rpc.FileInfo.tags:type_name -> rpc.FileInfo.TagsEntry
+	10, // 5: rpc.FileInfo.headers:type_name -> rpc.FileInfo.HeadersEntry
+	3,  // 6: rpc.FileUploadReq.fileInfo:type_name -> rpc.FileInfo
+	4,  // 7: rpc.FileUploadReq.fileData:type_name -> rpc.FileData
+	8,  // [8:8] is the sub-list for method output_type
+	8,  // [8:8] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() }
@@ -527,7 +806,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TextFieldReq); i {
+			switch v := v.(*ClientCert); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -539,7 +818,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TextFieldRes); i {
+			switch v := v.(*TextFieldReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -551,7 +830,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FileInfo); i {
+			switch v := v.(*TextFieldRes); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -563,7 +842,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FileData); i {
+			switch v := v.(*FileInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -575,7 +854,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FileUploadReq); i {
+			switch v := v.(*FileData); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -587,6 +866,18 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileUploadReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*FileUploadRes); i {
 			case 0:
 				return &v.state
@@ -599,7 +890,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			}
 		}
 	}
-	file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[4].OneofWrappers = []interface{}{
+	file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_msgTypes[5].OneofWrappers = []interface{}{
 		(*FileUploadReq_FileInfo)(nil),
 		(*FileUploadReq_FileData)(nil),
 	}
@@ -609,7 +900,7 @@ func file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_internal_adapters_driven_rpc_tosaver_proto_msg_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
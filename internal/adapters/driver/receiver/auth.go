@@ -0,0 +1,389 @@
+package receiver
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/config"
+	"github.com/vynovikov/postParser/internal/repo"
+)
+
+// MaxSkew is max difference between signed Date header and current time
+const MaxSkew = time.Minute * 5
+
+// DefaultRealm is realm of WWW-Authenticate challenges if none is set
+const DefaultRealm = "postParser"
+
+// Auth authenticates requests by Authorization header.
+// Basic credentials, bearer tokens, bearer JWT and HMAC request signatures are supported. Nil Auth lets every request through
+type Auth struct {
+	basic       map[string]string
+	tokens      map[[sha256.Size]byte]string // principals by token hash
+	jwtKey      crypto.PublicKey
+	jwtIssuer   string
+	jwtAudience string
+	hmac        map[string][]byte
+	hmacHeaders []string
+	challenges  []string // WWW-Authenticate of request failing authentication
+	now         func() time.Time
+}
+
+// NewAuth returns authenticator configured by c, nil if no authentication method is set
+func NewAuth(c config.Auth) (*Auth, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	a := &Auth{
+		basic:       c.Basic,
+		jwtIssuer:   c.JWTIssuer,
+		jwtAudience: c.JWTAudience,
+		hmac:        make(map[string][]byte),
+		hmacHeaders: c.HMACHeaders,
+		now:         time.Now,
+	}
+	for id, secret := range c.HMAC {
+		a.hmac[id] = []byte(secret)
+	}
+	if len(a.hmacHeaders) == 0 {
+		a.hmacHeaders = []string{"(request-target)", "date"}
+	}
+
+	if len(c.TokenFile) > 0 {
+		tokens, err := readTokens(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = tokens
+	}
+
+	if len(c.JWTKey) > 0 {
+		key, err := readPublicKey(c.JWTKey)
+		if err != nil {
+			return nil, err
+		}
+		a.jwtKey = key
+	}
+	a.challenges = challenges(c, a.hmacHeaders)
+
+	return a, nil
+}
+
+// challenges returns WWW-Authenticate challenge of every scheme c sets, hmacHeaders are headers signature should cover
+func challenges(c config.Auth, hmacHeaders []string) []string {
+	realm := c.Realm
+	if len(realm) == 0 {
+		realm = DefaultRealm
+	}
+	realm = "realm=" + strconv.Quote(realm)
+
+	ch := make([]string, 0, 3)
+	if len(c.Basic) > 0 {
+		ch = append(ch, "Basic "+realm)
+	}
+	if len(c.TokenFile) > 0 || len(c.JWTKey) > 0 {
+		ch = append(ch, "Bearer "+realm)
+	}
+	if len(c.HMAC) > 0 {
+		ch = append(ch, "Signature "+realm+`, headers="`+strings.ToLower(strings.Join(hmacHeaders, " "))+`"`)
+	}
+	return ch
+}
+
+// readTokens reads file having "principal token" per line, empty lines and lines starting with # are skipped
+func readTokens(path string) (map[[sha256.Size]byte]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("in receiver.readTokens cannot open token file: %w", err)
+	}
+	defer f.Close()
+
+	tokens := make(map[[sha256.Size]byte]string)
+	sc, n := bufio.NewScanner(f), 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("in receiver.readTokens line %d of %q is not \"principal token\"", n, path)
+		}
+		tokens[sha256.Sum256([]byte(fields[1]))] = fields[0]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("in receiver.readTokens cannot read token file: %w", err)
+	}
+	return tokens, nil
+}
+
+// readPublicKey reads PEM encoded RSA, ECDSA or Ed25519 public key
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("in receiver.readPublicKey cannot read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("in receiver.readPublicKey no PEM data in %q", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("in receiver.readPublicKey cannot parse JWT key %q: %w", path, err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("in receiver.readPublicKey unsupported key type %T in %q", key, path)
+}
+
+// Authenticate returns principal of request having header rh.
+// Error tells client how to authenticate by challenge of every scheme set.
+// Tested in auth_test.go
+func (a *Auth) Authenticate(rh repo.RequestHeader) (string, error) {
+	if a == nil {
+		return "", nil
+	}
+	p, err := a.authenticate(rh)
+	if err != nil {
+		return "", &repo.ChallengeError{Err: err, Challenges: a.challenges}
+	}
+	return p, nil
+}
+
+// authenticate checks credentials of scheme Authorization header of rh has
+func (a *Auth) authenticate(rh repo.RequestHeader) (string, error) {
+	scheme, cred, _ := strings.Cut(rh.Fields.Get("Authorization"), " ")
+	switch {
+	case strings.EqualFold(scheme, "Basic") && len(a.basic) > 0:
+		return a.authBasic(cred)
+	case strings.EqualFold(scheme, "Bearer") && (len(a.tokens) > 0 || a.jwtKey != nil):
+		return a.authBearer(strings.TrimSpace(cred))
+	case strings.EqualFold(scheme, "Signature") && len(a.hmac) > 0:
+		return a.authSignature(rh, cred)
+	case len(scheme) == 0:
+		return "", fmt.Errorf("in receiver.Authenticate %w: no credentials", repo.ErrUnauthorized)
+	}
+	return "", fmt.Errorf("in receiver.Authenticate %w: unsupported scheme %q", repo.ErrUnauthorized, scheme)
+}
+
+// authBasic checks user name and password
+func (a *Auth) authBasic(cred string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cred))
+	if err != nil {
+		return "", fmt.Errorf("in receiver.authBasic %w: malformed credentials", repo.ErrUnauthorized)
+	}
+	user, pass, _ := strings.Cut(string(b), ":")
+
+	want, ok := a.basic[user]
+	if !ok || subtle.ConstantTimeCompare([]byte(pass), []byte(want)) != 1 {
+		return "", fmt.Errorf("in receiver.authBasic %w: wrong user name or password for %q", repo.ErrUnauthorized, user)
+	}
+	return user, nil
+}
+
+// authBearer looks token up in token file, JWT is verified if it is not found there
+func (a *Auth) authBearer(token string) (string, error) {
+	if p, ok := a.tokens[sha256.Sum256([]byte(token))]; ok {
+		return p, nil
+	}
+	if a.jwtKey == nil || strings.Count(token, ".") != 2 {
+		return "", fmt.Errorf("in receiver.authBearer %w: unknown token", repo.ErrUnauthorized)
+	}
+	return a.authJWT(token)
+}
+
+// jwtClaims are claims checked by authJWT
+type jwtClaims struct {
+	Sub string          `json:"sub"`
+	Iss string          `json:"iss"`
+	Aud json.RawMessage `json:"aud"` // string or array of strings
+	Exp *float64        `json:"exp"`
+	Nbf *float64        `json:"nbf"`
+}
+
+// authJWT verifies JWT signature and claims, subject is principal
+func (a *Auth) authJWT(token string) (string, error) {
+	parts := strings.Split(token, ".")
+
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	var c jwtClaims
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return "", fmt.Errorf("in receiver.authJWT %w: malformed header: %v", repo.ErrUnauthorized, err)
+	}
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return "", fmt.Errorf("in receiver.authJWT %w: malformed claims: %v", repo.ErrUnauthorized, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("in receiver.authJWT %w: malformed signature", repo.ErrUnauthorized)
+	}
+	if err = verifyJWT(a.jwtKey, h.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return "", fmt.Errorf("in receiver.authJWT %w: %v", repo.ErrUnauthorized, err)
+	}
+
+	now := float64(a.now().Unix())
+	switch {
+	case len(c.Sub) == 0:
+		return "", fmt.Errorf("in receiver.authJWT %w: no subject", repo.ErrUnauthorized)
+	case c.Exp != nil && now >= *c.Exp:
+		return "", fmt.Errorf("in receiver.authJWT %w: token of %q is expired", repo.ErrUnauthorized, c.Sub)
+	case c.Nbf != nil && now < *c.Nbf:
+		return "", fmt.Errorf("in receiver.authJWT %w: token of %q is not valid yet", repo.ErrUnauthorized, c.Sub)
+	case len(a.jwtIssuer) > 0 && c.Iss != a.jwtIssuer:
+		return "", fmt.Errorf("in receiver.authJWT %w: issuer %q is not accepted", repo.ErrUnauthorized, c.Iss)
+	case len(a.jwtAudience) > 0 && !hasAudience(c.Aud, a.jwtAudience):
+		return "", fmt.Errorf("in receiver.authJWT %w: token of %q is not issued for %q", repo.ErrUnauthorized, c.Sub, a.jwtAudience)
+	}
+	return c.Sub, nil
+}
+
+// decodeSegment decodes base64url encoded JSON into v
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifyJWT verifies signature of signed part of JWT. Algorithm should match key type
+func verifyJWT(key crypto.PublicKey, alg, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			break
+		}
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig)
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(sig) != 64 {
+			break
+		}
+		if !ecdsa.Verify(k, sum[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return fmt.Errorf("ECDSA verification failure")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			break
+		}
+		if !ed25519.Verify(k, []byte(signed), sig) {
+			return fmt.Errorf("Ed25519 verification failure")
+		}
+		return nil
+	}
+	return fmt.Errorf("algorithm %q does not match key", alg)
+}
+
+// hasAudience returns true if aud claim is aud or contains it
+func hasAudience(claim json.RawMessage, aud string) bool {
+	var one string
+	if json.Unmarshal(claim, &one) == nil {
+		return one == aud
+	}
+	var many []string
+	if json.Unmarshal(claim, &many) == nil {
+		for _, v := range many {
+			if v == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authSignature verifies HMAC-SHA256 signature of request headers, key id is principal.
+// Credentials are keyId="...",algorithm="hmac-sha256",headers="(request-target) date",signature="base64"
+func (a *Auth) authSignature(rh repo.RequestHeader, cred string) (string, error) {
+	params := parseParams(cred)
+	id := params["keyId"]
+
+	secret, ok := a.hmac[id]
+	switch {
+	case !ok:
+		return "", fmt.Errorf("in receiver.authSignature %w: unknown key %q", repo.ErrUnauthorized, id)
+	case len(params["algorithm"]) > 0 && !strings.EqualFold(params["algorithm"], "hmac-sha256"):
+		return "", fmt.Errorf("in receiver.authSignature %w: unsupported algorithm %q", repo.ErrUnauthorized, params["algorithm"])
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	for _, h := range a.hmacHeaders {
+		if !contains(headers, strings.ToLower(h)) {
+			return "", fmt.Errorf("in receiver.authSignature %w: header %q is not signed", repo.ErrUnauthorized, h)
+		}
+	}
+
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		if h == "(request-target)" {
+			lines = append(lines, h+": "+strings.ToLower(rh.Method)+" "+rh.Path)
+			continue
+		}
+		vs := rh.Fields.Values(h)
+		if len(vs) == 0 {
+			return "", fmt.Errorf("in receiver.authSignature %w: signed header %q is missing", repo.ErrUnauthorized, h)
+		}
+		lines = append(lines, h+": "+strings.Join(vs, ", "))
+	}
+
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(strings.Join(lines, "\n")))
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || !hmac.Equal(sig, m.Sum(nil)) {
+		return "", fmt.Errorf("in receiver.authSignature %w: wrong signature of %q", repo.ErrUnauthorized, id)
+	}
+
+	if contains(headers, "date") {
+		d, err := http.ParseTime(rh.Fields.Get("Date"))
+		if err != nil || a.now().Sub(d).Abs() > MaxSkew {
+			return "", fmt.Errorf("in receiver.authSignature %w: signed Date %q is too far from now", repo.ErrUnauthorized, rh.Fields.Get("Date"))
+		}
+	}
+	return id, nil
+}
+
+// parseParams parses comma separated name="value" pairs
+func parseParams(s string) map[string]string {
+	params := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if ok {
+			params[k] = strings.Trim(v, "\"")
+		}
+	}
+	return params
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+package receiver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/config"
+	"github.com/vynovikov/postParser/internal/repo"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type authSuite struct {
+	suite.Suite
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(authSuite))
+}
+
+// jwt returns token having given claims signed by ES256 key, alg is put into header as is
+func jwt(key *ecdsa.PrivateKey, alg, claims string) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+	sum := sha256.Sum256([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, sum[:])
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// signature returns Signature credentials of lines signed by secret
+func signature(id, secret, headers, lines string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(lines))
+
+	return `Signature keyId="` + id + `",algorithm="hmac-sha256",headers="` + headers + `",signature="` + base64.StdEncoding.EncodeToString(m.Sum(nil)) + `"`
+}
+
+func (s *authSuite) TestAuthenticate() {
+	dir := s.T().TempDir()
+
+	tokens := filepath.Join(dir, "tokens")
+	s.NoError(os.WriteFile(tokens, []byte("# principal token\nuploader s3cr3t-t0ken\n\n"), 0600))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.NoError(err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	s.NoError(err)
+	pub := filepath.Join(dir, "jwt.pem")
+	s.NoError(os.WriteFile(pub, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	a, err := NewAuth(config.Auth{
+		Basic:       map[string]string{"alice": "wonderland"},
+		TokenFile:   tokens,
+		JWTKey:      pub,
+		JWTIssuer:   "issuer",
+		JWTAudience: "postParser",
+		HMAC:        map[string]string{"partner": "shared"},
+	})
+	s.NoError(err)
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+
+	tt := []struct {
+		name    string
+		a       *Auth
+		fields  map[string]string
+		wantP   string
+		wantErr error
+	}{
+		{
+			name: "authentication is off",
+		},
+
+		{
+			name:   "basic",
+			a:      a,
+			fields: map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:wonderland"))},
+			wantP:  "alice",
+		},
+
+		{
+			name:   "bearer token from file",
+			a:      a,
+			fields: map[string]string{"Authorization": "Bearer s3cr3t-t0ken"},
+			wantP:  "uploader",
+		},
+
+		{
+			name:   "bearer JWT",
+			a:      a,
+			fields: map[string]string{"Authorization": "Bearer " + jwt(key, "ES256", `{"sub":"bob","iss":"issuer","aud":["other","postParser"],"exp":1680350460}`)},
+			wantP:  "bob",
+		},
+
+		{
+			name:   "HMAC signature",
+			a:      a,
+			fields: map[string]string{"Date": date, "Authorization": signature("partner", "shared", "(request-target) date", "(request-target): post /avatars\ndate: "+date)},
+			wantP:  "partner",
+		},
+
+		{
+			name:    "unhappy no credentials",
+			a:       a,
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy wrong password",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:looking-glass"))},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy unknown scheme",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Digest username=\"alice\""},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy unknown token",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Bearer t0ken"},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy JWT signed by other key",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Bearer " + jwt(other, "ES256", `{"sub":"bob","iss":"issuer","aud":"postParser"}`)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy JWT algorithm does not match key",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Bearer " + jwt(key, "none", `{"sub":"bob","iss":"issuer","aud":"postParser"}`)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy JWT is expired",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Bearer " + jwt(key, "ES256", `{"sub":"bob","iss":"issuer","aud":"postParser","exp":1680350400}`)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy JWT audience",
+			a:       a,
+			fields:  map[string]string{"Authorization": "Bearer " + jwt(key, "ES256", `{"sub":"bob","iss":"issuer","aud":"other"}`)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy wrong HMAC signature",
+			a:       a,
+			fields:  map[string]string{"Date": date, "Authorization": signature("partner", "guess", "(request-target) date", "(request-target): post /avatars\ndate: "+date)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name:    "unhappy required header is not signed",
+			a:       a,
+			fields:  map[string]string{"Date": date, "Authorization": signature("partner", "shared", "date", "date: "+date)},
+			wantErr: repo.ErrUnauthorized,
+		},
+
+		{
+			name: "unhappy signed Date is stale",
+			a:    a,
+			fields: map[string]string{
+				"Date":          now.Add(-time.Hour).Format(http.TimeFormat),
+				"Authorization": signature("partner", "shared", "(request-target) date", "(request-target): post /avatars\ndate: "+now.Add(-time.Hour).Format(http.TimeFormat)),
+			},
+			wantErr: repo.ErrUnauthorized,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			rh := repo.RequestHeader{Method: "POST", Path: "/avatars", Fields: textproto.MIMEHeader{}}
+			for k, f := range v.fields {
+				rh.Fields.Set(k, f)
+			}
+
+			p, err := v.a.Authenticate(rh)
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+
+				var ce *repo.ChallengeError
+				s.True(errors.As(err, &ce), err)
+				s.Equal([]string{`Basic realm="postParser"`, `Bearer realm="postParser"`, `Signature realm="postParser", headers="(request-target) date"`}, ce.Challenges)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantP, p)
+		})
+	}
+}
+
+func (s *authSuite) TestChallenges() {
+	tt := []struct {
+		name string
+		c    config.Auth
+		want []string
+	}{
+		{
+			name: "basic",
+			c:    config.Auth{Basic: map[string]string{"alice": "wonderland"}},
+			want: []string{`Basic realm="postParser"`},
+		},
+
+		{
+			name: "bearer tokens in realm",
+			c:    config.Auth{TokenFile: "tokens", Realm: "uploads"},
+			want: []string{`Bearer realm="uploads"`},
+		},
+
+		{
+			name: "bearer JWT and signature",
+			c:    config.Auth{JWTKey: "jwt.pem", HMAC: map[string]string{"partner": "shared"}},
+			want: []string{`Bearer realm="postParser"`, `Signature realm="postParser", headers="date digest"`},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, challenges(v.c, []string{"Date", "Digest"}))
+		})
+	}
+}
+
+func (s *authSuite) TestNewAuth() {
+	dir := s.T().TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	s.NoError(os.WriteFile(tokens, []byte("uploader\n"), 0600))
+
+	a, err := NewAuth(config.Auth{})
+	s.NoError(err)
+	s.Nil(a)
+
+	_, err = NewAuth(config.Auth{TokenFile: tokens})
+	s.Error(err)
+	s.Contains(err.Error(), "line 1")
+
+	_, err = NewAuth(config.Auth{JWTKey: tokens})
+	s.Error(err)
+	s.Contains(err.Error(), "no PEM data")
+}
@@ -0,0 +1,116 @@
+package receiver
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/logger"
+	"github.com/vynovikov/postParser/internal/repo"
+	"golang.org/x/net/http2"
+)
+
+// h2Preface starts every HTTP/2 connection
+const h2Preface = http2.ClientPreface
+
+// tlsConn is connection upgraded to TLS
+type tlsConn interface {
+	Handshake() error
+	ConnectionState() tls.ConnectionState
+}
+
+// servedConn is TLS connection knowing name of certificate served to client
+type servedConn interface {
+	ServedName() string
+}
+
+// bufConn is connection which data is partially read into br already
+type bufConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+// Read returns buffered data first, then reads from connection directly
+func (c *bufConn) Read(b []byte) (int, error) {
+	if c.br.Buffered() > 0 {
+		return c.br.Read(b)
+	}
+	return c.Conn.Read(b)
+}
+
+// handshake runs TLS handshake, it should be finished within idle timeout.
+// Connection is treated as idle until handshake is finished
+func (r *Receiver) handshake(conn net.Conn, tc tlsConn) error {
+	r.setIdle(conn, true)
+	defer r.setIdle(conn, false)
+
+	if r.T.Idle > 0 {
+		conn.SetDeadline(time.Now().Add(r.T.Idle))
+		defer conn.SetDeadline(time.Time{})
+	}
+	return tc.Handshake()
+}
+
+// h2 returns servers handling HTTP/2 connections, they are created on first use
+func (r *Receiver) h2() (*http.Server, *http2.Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hs == nil {
+		r.hs, r.h2s = &http.Server{}, &http2.Server{IdleTimeout: r.T.Idle}
+		http2.ConfigureServer(r.hs, r.h2s)
+	}
+	return r.hs, r.h2s
+}
+
+// serveH2 serves HTTP/2 connection until it is closed. Every stream is independent request having its own TS
+func (r *Receiver) serveH2(conn net.Conn) {
+	conn.SetDeadline(time.Time{}) // HTTP/2 server handles timeouts itself
+
+	client := newClient(conn)
+	hs, h2s := r.h2()
+
+	h2s.ServeConn(conn, &http2.ServeConnOpts{
+		BaseConfig: hs,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			repo.WriteResult(w, r.handleStream(client, req))
+		}),
+	})
+}
+
+// handleStream feeds application with HTTP/2 request and returns result of its handling.
+// Tested in tp/http_test.go and tps/https_test.go
+func (r *Receiver) handleStream(client repo.Client, req *http.Request) repo.Result {
+	ts := repo.NewTS()
+
+	rh, err := repo.NewRequestHeader(req.Method, req.URL.RequestURI(), req.Proto, textproto.MIMEHeader(req.Header))
+	var route repo.Route
+	if err == nil {
+		route, err = r.admit(rh, &client)
+	}
+	if err != nil {
+		logger.L.Errorf("in receiver.handleStream rejected request from %v: %v\n", client.Addr, err)
+		return repo.NewResultErr(ts, err)
+	}
+
+	end := time.Time{}
+	if r.T.Request > 0 {
+		end = time.Now().Add(r.T.Request)
+	}
+	return r.handle(ts, client, route, req.Body, rh, end)
+}
+
+// stopH2 starts graceful shutdown of HTTP/2 connections if there are any
+func (r *Receiver) stopH2() {
+	r.mu.Lock()
+	hs := r.hs
+	r.mu.Unlock()
+
+	if hs != nil {
+		hs.Shutdown(context.Background())
+	}
+}
@@ -0,0 +1,279 @@
+package receiver
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/config"
+	"github.com/vynovikov/postParser/internal/repo"
+)
+
+// Rejected counts requests and connections rejected by limits, keyed by limit name
+var Rejected = expvar.NewMap("limits_rejected")
+
+// connRetry is Retry-After of connection rejected by connection limits
+const connRetry = time.Second
+
+// sweepPeriod is how often state of clients having nothing limited is dropped
+const sweepPeriod = time.Minute
+
+// Limiter restricts concurrent connections, request and body byte rates globally and per client IP.
+// Nil Limiter restricts nothing
+type Limiter struct {
+	c     config.Limits
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	mu    sync.Mutex
+	all   clientState
+	ips   map[string]*clientState
+	swept time.Time
+}
+
+// clientState is what Limiter knows about client or all of them
+type clientState struct {
+	conns int
+	reqs  bucket
+	bytes bucket
+}
+
+// NewLimiter returns Limiter enforcing c, nil if no limit is set
+func NewLimiter(c config.Limits) *Limiter {
+	if !c.Enabled() {
+		return nil
+	}
+	return &Limiter{
+		c:     c,
+		now:   time.Now,
+		sleep: time.Sleep,
+		all:   clientState{reqs: bucket{rate: c.Requests}, bytes: bucket{rate: c.Bytes}},
+		ips:   make(map[string]*clientState),
+	}
+}
+
+// Conn reserves connection slot for client ip, returned func frees it.
+// Tested in limits_test.go
+func (l *Limiter) Conn(ip string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.client(ip)
+	switch {
+	case l.c.Conns > 0 && l.all.conns >= l.c.Conns:
+		return nil, l.reject("conns", connRetry, "%d concurrent connections", l.c.Conns)
+	case l.c.ConnsPerIP > 0 && c.conns >= l.c.ConnsPerIP:
+		return nil, l.reject("connsPerIP", connRetry, "%d concurrent connections from %s", l.c.ConnsPerIP, ip)
+	}
+	l.all.conns++
+	c.conns++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.all.conns--
+			c.conns--
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// Request takes request token of client ip. Request is rejected if there are no tokens
+// or body bytes of client are run out.
+// Tested in limits_test.go
+func (l *Limiter) Request(ip string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now, c := l.now(), l.client(ip)
+	if d := l.all.reqs.wait(now); d > 0 {
+		return l.reject("requests", d, "%g requests per second", l.c.Requests)
+	}
+	if d := c.reqs.wait(now); d > 0 {
+		return l.reject("requestsPerIP", d, "%g requests per second from %s", l.c.RequestsPerIP, ip)
+	}
+	if d := l.all.bytes.wait(now); d > 0 {
+		return l.reject("bytes", d, "%g bytes per second", l.c.Bytes)
+	}
+	if d := c.bytes.wait(now); d > 0 {
+		return l.reject("bytesPerIP", d, "%g bytes per second from %s", l.c.BytesPerIP, ip)
+	}
+	l.all.reqs.take(1)
+	c.reqs.take(1)
+
+	return nil
+}
+
+// Body returns reader of body sent by client ip, bytes read are taken from byte buckets.
+// Reading waits until buckets are out of debt, so body is read no faster than byte rates allow.
+// Reading fails with error allowing retry once buckets are out of debt if waiting passes request deadline end,
+// zero end means there is no deadline.
+// Tested in limits_test.go
+func (l *Limiter) Body(ip string, end time.Time, body io.Reader) io.Reader {
+	if l == nil || (l.c.Bytes <= 0 && l.c.BytesPerIP <= 0) {
+		return body
+	}
+	return &countingReader{r: body, l: l, ip: ip, end: end}
+}
+
+// client returns state of client ip, state of idle clients is dropped from time to time.
+// l.mu should be locked
+func (l *Limiter) client(ip string) *clientState {
+	now := l.now()
+	if now.Sub(l.swept) > sweepPeriod {
+		for k, c := range l.ips {
+			if c.conns == 0 && c.reqs.full(now) && c.bytes.full(now) {
+				delete(l.ips, k)
+			}
+		}
+		l.swept = now
+	}
+
+	c, ok := l.ips[ip]
+	if !ok {
+		c = &clientState{reqs: bucket{rate: l.c.RequestsPerIP}, bytes: bucket{rate: l.c.BytesPerIP}}
+		l.ips[ip] = c
+	}
+	return c
+}
+
+// reject counts rejection by limit name and returns error allowing retry after d
+func (l *Limiter) reject(name string, d time.Duration, format string, args ...interface{}) error {
+	Rejected.Add(name, 1)
+
+	return &repo.RetryError{
+		Err:   fmt.Errorf("in receiver.Limiter %w: limit of "+format+" is exceeded", append([]interface{}{repo.ErrTooManyRequests}, args...)...),
+		After: d,
+	}
+}
+
+// take removes n bytes read by client ip from byte buckets, returns time left until buckets are out of debt
+func (l *Limiter) take(ip string, n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now, c := l.now(), l.client(ip)
+	l.all.bytes.wait(now)
+	c.bytes.wait(now)
+	l.all.bytes.take(float64(n))
+	c.bytes.take(float64(n))
+
+	d, dIP := l.all.bytes.debt(), c.bytes.debt()
+	if dIP > d {
+		return dIP
+	}
+	return d
+}
+
+// countingReader takes bytes read from r from byte buckets of client ip, waiting for buckets to be out of debt
+type countingReader struct {
+	r   io.Reader
+	l   *Limiter
+	ip  string
+	end time.Time // request deadline, zero if there is none
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if n == 0 {
+		return n, err
+	}
+	d := c.l.take(c.ip, n)
+	if d <= 0 {
+		return n, err
+	}
+	if !c.end.IsZero() && c.l.now().Add(d).After(c.end) {
+		Rejected.Add("bytesDeadline", 1)
+		return n, &repo.RetryError{
+			Err:   fmt.Errorf("in receiver.countingReader %w: waiting %v for byte rate limit passes request deadline", repo.ErrTooManyRequests, d),
+			After: d,
+		}
+	}
+	c.l.sleep(d)
+
+	return n, err
+}
+
+// bucket is token bucket filled at rate tokens per second, it holds one second worth of tokens but at least one.
+// Zero rate means bucket is never empty
+type bucket struct {
+	rate   float64
+	tokens float64
+	at     time.Time
+}
+
+// size returns capacity of b
+func (b *bucket) size() float64 {
+	if b.rate < 1 {
+		return 1
+	}
+	return b.rate
+}
+
+// refill adds tokens accumulated since the last refill
+func (b *bucket) refill(now time.Time) {
+	if b.at.IsZero() {
+		b.tokens, b.at = b.size(), now
+		return
+	}
+	if now.After(b.at) {
+		b.tokens += b.rate * now.Sub(b.at).Seconds()
+		if s := b.size(); b.tokens > s {
+			b.tokens = s
+		}
+		b.at = now
+	}
+}
+
+// wait refills b and returns time left until it has whole token, zero if it has one already
+func (b *bucket) wait(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// debt returns time left until b has no debt, b should be refilled
+func (b *bucket) debt() time.Duration {
+	if b.rate <= 0 || b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// take removes n tokens, b may go into debt
+func (b *bucket) take(n float64) {
+	if b.rate > 0 {
+		b.tokens -= n
+	}
+}
+
+// full returns true if b would be full at now
+func (b *bucket) full(now time.Time) bool {
+	if b.rate <= 0 || b.at.IsZero() {
+		return true
+	}
+	return b.tokens+b.rate*now.Sub(b.at).Seconds() >= b.size()
+}
+
+// ipOf returns IP part of client address, whole address if it has no port
+func ipOf(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
+}
@@ -0,0 +1,254 @@
+package receiver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/config"
+	"github.com/vynovikov/postParser/internal/repo"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type limitsSuite struct {
+	suite.Suite
+}
+
+func TestLimitsSuite(t *testing.T) {
+	suite.Run(t, new(limitsSuite))
+}
+
+// newTestLimiter returns limiter enforcing c, its clock is moved by returned func.
+// Reading body never sleeps
+func newTestLimiter(c config.Limits) (*Limiter, func(time.Duration)) {
+	l, now := NewLimiter(c), time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	l.sleep = func(time.Duration) {}
+
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func (s *limitsSuite) TestConn() {
+	l, _ := newTestLimiter(config.Limits{Conns: 3, ConnsPerIP: 2})
+
+	r1, err := l.Conn("10.0.0.1")
+	s.NoError(err)
+	_, err = l.Conn("10.0.0.1")
+	s.NoError(err)
+
+	_, err = l.Conn("10.0.0.1")
+	s.ErrorIs(err, repo.ErrTooManyRequests)
+	s.Contains(err.Error(), "from 10.0.0.1")
+
+	r3, err := l.Conn("10.0.0.2")
+	s.NoError(err)
+
+	_, err = l.Conn("10.0.0.3")
+	s.ErrorIs(err, repo.ErrTooManyRequests)
+	s.Equal(http.StatusTooManyRequests, repo.NewResultErr("qqq", err).Status)
+	s.Equal(connRetry, repo.NewResultErr("qqq", err).RetryAfter)
+
+	r1()
+	r1() // releasing twice frees single slot
+	r3()
+	_, err = l.Conn("10.0.0.1")
+	s.NoError(err)
+	_, err = l.Conn("10.0.0.3")
+	s.NoError(err)
+	_, err = l.Conn("10.0.0.4")
+	s.ErrorIs(err, repo.ErrTooManyRequests)
+
+	var nl *Limiter
+	release, err := nl.Conn("10.0.0.1")
+	s.NoError(err)
+	release()
+}
+
+func (s *limitsSuite) TestRequest() {
+	tt := []struct {
+		name      string
+		c         config.Limits
+		before    func(*Limiter, func(time.Duration))
+		ip        string
+		wantErr   bool
+		wantAfter time.Duration
+	}{
+		{
+			name: "unlimited",
+			ip:   "10.0.0.1",
+		},
+
+		{
+			name: "burst fits",
+			c:    config.Limits{RequestsPerIP: 2},
+			before: func(l *Limiter, _ func(time.Duration)) {
+				l.Request("10.0.0.1")
+			},
+			ip: "10.0.0.1",
+		},
+
+		{
+			name: "tokens are refilled",
+			c:    config.Limits{RequestsPerIP: 2},
+			before: func(l *Limiter, move func(time.Duration)) {
+				l.Request("10.0.0.1")
+				l.Request("10.0.0.1")
+				move(500 * time.Millisecond)
+			},
+			ip: "10.0.0.1",
+		},
+
+		{
+			name: "other client is not limited",
+			c:    config.Limits{RequestsPerIP: 1},
+			before: func(l *Limiter, _ func(time.Duration)) {
+				l.Request("10.0.0.1")
+			},
+			ip: "10.0.0.2",
+		},
+
+		{
+			name: "unhappy per IP rate",
+			c:    config.Limits{RequestsPerIP: 2},
+			before: func(l *Limiter, move func(time.Duration)) {
+				l.Request("10.0.0.1")
+				l.Request("10.0.0.1")
+				move(250 * time.Millisecond)
+			},
+			ip:        "10.0.0.1",
+			wantErr:   true,
+			wantAfter: 250 * time.Millisecond,
+		},
+
+		{
+			name: "unhappy rate below one request per second",
+			c:    config.Limits{Requests: 0.5},
+			before: func(l *Limiter, _ func(time.Duration)) {
+				l.Request("10.0.0.1")
+			},
+			ip:        "10.0.0.2",
+			wantErr:   true,
+			wantAfter: 2 * time.Second,
+		},
+
+		{
+			name: "unhappy body bytes are run out",
+			c:    config.Limits{BytesPerIP: 4},
+			before: func(l *Limiter, _ func(time.Duration)) {
+				io.ReadAll(l.Body("10.0.0.1", time.Time{}, strings.NewReader("azazaza")))
+			},
+			ip:        "10.0.0.1",
+			wantErr:   true,
+			wantAfter: time.Second,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			var l *Limiter
+			if v.c.Enabled() {
+				var move func(time.Duration)
+				l, move = newTestLimiter(v.c)
+				if v.before != nil {
+					v.before(l, move)
+				}
+			}
+
+			err := l.Request(v.ip)
+			if !v.wantErr {
+				s.NoError(err)
+				return
+			}
+			s.True(errors.Is(err, repo.ErrTooManyRequests), err)
+			var re *repo.RetryError
+			s.True(errors.As(err, &re))
+			s.Equal(v.wantAfter, re.After)
+		})
+	}
+}
+
+func (s *limitsSuite) TestSweep() {
+	l, move := newTestLimiter(config.Limits{ConnsPerIP: 1, RequestsPerIP: 1})
+
+	release, err := l.Conn("10.0.0.1")
+	s.NoError(err)
+	s.NoError(l.Request("10.0.0.2"))
+
+	move(2 * sweepPeriod)
+	l.Request("10.0.0.3")
+	s.Len(l.ips, 2) // 10.0.0.1 is connected
+
+	release()
+	move(2 * sweepPeriod)
+	l.Request("10.0.0.3")
+	s.Len(l.ips, 1)
+}
+
+func (s *limitsSuite) TestBody() {
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name      string
+		c         config.Limits
+		end       time.Time
+		wantWait  time.Duration
+		wantErr   bool
+		wantRetry string // Retry-After of rejected request
+	}{
+		{
+			name:     "body is throttled by client byte rate",
+			c:        config.Limits{BytesPerIP: 100},
+			wantWait: 9 * time.Second,
+		},
+
+		{
+			name:     "body is throttled by global byte rate",
+			c:        config.Limits{Bytes: 200, BytesPerIP: 1000},
+			wantWait: 4 * time.Second,
+		},
+
+		{
+			name:     "waiting is within deadline",
+			c:        config.Limits{BytesPerIP: 100},
+			end:      start.Add(10 * time.Second),
+			wantWait: 9 * time.Second,
+		},
+
+		{
+			name:      "unhappy waiting passes deadline",
+			c:         config.Limits{BytesPerIP: 100},
+			end:       start.Add(5 * time.Second),
+			wantWait:  5 * time.Second,
+			wantErr:   true,
+			wantRetry: "1",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			l, move := newTestLimiter(v.c)
+			waited := time.Duration(0)
+			l.sleep = func(d time.Duration) {
+				waited += d
+				move(d)
+			}
+
+			n, err := io.Copy(io.Discard, iotest.OneByteReader(l.Body("10.0.0.1", v.end, strings.NewReader(strings.Repeat("a", 1000)))))
+			s.Equal(v.wantWait, waited)
+			if v.wantErr {
+				s.ErrorIs(err, repo.ErrTooManyRequests)
+
+				w := httptest.NewRecorder()
+				repo.WriteResult(w, repo.NewResultErr("1", err))
+				s.Equal(http.StatusTooManyRequests, w.Code)
+				s.Equal(v.wantRetry, w.Header().Get("Retry-After"))
+				return
+			}
+			s.NoError(err)
+			s.Equal(int64(1000), n)
+		})
+	}
+}
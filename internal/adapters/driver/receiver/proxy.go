@@ -0,0 +1,202 @@
+package receiver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrProxyHeader = errors.New("invalid PROXY protocol header")
+
+var (
+	proxyV1Prefix = []byte("PROXY ")
+	proxyV2Sig    = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const proxyV1MaxLen = 107 // including CRLF
+
+// proxyConn is connection with client address taken from PROXY protocol header
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader // holds bytes read after header
+	remote net.Addr
+	local  net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// Proxy returns upgrade reading PROXY protocol v1 or v2 header which should be sent by peer before anything else.
+// Connection without valid header is rejected. Header should arrive within timeout, zero timeout means no restriction.
+// Peer address is kept if header carries no addresses, e.g. on health checks
+func Proxy(timeout time.Duration) Upgrade {
+	return func(conn net.Conn) (net.Conn, error) {
+		if timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+			defer conn.SetReadDeadline(time.Time{})
+		}
+
+		br := bufio.NewReader(conn)
+
+		src, dst, err := ReadProxyHeader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		pc := &proxyConn{
+			Conn:   conn,
+			r:      br,
+			remote: conn.RemoteAddr(),
+			local:  conn.LocalAddr(),
+		}
+		if src != nil {
+			pc.remote, pc.local = src, dst
+		}
+		return pc, nil
+	}
+}
+
+// Chain returns upgrade applying us one after another
+func Chain(us ...Upgrade) Upgrade {
+	return func(conn net.Conn) (net.Conn, error) {
+		for _, u := range us {
+			c, err := u(conn)
+			if err != nil {
+				return nil, err
+			}
+			conn = c
+		}
+		return conn, nil
+	}
+}
+
+// ReadProxyHeader reads PROXY protocol header of any version from br.
+// Returns nil addresses if header is valid but has no addresses.
+// Tested in proxy_test.go
+func ReadProxyHeader(br *bufio.Reader) (src, dst net.Addr, err error) {
+	b, err := br.Peek(len(proxyV1Prefix))
+	if err != nil {
+		return nil, nil, fmt.Errorf("in receiver.ReadProxyHeader %w: %v", ErrProxyHeader, err)
+	}
+	if bytes.Equal(b, proxyV1Prefix) {
+		return readProxyV1(br)
+	}
+	if bytes.HasPrefix(proxyV2Sig, b) {
+		return readProxyV2(br)
+	}
+	return nil, nil, fmt.Errorf("in receiver.ReadProxyHeader %w: no header found", ErrProxyHeader)
+}
+
+// readProxyV1 reads human-readable header like "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n"
+func readProxyV1(br *bufio.Reader) (net.Addr, net.Addr, error) {
+	line := make([]byte, 0, proxyV1MaxLen)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == proxyV1MaxLen {
+			return nil, nil, fmt.Errorf("in receiver.readProxyV1 %w: header is too long", ErrProxyHeader)
+		}
+		c, err := br.ReadByte()
+		if err != nil {
+			return nil, nil, fmt.Errorf("in receiver.readProxyV1 %w: %v", ErrProxyHeader, err)
+		}
+		line = append(line, c)
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) > 1 && fields[1] == "UNKNOWN" { // the rest of line is ignored
+		return nil, nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV1 %w: %q", ErrProxyHeader, line)
+	}
+
+	src, err := tcpAddr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := tcpAddr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+// tcpAddr parses address of v1 header, family is TCP4 or TCP6
+func tcpAddr(family, ip, port string) (*net.TCPAddr, error) {
+	a, v6 := net.ParseIP(ip), strings.Contains(ip, ":")
+	if a == nil || !(family == "TCP4" && !v6 || family == "TCP6" && v6) {
+		return nil, fmt.Errorf("in receiver.tcpAddr %w: %s address %q", ErrProxyHeader, family, ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("in receiver.tcpAddr %w: port %q", ErrProxyHeader, port)
+	}
+	return &net.TCPAddr{IP: a, Port: int(p)}, nil
+}
+
+// readProxyV2 reads binary header. Only TCP over IPv4 and IPv6 addresses are taken, TLVs are skipped
+func readProxyV2(br *bufio.Reader) (net.Addr, net.Addr, error) {
+	h := make([]byte, 16)
+	if _, err := io.ReadFull(br, h); err != nil {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: %v", ErrProxyHeader, err)
+	}
+	if !bytes.Equal(h[:12], proxyV2Sig) {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: wrong signature", ErrProxyHeader)
+	}
+	if h[12]>>4 != 2 {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: version %d", ErrProxyHeader, h[12]>>4)
+	}
+
+	body := make([]byte, binary.BigEndian.Uint16(h[14:]))
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: %v", ErrProxyHeader, err)
+	}
+
+	switch h[12] & 0x0f {
+	case 0: // LOCAL, connection is made by proxy itself
+		return nil, nil, nil
+	case 1: // PROXY
+	default:
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: command %d", ErrProxyHeader, h[12]&0x0f)
+	}
+
+	var n int
+	switch h[13] {
+	case 0x00, 0x31, 0x32: // UNSPEC and UNIX
+		return nil, nil, nil
+	case 0x11: // TCP over IPv4
+		n = net.IPv4len
+	case 0x21: // TCP over IPv6
+		n = net.IPv6len
+	default:
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: unsupported family and protocol 0x%02x", ErrProxyHeader, h[13])
+	}
+	if len(body) < 2*n+4 {
+		return nil, nil, fmt.Errorf("in receiver.readProxyV2 %w: address block is too short", ErrProxyHeader)
+	}
+
+	src := &net.TCPAddr{
+		IP:   net.IP(append([]byte{}, body[:n]...)),
+		Port: int(binary.BigEndian.Uint16(body[2*n:])),
+	}
+	dst := &net.TCPAddr{
+		IP:   net.IP(append([]byte{}, body[n:2*n]...)),
+		Port: int(binary.BigEndian.Uint16(body[2*n+2:])),
+	}
+	return src, dst, nil
+}
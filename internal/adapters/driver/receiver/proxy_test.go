@@ -0,0 +1,213 @@
+package receiver
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type proxySuite struct {
+	suite.Suite
+}
+
+func TestProxySuite(t *testing.T) {
+	suite.Run(t, new(proxySuite))
+}
+
+// v2 builds binary header with command cmd, family and protocol fp and address block addr
+func v2(cmd, fp byte, addr ...byte) string {
+	return "\r\n\r\n\x00\r\nQUIT\n" + string([]byte{0x20 | cmd, fp, byte(len(addr) >> 8), byte(len(addr))}) + string(addr)
+}
+
+func (s *proxySuite) TestReadProxyHeader() {
+	tt := []struct {
+		name     string
+		in       string
+		wantSrc  net.Addr
+		wantDst  net.Addr
+		wantRest string
+		wantErr  error
+	}{
+		{
+			name:     "v1 TCP4",
+			in:       "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\nPOST / HTTP/1.1\r\n",
+			wantSrc:  &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324},
+			wantDst:  &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 443},
+			wantRest: "POST / HTTP/1.1\r\n",
+		},
+
+		{
+			name:     "v1 TCP6",
+			in:       "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nPOST",
+			wantSrc:  &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+			wantDst:  &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+			wantRest: "POST",
+		},
+
+		{
+			name:     "v1 UNKNOWN",
+			in:       "PROXY UNKNOWN ffff:f...f:ffff 65535 65535\r\nPOST",
+			wantRest: "POST",
+		},
+
+		{
+			name:     "v2 TCP4",
+			in:       v2(1, 0x11, 192, 0, 2, 1, 192, 0, 2, 2, 0xdc, 0x04, 0x01, 0xbb) + "POST",
+			wantSrc:  &net.TCPAddr{IP: net.IP{192, 0, 2, 1}, Port: 56324},
+			wantDst:  &net.TCPAddr{IP: net.IP{192, 0, 2, 2}, Port: 443},
+			wantRest: "POST",
+		},
+
+		{
+			name: "v2 TCP6 with TLV",
+			in: v2(1, 0x21,
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2,
+				0xdc, 0x04, 0x01, 0xbb,
+				0x04, 0x00, 0x01, 0x00) + "POST",
+			wantSrc:  &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+			wantDst:  &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+			wantRest: "POST",
+		},
+
+		{
+			name:     "v2 LOCAL",
+			in:       v2(0, 0x00) + "POST",
+			wantRest: "POST",
+		},
+
+		{
+			name:    "unhappy no header",
+			in:      "POST / HTTP/1.1\r\n",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy connection closed",
+			in:      "PRO",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v1 without CRLF",
+			in:      "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443" + strings.Repeat(" ", 100),
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v1 family mismatch",
+			in:      "PROXY TCP4 2001:db8::1 2001:db8::2 56324 443\r\n",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v1 port",
+			in:      "PROXY TCP4 192.0.2.1 192.0.2.2 65536 443\r\n",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v1 missing field",
+			in:      "PROXY TCP4 192.0.2.1 192.0.2.2 56324\r\n",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v2 version",
+			in:      "\r\n\r\n\x00\r\nQUIT\n\x11\x11\x00\x00",
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v2 UDP",
+			in:      v2(1, 0x12, 192, 0, 2, 1, 192, 0, 2, 2, 0xdc, 0x04, 0x01, 0xbb),
+			wantErr: ErrProxyHeader,
+		},
+
+		{
+			name:    "unhappy v2 short address block",
+			in:      v2(1, 0x11, 192, 0, 2, 1),
+			wantErr: ErrProxyHeader,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			br := bufio.NewReader(strings.NewReader(v.in))
+
+			src, dst, err := ReadProxyHeader(br)
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantSrc, src)
+			s.Equal(v.wantDst, dst)
+
+			rest, _ := io.ReadAll(br)
+			s.Equal(v.wantRest, string(rest))
+		})
+	}
+}
+
+func (s *proxySuite) TestProxy() {
+	tt := []struct {
+		name       string
+		in         string
+		wantRemote string
+		wantErr    error
+	}{
+		{
+			name:       "address is taken from header",
+			in:         "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\nPOST",
+			wantRemote: "192.0.2.1:56324",
+		},
+
+		{
+			name:       "address is kept on LOCAL",
+			in:         v2(0, 0x00) + "POST",
+			wantRemote: "pipe",
+		},
+
+		{
+			name:    "unhappy no header",
+			in:      "POST / HTTP/1.1\r\n",
+			wantErr: ErrProxyHeader,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			cl, sr := net.Pipe()
+			defer sr.Close()
+
+			go func() {
+				io.WriteString(cl, v.in)
+				cl.Close()
+			}()
+
+			conn, err := Proxy(time.Second)(sr)
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantRemote, conn.RemoteAddr().String())
+
+			rest, _ := io.ReadAll(conn)
+			s.Equal("POST", string(rest))
+		})
+	}
+}
+
+func (s *proxySuite) TestProxyTimeout() {
+	cl, sr := net.Pipe()
+	defer cl.Close()
+
+	_, err := Proxy(time.Millisecond * 20)(sr)
+	s.True(errors.Is(err, ErrProxyHeader), err)
+}
@@ -0,0 +1,326 @@
+// Receiver engine shared by HTTP and HTTPS receivers.
+//
+// Receiver accepts connections from any net.Listener. Accepted connection may be upgraded before serving, TLS and PROXY protocol are upgrades
+package receiver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/logger"
+	"github.com/vynovikov/postParser/internal/repo"
+	"golang.org/x/net/http2"
+)
+
+// Upgrade wraps accepted connection, e.g. into TLS
+type Upgrade func(net.Conn) (net.Conn, error)
+
+type Receiver struct {
+	A      application.Application
+	T      repo.Timeouts
+	U      Upgrade     // nil means connection is served as is
+	Routes repo.Routes // empty table passes every request to default route
+	Auth   *Auth       // nil means requests are not authenticated
+	Limits *Limiter    // nil means clients are not limited
+	Upload repo.UploadLimits
+	Decode repo.Decoding // how bodies other than multipart are converted into multipart
+
+	l  net.Listener
+	wg sync.WaitGroup
+
+	mu   sync.Mutex
+	idle map[net.Conn]struct{} // connections waiting for next request
+	hs   *http.Server          // HTTP/2 servers, nil until first HTTP/2 connection
+	h2s  *http2.Server
+}
+
+// New returns receiver accepting connections from l, upgrading them by u and restricting them by t
+func New(a application.Application, l net.Listener, u Upgrade, t repo.Timeouts) *Receiver {
+	return &Receiver{
+		A: a,
+		T: t,
+		U: u,
+		l: l,
+	}
+}
+
+// Run accepts connections until listener is closed
+func (r *Receiver) Run() {
+	for {
+		conn, err := r.l.Accept()
+		if err != nil {
+			if r.A.Stopping() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.L.Errorf("in receiver.Run accept error: %v\n", err)
+			continue
+		}
+
+		r.wg.Add(1)
+		ts := repo.NewTS()
+
+		go r.HandleRequest(conn, ts, &r.wg)
+	}
+}
+
+// HandleRequest serves requests coming one after another through conn.
+// Every request except the first gets its own TS. HTTP/2 connection is passed to HTTP/2 server,
+// it is recognized by ALPN on TLS connection or by client preface on plain one.
+// Tested in tp/http_test.go and tps/https_test.go
+func (r *Receiver) HandleRequest(conn net.Conn, ts string, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if r.U != nil {
+		uc, err := r.U(conn)
+		if err != nil {
+			logger.L.Errorf("in receiver.HandleRequest cannot upgrade connection from %v: %v\n", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		conn = uc
+	}
+	defer conn.Close()
+
+	tc, isTLS := conn.(tlsConn)
+
+	release, err := r.Limits.Conn(ipOf(newClient(conn).Addr))
+	if err != nil {
+		r.reject(conn, tc, ts, err)
+		return
+	}
+	defer release()
+
+	if isTLS {
+		if err := r.handshake(conn, tc); err != nil {
+			logger.L.Errorf("in receiver.HandleRequest TLS handshake with %v failed: %v\n", conn.RemoteAddr(), err)
+			return
+		}
+		if tc.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			r.serveH2(conn)
+			return
+		}
+	}
+
+	dr := repo.NewDeadlineReader(conn, r.T)
+	br := bufio.NewReader(dr)
+
+	h2c := !isTLS // HTTP/2 without TLS is allowed with prior knowledge only
+	for r.serve(conn, dr, br, ts, h2c) {
+		ts, h2c = repo.NewTS(), false
+	}
+}
+
+// serve reads single request from br and responds to it. If h2c is true, request may be HTTP/2 client preface.
+// Returns true if connection should be kept alive
+func (r *Receiver) serve(conn net.Conn, dr *repo.DeadlineReader, br *bufio.Reader, ts string, h2c bool) bool {
+	dr.End()
+	r.setIdle(conn, true)
+	if r.A.Stopping() {
+		r.setIdle(conn, false)
+		return false
+	}
+	_, err := br.Peek(1) // waiting for request
+	r.setIdle(conn, false)
+	if err != nil {
+		return false
+	}
+	dr.Start()
+
+	if b, _ := br.Peek(1); h2c && b[0] == h2Preface[0] {
+		if b, _ = br.Peek(len(h2Preface)); string(b) == h2Preface {
+			r.serveH2(&bufConn{Conn: conn, br: br})
+			return false
+		}
+	}
+
+	client := newClient(conn)
+
+	rh, err := repo.AnalyzeHeader(br)
+	var route repo.Route
+	if err == nil {
+		route, err = r.admit(rh, &client)
+	}
+	if err != nil { // body is left unread
+		logger.L.Errorf("in receiver.serve rejected request from %v: %v\n", conn.RemoteAddr(), err)
+		repo.RespondResult(conn, repo.NewResultErr(ts, err), "close")
+		return false
+	}
+	if rh.ExpectsContinue() && br.Buffered() == 0 { // client waits for request to be admitted before sending body
+		repo.RespondContinue(conn)
+	}
+
+	res := r.handle(ts, client, route, repo.NewBodyReader(br, rh), rh, dr.Deadline())
+
+	// body framed by closing boundary may be followed by epilogue
+	keep := res.Status == http.StatusOK && rh.KeepAlive() && !r.A.Stopping() &&
+		(rh.ContentLength >= 0 || rh.TransferEncoding == "chunked")
+
+	switch {
+	case !keep:
+		repo.RespondResult(conn, res, "close")
+	case rh.Proto == "HTTP/1.0":
+		repo.RespondResult(conn, res, "keep-alive")
+	default:
+		repo.RespondResult(conn, res, "")
+	}
+	return keep
+}
+
+// admit checks and authenticates request having header rh, returns route request is passed to.
+// Principal is recorded in client
+func (r *Receiver) admit(rh repo.RequestHeader, client *repo.Client) (repo.Route, error) {
+	if err := r.Limits.Request(ipOf(client.Addr)); err != nil {
+		return repo.Route{}, err
+	}
+	if err := rh.Check(); err != nil {
+		return repo.Route{}, err
+	}
+	if err := r.Upload.Check(rh); err != nil {
+		return repo.Route{}, err
+	}
+	p, err := r.Auth.Authenticate(rh)
+	if err != nil {
+		return repo.Route{}, err
+	}
+	client.Principal = p
+
+	route, err := r.Routes.Match(rh.Method, rh.Path)
+	if err != nil {
+		return route, err
+	}
+	if err = route.Allows(p); err != nil {
+		return route, err
+	}
+	return route, route.Check(rh)
+}
+
+// handle feeds application with multipart body made of request body read from rd and returns result of request handling.
+// Reading stops at the first error, e.g. when body exceeds limits. Application aborts request then.
+// Waiting for byte rate limits is bounded by request deadline end
+func (r *Receiver) handle(ts string, client repo.Client, route repo.Route, rd io.Reader, rh repo.RequestHeader, end time.Time) repo.Result {
+	bou := rh.Bou
+	rd = r.Upload.LimitBody(route.LimitBody(r.Limits.Body(ipOf(client.Addr), end, rd)))
+	parts := new(repo.Parts)
+	rd = r.Upload.Watch(r.Decode.Multipart(rd, rh, parts), bou)
+	body, p := repo.NewClosingWatcher(rd, bou), 0
+	var bodyErr error
+
+	for {
+		h := repo.NewReceiverHeader(ts, p, bou)
+		h.Client, h.Route, h.Parts = client, route.Name, parts
+		b, err := repo.AnalyzeBits(body, 1024, p)
+
+		u := repo.NewReceiverUnit(h, b)
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.L.Errorf("in receiver.handle reading body from %v %q error: %v\n", client.Addr, client.Principal, err)
+				bodyErr = err
+			}
+			if errors.Is(err, repo.ErrBodyEmpty) {
+				break
+			}
+			u.H.Unblock = true
+			r.A.AddToFeeder(u)
+			break
+		}
+
+		r.A.AddToFeeder(u)
+
+		p++
+	}
+
+	if bodyErr == nil && !body.Met() {
+		bodyErr = fmt.Errorf("in receiver.serve %w: closing boundary is missing", repo.ErrBodyMalformed)
+	}
+
+	if bodyErr != nil {
+		r.A.Abort(ts)
+		return repo.NewResultErr(ts, bodyErr)
+	}
+	return r.A.Await(ts, r.T.Response)
+}
+
+// reject responds to the first request coming through conn by error err and closes conn.
+// Request should come within idle timeout, HTTP/2 connection is closed at once
+func (r *Receiver) reject(conn net.Conn, tc tlsConn, ts string, err error) {
+	logger.L.Errorf("in receiver.HandleRequest rejected connection from %v: %v\n", conn.RemoteAddr(), err)
+
+	if tc != nil {
+		if r.handshake(conn, tc) != nil || tc.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			return
+		}
+	}
+	if r.T.Idle > 0 {
+		conn.SetDeadline(time.Now().Add(r.T.Idle))
+	}
+	if _, rerr := repo.AnalyzeHeader(bufio.NewReader(conn)); rerr != nil {
+		return
+	}
+	repo.RespondResult(conn, repo.NewResultErr(ts, err), "close")
+}
+
+// newClient returns description of client connected through conn.
+// Certificate identity and server name are taken if conn is TLS connection.
+// Server name is name of certificate served if conn knows it, name requested through SNI otherwise
+func newClient(conn net.Conn) repo.Client {
+	c := repo.Client{}
+	if a := conn.RemoteAddr(); a != nil {
+		c.Addr = a.String()
+	}
+
+	tc, ok := conn.(tlsConn)
+	if !ok {
+		return c
+	}
+	cs := tc.ConnectionState()
+	c.ServerName = cs.ServerName
+	if sc, ok := conn.(servedConn); ok {
+		c.ServerName = sc.ServedName()
+	}
+	if len(cs.VerifiedChains) > 0 && len(cs.VerifiedChains[0]) > 0 {
+		c.Cert = repo.NewClientCert(cs.VerifiedChains[0][0])
+	}
+	return c
+}
+
+// setIdle marks conn as waiting for request, idle connections are closed on stop
+func (r *Receiver) setIdle(conn net.Conn, idle bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.idle == nil {
+		r.idle = make(map[net.Conn]struct{})
+	}
+	if idle {
+		r.idle[conn] = struct{}{}
+		return
+	}
+	delete(r.idle, conn)
+}
+
+// Stop closes listener and idle connections, shuts HTTP/2 connections down gracefully.
+// Waits for requests in progress to be served
+func (r *Receiver) Stop(wg *sync.WaitGroup) {
+
+	r.l.Close()
+
+	r.mu.Lock()
+	for conn := range r.idle {
+		conn.Close()
+	}
+	r.mu.Unlock()
+
+	r.stopH2()
+
+	r.wg.Wait()
+
+	wg.Done()
+}
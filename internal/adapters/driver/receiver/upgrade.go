@@ -0,0 +1,13 @@
+package receiver
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// TLS returns upgrade wrapping connection into TLS server side. Handshake is performed on first read
+func TLS(config *tls.Config) Upgrade {
+	return func(conn net.Conn) (net.Conn, error) {
+		return tls.Server(conn, config), nil
+	}
+}
@@ -1,17 +1,27 @@
 package tp
 
 import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"expvar"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+	"github.com/vynovikov/postParser/internal/config"
 	"github.com/vynovikov/postParser/internal/repo"
 
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
 )
 
 type tpSuite struct {
@@ -22,7 +32,7 @@ func TestTpSuite(t *testing.T) {
 	suite.Run(t, new(tpSuite))
 }
 
-var a application.AppService
+var a *application.AppService
 
 func (s *tpSuite) SetupTest() {
 	a = application.NewAppService(make(chan struct{}))
@@ -37,24 +47,23 @@ func (s *tpSuite) TestHandleRequest() {
 		cl      net.Conn
 		sr      net.Conn
 		req     string
-		wg      sync.WaitGroup
 		TS      string
 		wantR   TpReceiver
 		wantRes []byte
 	}{
 		{
-			name: "len(req) < 512",
-			R: &tpReceiverStruct{
-				A: &application.App{
+			name: "len(body) < 1022",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 143\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -63,8 +72,8 @@ func (s *tpSuite) TestHandleRequest() {
 				"azaza\r\n" +
 				"--------------------------c61fd8e07a9d3f9b--",
 			TS: "qqq",
-			wantR: &tpReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 1,
@@ -74,48 +83,99 @@ func (s *tpSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
 									Unblock: true,
 								},
-
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"\r\n" +
 											"\r\n" +
 											"azaza\r\n" +
-											"--------------------------c61fd8e07a9d3f9b--")}},
+											"--------------------------c61fd8e07a9d3f9b--"),
+								},
+							},
 						},
 					},
-				},
+				}},
 			},
-
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
+		},
+		{
+			name: "len(body) == 1022",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 1022\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"--------------------------c61fd8e07a9d3f9b\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azazaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{
+						calls: 1,
+						lastParams: []repo.AppUnit{
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    0,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"\r\n" +
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"\r\n" +
+											"\r\n" +
+											"azazaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
+								},
+							},
+						},
+					},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
 		},
-
 		{
-			name: "len(req) > 512 && len(req) < 1024",
-			R: &tpReceiverStruct{
-				A: &application.App{
+			name: "len(body) > 1022 && len(body) < 2046",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 1458\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -129,30 +189,33 @@ func (s *tpSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
 			TS: "qqq",
-			wantR: &tpReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
-						calls: 1,
+						calls: 2,
 						lastParams: []repo.AppUnit{
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
-									Unblock: true,
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -164,30 +227,53 @@ func (s *tpSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666")}}},
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    1,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n"),
+								},
+							},
+						},
 					},
-				},
+				}},
 			},
-			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in receiver.serve malformed request body: closing boundary is missing\"}"),
 		},
-
 		{
-			name: "len(req) == 1024",
-			R: &tpReceiverStruct{
-				A: &application.App{
+			name: "no Content-Length",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -201,65 +287,95 @@ func (s *tpSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
 			TS: "qqq",
-			wantR: &tpReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
-						calls: 1,
+						calls: 2,
 						lastParams: []repo.AppUnit{
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
-									TS:   "qqq",
-									Part: 0,
-									Bou:  repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									TS:      "qqq",
+									Part:    0,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: false,
 								},
-								B: repo.ReceiverBody{B: []byte(
-									"POST / HTTP/1.1\r\n" +
-										"Host: localhost\r\n" +
-										"User-Agent: curl/7.75.0\r\n" +
-										"Accept: */*\r\n" +
-										"Content-Length: 5250\r\n" +
-										"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-										"\r\n" +
-										"--------------------------c61fd8e07a9d3f9b\r\n" +
-										"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
-										"Content-Type: text/plain\r\n" +
+								B: repo.ReceiverBody{
+									B: []byte(
 										"\r\n" +
-										"0\r\n" +
-										"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
-										"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
-										"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
-										"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
-										"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
-										"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-										"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666")}},
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
+											"Content-Type: text/plain\r\n" +
+											"\r\n" +
+											"0\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
+											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
+											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    1,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
+								},
+							},
 						},
 					},
-				},
+				}},
 			},
-
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
 		},
-
 		{
-			name: "len(req) > 1024 && len(req) < 2048",
-			R: &tpReceiverStruct{
-				A: &application.App{
+			name: "body is cut by idle timeout",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
+				T: repo.Timeouts{Idle: time.Millisecond * 20},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 1558\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -273,10 +389,17 @@ func (s *tpSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"66666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
 			TS: "qqq",
-			wantR: &tpReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 2,
@@ -286,17 +409,13 @@ func (s *tpSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -308,7 +427,9 @@ func (s *tpSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666"),
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
 								},
 							},
 							repo.ReceiverUnit{
@@ -316,38 +437,192 @@ func (s *tpSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    1,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n"),
+								},
+							},
+						},
+					},
+				}},
+				T: repo.Timeouts{Idle: time.Millisecond * 20},
+			},
+			wantRes: []byte("HTTP/1.1 408 Request Timeout\r\n" +
+				"Content-Length: 68\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"read pipe: i/o timeout\"}"),
+		},
+		{
+			name: "len(header) > 512",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Cookie: session00=0123456789abcdef0123456789abcdef; session01=0123456789abcdef0123456789abcdef; session02=0123456789abcdef0123456789abcdef; session03=0123456789abcdef0123456789abcdef; session04=0123456789abcdef0123456789abcdef; session05=0123456789abcdef0123456789abcdef; session06=0123456789abcdef0123456789abcdef; session07=0123456789abcdef0123456789abcdef; session08=0123456789abcdef0123456789abcdef; session09=0123456789abcdef0123456789abcdef; session10=0123456789abcdef0123456789abcdef; session11=0123456789abcdef0123456789abcdef; session12=0123456789abcdef0123456789abcdef; session13=0123456789abcdef0123456789abcdef; session14=0123456789abcdef0123456789abcdef; session15=0123456789abcdef0123456789abcdef; session16=0123456789abcdef0123456789abcdef; session17=0123456789abcdef0123456789abcdef; session18=0123456789abcdef0123456789abcdef; session19=0123456789abcdef0123456789abcdef\r\n" +
+				"Content-Length: 143\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"--------------------------c61fd8e07a9d3f9b\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{
+						calls: 1,
+						lastParams: []repo.AppUnit{
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    0,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
 									Unblock: true,
 								},
 								B: repo.ReceiverBody{
-									B: []byte("6"),
+									B: []byte(
+										"\r\n" +
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"\r\n" +
+											"\r\n" +
+											"azaza\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
 								},
 							},
 						},
 					},
-				},
+				}},
 			},
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
 		},
-
 		{
-			name: "len(req) == 2048",
-			R: &tpReceiverStruct{
-				A: &application.App{
+			name: "method is not POST",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
+			},
+			req: "GET / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 405 Method Not Allowed\r\n" +
+				"Content-Length: 101\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check method not allowed: \\\"GET\\\"\"}"),
+		},
+		{
+			name: "content type is not supported",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: text/plain\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 415 Unsupported Media Type\r\n" +
+				"Content-Length: 112\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check unsupported media type: \\\"text/plain\\\"\"}"),
+		},
+		{
+			name: "no boundary in content type",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 138\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check malformed request header: no boundary in \\\"multipart/form-data\\\"\"}"),
+		},
+		{
+			name: "chunked body",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
+				"2bc\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
 				"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 				"Content-Type: text/plain\r\n" +
@@ -358,18 +633,26 @@ func (s *tpSuite) TestHandleRequest() {
 				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
-				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+				"55555555555555555555555555555555555555555555555555555\r\n" +
+				"12c;name=value\r\n" +
+				"5555555555555555555555555555555555555555555555\r\n" +
 				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
 				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
-				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"88888888888888888888888888888888888888888888888888\r\n" +
+				"1ca\r\n" +
+				"8888888888888888888888888888888888888888888888888\r\n" +
 				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 				"1\r\n" +
 				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
 				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
-				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+				"\r\n" +
+				"0\r\n" +
+				"Checksum: azaza\r\n" +
+				"\r\n",
 			TS: "qqq",
-			wantR: &tpReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 2,
@@ -379,28 +662,27 @@ func (s *tpSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: false,
 								},
-								B: repo.ReceiverBody{B: []byte(
-									"POST / HTTP/1.1\r\n" +
-										"Host: localhost\r\n" +
-										"User-Agent: curl/7.75.0\r\n" +
-										"Accept: */*\r\n" +
-										"Content-Length: 5250\r\n" +
-										"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-										"\r\n" +
-										"--------------------------c61fd8e07a9d3f9b\r\n" +
-										"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
-										"Content-Type: text/plain\r\n" +
+								B: repo.ReceiverBody{
+									B: []byte(
 										"\r\n" +
-										"0\r\n" +
-										"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
-										"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
-										"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
-										"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
-										"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
-										"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-										"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666"),
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
+											"Content-Type: text/plain\r\n" +
+											"\r\n" +
+											"0\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
+											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
+											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
 								},
 							},
 							repo.ReceiverUnit{
@@ -408,13 +690,13 @@ func (s *tpSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    1,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: true,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"66666\r\n" +
-											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
-											"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+										"888888888888888888888888888\r\n" +
 											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 											"1\r\n" +
 											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
@@ -424,13 +706,77 @@ func (s *tpSuite) TestHandleRequest() {
 							},
 						},
 					},
-				},
+				}},
 			},
-			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in receiver.serve malformed request body: closing boundary is missing\"}"),
+		},
+		{
+			name: "chunked body, malformed chunk size",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"zz\r\n" +
+				"----------\r\n" +
+				"0\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 175\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.AnalyzeBits request body is empty: part 0: in repo.chunkedReader.nextChunk malformed request body: chunk size line \\\"zz\\\"\"}"),
+		},
+		{
+			name: "transfer coding is not chunked",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Transfer-Encoding: gzip\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 501 Not Implemented\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check transfer coding not implemented: \\\"gzip\\\"\"}"),
 		},
 	}
 	for _, v := range tt {
@@ -438,22 +784,846 @@ func (s *tpSuite) TestHandleRequest() {
 
 			v.cl, v.sr = net.Pipe()
 
-			v.wg.Add(1)
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
 
-			go v.R.HandleRequest(v.sr, v.TS, &v.wg)
+			go v.R.HandleRequest(v.sr, v.TS, wg)
 
 			fmt.Fprint(v.cl, v.req)
 			time.Sleep(time.Millisecond * 50)
 			s.Equal(v.wantRes, GetResponse(v.cl))
 
-			v.wg.Wait()
-			s.Equal(v.wantR, v.R)
-			v.cl.Close()
+			v.cl.Close() // connection is kept alive until client closes it
+			wg.Wait()
+			s.Equal(v.wantR.(*receiver.Receiver).A, v.R.(*receiver.Receiver).A)
+			for len(a.C.ChanIn) > 0 { // no workers are running, ChanIn should not be filled up
+				<-a.C.ChanIn
+			}
 			v.sr.Close()
 		})
 	}
 }
 
+// TestKeepAlive tests serving of several requests through single connection
+func (s *tpSuite) TestKeepAlive() {
+	req := func(proto, connection string) string {
+		body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+			"Content-Disposition: form-data; name=\"alice\"\r\n" +
+			"\r\n" +
+			"azaza\r\n" +
+			"--------------------------c61fd8e07a9d3f9b--"
+		if len(connection) > 0 {
+			connection = "Connection: " + connection + "\r\n"
+		}
+		return "POST / " + proto + "\r\n" +
+			"Host: localhost\r\n" +
+			connection +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+			"\r\n" +
+			body
+	}
+	res := func(ts, connection string) string {
+		if len(connection) > 0 {
+			connection = "Connection: " + connection + "\r\n"
+		}
+		body := fmt.Sprintf("{\"ts\":%q,\"fields\":[],\"files\":[]}", ts)
+		return "HTTP/1.1 200 OK\r\n" +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: application/json\r\n" +
+			connection +
+			"\r\n" +
+			body
+	}
+
+	tt := []struct {
+		name      string
+		T         repo.Timeouts
+		req       string
+		wantConn  []string // Connection header of every response
+		wantCalls int
+	}{
+		{
+			name:      "pipelined requests, the last closes connection",
+			req:       req("HTTP/1.1", "") + req("HTTP/1.0", "keep-alive") + req("HTTP/1.1", "close"),
+			wantConn:  []string{"", "keep-alive", "close"},
+			wantCalls: 3,
+		},
+
+		{
+			name:      "HTTP/1.0 request closes connection by default",
+			req:       req("HTTP/1.0", "") + req("HTTP/1.1", ""),
+			wantConn:  []string{"close"},
+			wantCalls: 1,
+		},
+
+		{
+			name:      "idle connection is closed by timeout",
+			T:         repo.Timeouts{Idle: time.Millisecond * 20},
+			req:       req("HTTP/1.1", ""),
+			wantConn:  []string{""},
+			wantCalls: 1,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				T: v.T,
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+			s.Equal(v.wantCalls, spy.calls)
+
+			ts, wantRes := make(map[string]bool), ""
+			for i, u := range spy.lastParams {
+				ts[u.(repo.ReceiverUnit).H.TS] = true
+				s.True(u.(repo.ReceiverUnit).H.Unblock)
+				wantRes += res(u.(repo.ReceiverUnit).H.TS, v.wantConn[i])
+			}
+			s.Len(ts, v.wantCalls)
+			s.True(ts["qqq"])
+			s.Equal(wantRes, string(got))
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// TestProxy tests that client address is taken from PROXY protocol header and connection without header is rejected
+func (s *tpSuite) TestProxy() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: close\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+		"\r\n" +
+		body
+
+	tt := []struct {
+		name       string
+		req        string
+		wantClient []string
+		wantRes    string
+	}{
+		{
+			name:       "v1 header",
+			req:        "PROXY TCP4 192.0.2.1 192.0.2.2 56324 3000\r\n" + req,
+			wantClient: []string{"192.0.2.1:56324"},
+			wantRes:    "HTTP/1.1 200 OK\r\n",
+		},
+
+		{
+			name:       "v2 LOCAL header keeps peer address",
+			req:        "\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00" + req,
+			wantClient: []string{"pipe"},
+			wantRes:    "HTTP/1.1 200 OK\r\n",
+		},
+
+		{
+			name: "unhappy no header",
+			req:  req,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				U: receiver.Proxy(time.Second),
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+
+			client := []string{}
+			for _, u := range spy.lastParams {
+				client = append(client, u.(repo.ReceiverUnit).H.Client.Addr)
+			}
+			s.ElementsMatch(v.wantClient, client)
+			s.True(strings.HasPrefix(string(got), v.wantRes))
+			if len(v.wantRes) == 0 {
+				s.Empty(got)
+			}
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// TestRoutes tests that request is passed to route matching its path and checked against route limits
+func (s *tpSuite) TestRoutes() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := func(path string, chunked bool) string {
+		framing := fmt.Sprintf("Content-Length: %d\r\n", len(body))
+		b := body
+		if chunked {
+			framing = "Transfer-Encoding: chunked\r\n"
+			b = fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", len(body), body)
+		}
+		return "POST " + path + " HTTP/1.1\r\n" +
+			"Host: localhost\r\n" +
+			"Connection: close\r\n" +
+			framing +
+			"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+			"\r\n" +
+			b
+	}
+	routes := repo.Routes{
+		{Name: "avatars", Path: "/avatars", MaxBodySize: int64(len(body))},
+		{Name: "telemetry", Path: "/telemetry", MaxBodySize: int64(len(body)) - 1},
+		{Name: "documents", Path: "/documents", ContentTypes: []string{"application/json"}},
+	}
+
+	tt := []struct {
+		name      string
+		req       string
+		wantRoute []string
+		wantRes   string
+	}{
+		{
+			name:      "route name is passed with request",
+			req:       req("/avatars/alice?size=1", false),
+			wantRoute: []string{"avatars"},
+			wantRes:   "HTTP/1.1 200 OK\r\n",
+		},
+
+		{
+			name:    "unhappy no route",
+			req:     req("/unknown", false),
+			wantRes: "HTTP/1.1 404 Not Found\r\n",
+		},
+
+		{
+			name:    "unhappy Content-Length exceeds route limit",
+			req:     req("/telemetry", false),
+			wantRes: "HTTP/1.1 413 Request Entity Too Large\r\n",
+		},
+
+		{
+			name:      "unhappy chunked body exceeds route limit",
+			req:       req("/telemetry", true),
+			wantRoute: []string{"telemetry"},
+			wantRes:   "HTTP/1.1 413 Request Entity Too Large\r\n",
+		},
+
+		{
+			name:    "unhappy content type is not allowed by route",
+			req:     req("/documents", false),
+			wantRes: "HTTP/1.1 415 Unsupported Media Type\r\n",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				Routes: routes,
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+
+			route := map[string]bool{}
+			for _, u := range spy.lastParams {
+				route[u.(repo.ReceiverUnit).H.Route] = true
+			}
+			s.Len(route, len(v.wantRoute))
+			for _, r := range v.wantRoute {
+				s.True(route[r], r)
+			}
+			s.True(strings.HasPrefix(string(got), v.wantRes), string(got))
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// TestAuth tests that request is authenticated by header before body is read
+func (s *tpSuite) TestAuth() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	header := func(user string) string {
+		auth := ""
+		if len(user) > 0 {
+			auth = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(user+":secret")) + "\r\n"
+		}
+		return "POST /avatars HTTP/1.1\r\n" +
+			"Host: localhost\r\n" +
+			"Connection: close\r\n" +
+			auth +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+			"\r\n"
+	}
+	auth, err := receiver.NewAuth(config.Auth{Basic: map[string]string{"alice": "secret", "bob": "secret"}})
+	s.NoError(err)
+
+	tt := []struct {
+		name          string
+		req           string
+		wantPrincipal []string
+		wantRes       string
+		wantChallenge string // WWW-Authenticate header line, none if empty
+	}{
+		{
+			name:          "principal is passed with request",
+			req:           header("alice") + body,
+			wantPrincipal: []string{"alice"},
+			wantRes:       "HTTP/1.1 200 OK\r\n",
+		},
+
+		{
+			name:          "unhappy no credentials, body is not awaited",
+			req:           header(""),
+			wantRes:       "HTTP/1.1 401 Unauthorized\r\n",
+			wantChallenge: "WWW-Authenticate: Basic realm=\"postParser\"\r\n",
+		},
+
+		{
+			name:    "unhappy principal is not allowed to use route",
+			req:     header("bob"),
+			wantRes: "HTTP/1.1 403 Forbidden\r\n",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				Routes: repo.Routes{{Name: "avatars", Path: "/avatars", Principals: []string{"alice"}}},
+				Auth:   auth,
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+
+			principal := map[string]bool{}
+			for _, u := range spy.lastParams {
+				principal[u.(repo.ReceiverUnit).H.Client.Principal] = true
+			}
+			s.Len(principal, len(v.wantPrincipal))
+			for _, p := range v.wantPrincipal {
+				s.True(principal[p], p)
+			}
+			s.True(strings.HasPrefix(string(got), v.wantRes), string(got))
+			if len(v.wantChallenge) > 0 {
+				s.Contains(string(got), v.wantChallenge)
+			} else {
+				s.NotContains(string(got), "WWW-Authenticate")
+			}
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// TestLimits tests that clients exceeding limits get 429 with Retry-After and rejections are counted
+func (s *tpSuite) TestLimits() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+		"\r\n" + body
+
+	tt := []struct {
+		name      string
+		c         config.Limits
+		hold      bool // connection slot of client is taken by another connection
+		req       string
+		wantRes   []string
+		wantLimit string
+	}{
+		{
+			name:      "unhappy request rate, the first request is served",
+			c:         config.Limits{RequestsPerIP: 1},
+			req:       req + req,
+			wantRes:   []string{"HTTP/1.1 200 OK\r\n", "HTTP/1.1 429 Too Many Requests\r\n", "Retry-After: 1\r\n"},
+			wantLimit: "requestsPerIP",
+		},
+
+		{
+			name:      "unhappy concurrent connections",
+			c:         config.Limits{ConnsPerIP: 1},
+			hold:      true,
+			req:       req,
+			wantRes:   []string{"HTTP/1.1 429 Too Many Requests\r\n", "Connection: close\r\nRetry-After: 1\r\n"},
+			wantLimit: "connsPerIP",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+				Limits: receiver.NewLimiter(v.c),
+			}
+			cl, sr := net.Pipe()
+			if v.hold {
+				release, err := R.Limits.Conn(sr.RemoteAddr().String())
+				s.NoError(err)
+				defer release()
+			}
+			before := int64(0)
+			if c, ok := receiver.Rejected.Get(v.wantLimit).(*expvar.Int); ok {
+				before = c.Value()
+			}
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+
+			for _, w := range v.wantRes {
+				s.Contains(string(got), w)
+			}
+			s.Equal(before+1, receiver.Rejected.Get(v.wantLimit).(*expvar.Int).Value())
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// TestUploadLimits tests that reading stops at the first byte exceeding upload limits
+func (s *tpSuite) TestUploadLimits() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"bob\"; filename=\"long.txt\"\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		strings.Repeat("bzbzbzbzbz", 300) + "\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+		"\r\n" + body
+
+	tt := []struct {
+		name     string
+		u        repo.UploadLimits
+		wantRes  string
+		wantRead int // body bytes passed to application
+	}{
+		{
+			name:     "limits are not exceeded",
+			u:        repo.UploadLimits{MaxBodySize: int64(len(body)), MaxParts: 2, MaxFieldSize: 5, MaxFileSize: 3000},
+			wantRes:  "HTTP/1.1 200 OK\r\n",
+			wantRead: len(body),
+		},
+
+		{
+			name:    "unhappy body size is known from Content-Length, body is not read",
+			u:       repo.UploadLimits{MaxBodySize: 100},
+			wantRes: "HTTP/1.1 413 Request Entity Too Large\r\n",
+		},
+
+		{
+			name:     "unhappy file size",
+			u:        repo.UploadLimits{MaxFileSize: 2000},
+			wantRes:  "HTTP/1.1 413 Request Entity Too Large\r\n",
+			wantRead: strings.Index(body, "bzbz") + 2000,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				Upload: v.u,
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, req)
+
+			got := make([]byte, 1024)
+			n, _ := cl.Read(got)
+			s.True(strings.HasPrefix(string(got[:n]), v.wantRes), string(got[:n]))
+			cl.Close()
+			wg.Wait()
+
+			read := 0
+			for _, u := range spy.lastParams {
+				read += len(u.(repo.ReceiverUnit).B.B)
+			}
+			if v.wantRead > 0 {
+				s.Equal(v.wantRead+len("\r\n"), read) // the first part begins with CRLF
+				s.True(spy.lastParams[len(spy.lastParams)-1].(repo.ReceiverUnit).H.Unblock)
+			}
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+		})
+	}
+}
+
+// TestExpectContinue tests that client waiting for 100 Continue gets it after request is admitted, or gets final rejection
+func (s *tpSuite) TestExpectContinue() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	header := func(expect, auth string) string {
+		return "POST /avatars HTTP/1.1\r\n" +
+			"Host: localhost\r\n" +
+			"Connection: close\r\n" +
+			auth +
+			"Expect: " + expect + "\r\n" +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+			"\r\n"
+	}
+	basic := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret")) + "\r\n"
+	auth, err := receiver.NewAuth(config.Auth{Basic: map[string]string{"alice": "secret"}})
+	s.NoError(err)
+
+	tt := []struct {
+		name      string
+		header    string
+		wantFirst string
+		wantFinal string
+	}{
+		{
+			name:      "body is sent after 100 Continue",
+			header:    header("100-continue", basic),
+			wantFirst: "HTTP/1.1 100 Continue\r\n\r\n",
+			wantFinal: "HTTP/1.1 200 OK\r\n",
+		},
+
+		{
+			name:      "unhappy no credentials",
+			header:    header("100-continue", ""),
+			wantFirst: "HTTP/1.1 401 Unauthorized\r\n",
+		},
+
+		{
+			name:      "unhappy unknown expectation",
+			header:    header("something-else", basic),
+			wantFirst: "HTTP/1.1 417 Expectation Failed\r\n",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+				Auth: auth,
+			}
+			cl, sr := net.Pipe()
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.header) // body is not sent until 100 Continue is got
+
+			got := make([]byte, 1024)
+			n, _ := cl.Read(got)
+			s.True(strings.HasPrefix(string(got[:n]), v.wantFirst), string(got[:n]))
+
+			if len(v.wantFinal) > 0 {
+				go fmt.Fprint(cl, body)
+				final, _ := io.ReadAll(cl)
+				s.True(strings.HasPrefix(string(final), v.wantFinal), string(final))
+			}
+			cl.Close()
+			wg.Wait()
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+		})
+	}
+}
+
+// TestForm tests that urlencoded body is passed to application as multipart one and body limits apply to body as sent
+func (s *tpSuite) TestForm() {
+	body := "alice=azaza&bob=bz+bz%21"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: close\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: application/x-www-form-urlencoded\r\n" +
+		"\r\n" + body
+
+	spy := &SpyLogger{}
+	R := &receiver.Receiver{
+		A: &doneApp{&application.App{
+			A: a,
+			L: spy,
+		}},
+		Upload: repo.UploadLimits{MaxBodySize: int64(len(body)), MaxFieldSize: 6},
+	}
+	cl, sr := net.Pipe()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go R.HandleRequest(sr, "qqq", wg)
+	go fmt.Fprint(cl, req)
+
+	got, _ := io.ReadAll(cl)
+	s.True(strings.HasPrefix(string(got), "HTTP/1.1 200 OK\r\n"), string(got))
+	cl.Close()
+	wg.Wait()
+
+	s.NotEmpty(spy.lastParams)
+	bou := spy.lastParams[0].(repo.ReceiverUnit).H.Bou
+	read := ""
+	for _, u := range spy.lastParams {
+		read += string(u.(repo.ReceiverUnit).B.B)
+	}
+	delim := string(bou.Prefix) + string(bou.Root)
+	s.Equal("\r\n"+ // the first part begins with CRLF
+		delim+"\r\n"+
+		"Content-Disposition: form-data; name=\"alice\"\r\n"+
+		"\r\n"+
+		"azaza\r\n"+
+		delim+"\r\n"+
+		"Content-Disposition: form-data; name=\"bob\"\r\n"+
+		"\r\n"+
+		"bz bz!\r\n"+
+		delim+"--\r\n", read)
+
+	for len(a.C.ChanIn) > 0 {
+		<-a.C.ChanIn
+	}
+}
+
+// TestJSON tests that JSON body is passed to application as multipart one having files decoded from base64 properties
+func (s *tpSuite) TestJSON() {
+	data := strings.Repeat("bzbzbzbzbz", 300)
+	body := `{"alice": "azaza", "avatar": {"filename": "long.txt", "contentType": "text/plain", "data": "` + base64.StdEncoding.EncodeToString([]byte(data)) + `"}}`
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: close\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: application/json\r\n" +
+		"\r\n" + body
+
+	spy := &SpyLogger{}
+	R := &receiver.Receiver{
+		A: &doneApp{&application.App{
+			A: a,
+			L: spy,
+		}},
+		Upload: repo.UploadLimits{MaxBodySize: int64(len(body)), MaxFileSize: int64(len(data))},
+		Decode: repo.Decoding{JSONFiles: []string{"avatar"}},
+	}
+	cl, sr := net.Pipe()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go R.HandleRequest(sr, "qqq", wg)
+	go fmt.Fprint(cl, req)
+
+	got, _ := io.ReadAll(cl)
+	s.True(strings.HasPrefix(string(got), "HTTP/1.1 200 OK\r\n"), string(got))
+	cl.Close()
+	wg.Wait()
+
+	s.NotEmpty(spy.lastParams)
+	bou := spy.lastParams[0].(repo.ReceiverUnit).H.Bou
+	read := ""
+	for _, u := range spy.lastParams {
+		read += string(u.(repo.ReceiverUnit).B.B)
+	}
+	delim := string(bou.Prefix) + string(bou.Root)
+	s.Equal("\r\n"+ // the first part begins with CRLF
+		delim+"\r\n"+
+		"Content-Disposition: form-data; name=\"alice\"\r\n"+
+		"\r\n"+
+		"azaza\r\n"+
+		delim+"\r\n"+
+		"Content-Disposition: form-data; name=\"avatar\"; filename=\"long.txt\"\r\n"+
+		"Content-Type: text/plain\r\n"+
+		"\r\n"+
+		data+"\r\n"+
+		delim+"--\r\n", read)
+
+	for len(a.C.ChanIn) > 0 {
+		<-a.C.ChanIn
+	}
+}
+
+// TestH2C tests that concurrent HTTP/2 streams sent with prior knowledge become independent requests
+func (s *tpSuite) TestH2C() {
+	spy := &syncSpy{}
+	li, err := net.Listen("tcp", "127.0.0.1:0")
+	s.NoError(err)
+	R := receiver.New(&doneApp{&application.App{
+		A: a,
+		L: spy,
+	}}, li, nil, repo.Timeouts{})
+	go R.Run()
+
+	var dials int32
+	c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return net.Dial(network, addr)
+		},
+	}}
+
+	names := []string{"alice", "bob", "carol"}
+	res := make([]repo.Result, len(names))
+	cw := sync.WaitGroup{}
+	for i, n := range names {
+		cw.Add(1)
+		go func(i int, n string) {
+			defer cw.Done()
+			res[i] = h2Post(s, c, "http://"+li.Addr().String(), n)
+		}(i, n)
+	}
+	cw.Wait()
+
+	resp, err := c.Get("http://" + li.Addr().String())
+	s.NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	s.Equal(int32(1), atomic.LoadInt32(&dials))
+
+	// every stream has its own TS and its own data
+	bodies := map[string]string{}
+	spy.mu.Lock()
+	for _, u := range spy.lastParams {
+		ru := u.(repo.ReceiverUnit)
+		bodies[ru.H.TS] += string(ru.B.B)
+	}
+	spy.mu.Unlock()
+	s.Len(bodies, len(names))
+	for i, n := range names {
+		s.Equal(http.StatusOK, res[i].Status)
+		s.Contains(bodies[res[i].TS], "name=\""+n+"\"\r\n\r\n"+n+"-value\r\n")
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	R.Stop(wg)
+	wg.Wait()
+
+	for len(a.C.ChanIn) > 0 {
+		<-a.C.ChanIn
+	}
+}
+
+// h2Post sends form with single field name through c, returns parsed result
+func h2Post(s *tpSuite, c *http.Client, url, name string) repo.Result {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"" + name + "\"\r\n" +
+		"\r\n" +
+		name + "-value\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	resp, err := c.Post(url, "multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b", strings.NewReader(body))
+	s.NoError(err)
+	defer resp.Body.Close()
+
+	res := repo.Result{Status: resp.StatusCode}
+	s.NoError(json.NewDecoder(resp.Body).Decode(&res))
+	return res
+}
+
+// syncSpy is SpyLogger which may be used by concurrent requests
+type syncSpy struct {
+	mu sync.Mutex
+	SpyLogger
+}
+
+func (s *syncSpy) LogStuff(au repo.AppUnit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SpyLogger.LogStuff(au)
+}
+
 type SpyLogger struct {
 	calls      int
 	lastParams []repo.AppUnit
@@ -465,7 +1635,7 @@ func (s *SpyLogger) LogStuff(au repo.AppUnit) {
 }
 
 func GetResponse(conn net.Conn) []byte {
-	r := make([]byte, 200)
+	r := make([]byte, 1024)
 	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 25))
 	n, _ := io.ReadFull(conn, r)
 	if n < len(r) {
@@ -473,3 +1643,25 @@ func GetResponse(conn net.Conn) []byte {
 	}
 	return r
 }
+
+// doneApp is application test double which does not wait for request handling to be finished
+type doneApp struct {
+	*application.App
+}
+
+func (d *doneApp) Await(ts string, t time.Duration) repo.Result {
+	d.Forget(ts)
+	return repo.NewResult(ts)
+}
+
+// partsOf returns headers of parts the way receiver keeps them, every header is given by its lines
+func partsOf(headers ...string) *repo.Parts {
+	b, bou := "", repo.Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	for _, h := range headers {
+		b += "--azaza\r\n" + h + "\r\n\r\n\r\n"
+	}
+	p := new(repo.Parts)
+	io.Copy(io.Discard, repo.NewFlatReader(strings.NewReader(b+"--azaza--\r\n"), bou, p, true))
+
+	return p
+}
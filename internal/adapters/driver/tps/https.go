@@ -1,129 +1,103 @@
 // HTTPS receiver.
 //
-// x509 pair should be in tls forlder inside root directory
+// x509 pair is set by configuration, tls folder inside root directory is used by default.
+// More pairs may be loaded from certificate directory, they are picked by SNI server name.
+// Pairs are reloaded without restart when its files change or SIGHUP is received.
+// Client certificates are verified if configuration sets client CA bundle
 package tps
 
 import (
 	"crypto/tls"
-	"io"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"os"
-	"strings"
 	"sync"
 
 	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+	"github.com/vynovikov/postParser/internal/config"
 	"github.com/vynovikov/postParser/internal/logger"
 	"github.com/vynovikov/postParser/internal/repo"
 )
 
-type TpsServer struct {
-	l net.Listener
-}
-
 type TpsReceiver interface {
 	Run()
 	HandleRequest(conn net.Conn, ts string, wg *sync.WaitGroup)
 	Stop(*sync.WaitGroup)
 }
 
-type tpsReceiverStruct struct {
-	A   application.Application
-	srv *TpsServer
-	wg  sync.WaitGroup
+// Receiver is HTTPS receiver reloading x509 pairs on change
+type Receiver struct {
+	*receiver.Receiver
+	CS *CertStore
 }
 
-func NewTpsReceiver(a application.Application) *tpsReceiverStruct {
+// NewTpsReceiver returns HTTPS receiver configured by c, connections are restricted by t. Watching of x509 pair files starts immediately.
+// If c.Proxy is true, every connection should start with PROXY protocol header preceding TLS handshake
+func NewTpsReceiver(a application.Application, c config.HTTPS, t repo.Timeouts) (*Receiver, error) {
 
-	cer, err := tls.LoadX509KeyPair("tls/cert.pem", "tls/key.pem")
+	cs, err := NewCertStore(c.Cert, c.Key, c.CertDir)
 	if err != nil {
-		logger.L.Errorf("in tps.NewTpsReceiver tls.LoadX509KeyPair returned err: %v\n", err)
-		return nil
+		return nil, err
 	}
 
-	config := &tls.Config{Certificates: []tls.Certificate{cer}}
-
-	li, err := tls.Listen("tcp", ":443", config)
+	tc, err := TLSConfig(c, cs)
 	if err != nil {
-		logger.L.Errorf("in driver.Run error: %v\n", err)
+		return nil, err
 	}
-	logger.L.Infoln("listening localhost:443")
 
-	srv := &TpsServer{l: li}
-
-	return &tpsReceiverStruct{
-		A:   a,
-		srv: srv,
+	li, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("in tps.NewTpsReceiver cannot listen %q: %w", c.Addr, err)
 	}
-}
+	logger.L.Infof("listening %s\n", li.Addr())
 
-func (r *tpsReceiverStruct) Run() {
-
-	for {
-		conn, err := r.srv.l.Accept()
-		if err != nil {
-
-			if r.A.Stopping() {
-				return
-			}
-			logger.L.Error(err)
-		}
-		r.wg.Add(1)
+	u := cs.TLS(tc)
+	if c.Proxy {
+		u = receiver.Chain(receiver.Proxy(t.Idle), u)
+	}
 
-		ts := repo.NewTS()
-		go r.HandleRequest(conn, ts, &r.wg)
+	cs.Watch()
 
-	}
+	return &Receiver{
+		Receiver: receiver.New(a, li, u, t),
+		CS:       cs,
+	}, nil
+}
 
+// Stop stops watching x509 pair files and stops receiver
+func (r *Receiver) Stop(wg *sync.WaitGroup) {
+	r.CS.Stop()
+	r.Receiver.Stop(wg)
 }
 
+// TLSConfig returns server TLS configuration. Server certificate is taken from cs for every handshake,
+// client CA bundle is loaded from c.ClientCA file unless client certificates are not requested.
 // Tested in https_test.go
-func (r *tpsReceiverStruct) HandleRequest(conn net.Conn, ts string, wg *sync.WaitGroup) {
-
-	p := 0
-
-	bou, header, errFirst := repo.AnalyzeHeader(conn)
-
-	for {
-		h := repo.NewReceiverHeader(ts, p, bou)
-		b, errSecond := repo.AnalyzeBits(conn, 1024, p, header)
-
-		u := repo.NewReceiverUnit(h, b)
-
-		if errFirst != nil {
-			if errFirst == io.EOF || errFirst == io.ErrUnexpectedEOF || os.IsTimeout(errFirst) {
-				u.H.Unblock = true
-				r.A.AddToFeeder(u)
-				break
-			}
-		}
-		if errSecond != nil {
-			if errSecond == io.EOF || errSecond == io.ErrUnexpectedEOF || os.IsTimeout(errSecond) {
-				u.H.Unblock = true
-				r.A.AddToFeeder(u)
-				break
-			}
-			if strings.Contains(errSecond.Error(), "empty") {
-				break
-			}
-		}
-
-		r.A.AddToFeeder(u)
+func TLSConfig(c config.HTTPS, cs *CertStore) (*tls.Config, error) {
+	tc := &tls.Config{
+		GetCertificate: cs.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
 
-		p++
+	switch c.ClientAuth {
+	case config.ClientAuthRequest:
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.ClientAuthRequire:
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tc, nil
+	}
 
+	pem, err := os.ReadFile(c.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("in tps.TLSConfig cannot load client CA bundle: %w", err)
 	}
-	repo.Respond(conn)
-	wg.Done()
-	if r.A.Stopping() {
-		r.A.ChainInClose()
+	tc.ClientCAs = x509.NewCertPool()
+	if !tc.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("in tps.TLSConfig no certificates found in client CA bundle %q", c.ClientCA)
 	}
-}
-
-func (r *tpsReceiverStruct) Stop(wg *sync.WaitGroup) {
-
-	r.srv.l.Close()
-
-	r.wg.Wait()
 
-	wg.Done()
+	return tc, nil
 }
@@ -1,17 +1,35 @@
 package tps
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"math/big"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+	"github.com/vynovikov/postParser/internal/config"
 	"github.com/vynovikov/postParser/internal/repo"
 
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
 )
 
 type tpsSuite struct {
@@ -22,10 +40,24 @@ func TestTpsSuite(t *testing.T) {
 	suite.Run(t, new(tpsSuite))
 }
 
-var a application.AppService
+var (
+	a   *application.AppService
+	cer tls.Certificate
+)
 
 func (s *tpsSuite) SetupTest() {
 	a = application.NewAppService(make(chan struct{}))
+
+	var err error
+	cer, err = tls.LoadX509KeyPair("../../../../tls/cert.pem", "../../../../tls/key.pem")
+	s.NoError(err)
+}
+
+// tlsPipe returns connected client and server sides of TLS connection, server side is not upgraded yet
+func tlsPipe() (net.Conn, net.Conn, receiver.Upgrade) {
+	cl, sr := net.Pipe()
+
+	return tls.Client(cl, &tls.Config{InsecureSkipVerify: true}), sr, receiver.TLS(&tls.Config{Certificates: []tls.Certificate{cer}})
 }
 
 // TestHandleRequest tests work of tps recriver. Testdouble spy is used to evaluate corectness of reciever operation
@@ -37,24 +69,23 @@ func (s *tpsSuite) TestHandleRequest() {
 		cl      net.Conn
 		sr      net.Conn
 		req     string
-		wg      sync.WaitGroup
 		TS      string
 		wantR   TpsReceiver
 		wantRes []byte
 	}{
 		{
-			name: "len(req) < 512",
-			R: &tpsReceiverStruct{
-				A: &application.App{
+			name: "len(body) < 1022",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 143\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -63,8 +94,8 @@ func (s *tpsSuite) TestHandleRequest() {
 				"azaza\r\n" +
 				"--------------------------c61fd8e07a9d3f9b--",
 			TS: "qqq",
-			wantR: &tpsReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 1,
@@ -74,17 +105,13 @@ func (s *tpsSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
 									Unblock: true,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"\r\n" +
 											"\r\n" +
@@ -94,28 +121,83 @@ func (s *tpsSuite) TestHandleRequest() {
 							},
 						},
 					},
-				},
+				}},
 			},
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
+		},
+		{
+			name: "len(body) == 1022",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 1022\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"--------------------------c61fd8e07a9d3f9b\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azazaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{
+						calls: 1,
+						params: []repo.AppUnit{
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    0,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"\r\n" +
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"\r\n" +
+											"\r\n" +
+											"azazaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
+								},
+							},
+						},
+					},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
 		},
-
 		{
-			name: "len(req) > 512 && len(req) < 1024",
-			R: &tpsReceiverStruct{
-				A: &application.App{
+			name: "len(body) > 1022 && len(body) < 2046",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 1458\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -129,30 +211,33 @@ func (s *tpsSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
 			TS: "qqq",
-			wantR: &tpsReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
-						calls: 1,
+						calls: 2,
 						params: []repo.AppUnit{
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
-									Unblock: true,
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -164,33 +249,53 @@ func (s *tpsSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666"),
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    1,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n"),
 								},
 							},
 						},
 					},
-				},
+				}},
 			},
-			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in receiver.serve malformed request body: closing boundary is missing\"}"),
 		},
-
 		{
-			name: "len(req) == 1024",
-			R: &tpsReceiverStruct{
-				A: &application.App{
+			name: "no Content-Length",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -204,30 +309,34 @@ func (s *tpsSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
 			TS: "qqq",
-			wantR: &tpsReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
-						calls: 1,
+						calls: 2,
 						params: []repo.AppUnit{
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -239,33 +348,56 @@ func (s *tpsSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666"),
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    1,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
 								},
 							},
 						},
 					},
-				},
+				}},
 			},
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
 		},
-
 		{
-			name: "len(req) > 1024 && len(req) < 2048",
-			R: &tpsReceiverStruct{
-				A: &application.App{
+			name: "body is cut by idle timeout",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
+				T: repo.Timeouts{Idle: time.Millisecond * 20},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 1558\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
@@ -279,10 +411,17 @@ func (s *tpsSuite) TestHandleRequest() {
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-				"66666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666",
+				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+				"1\r\n" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
 			TS: "qqq",
-			wantR: &tpsReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 2,
@@ -292,17 +431,13 @@ func (s *tpsSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -314,42 +449,202 @@ func (s *tpsSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666")}},
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
 									TS:      "qqq",
 									Part:    1,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
+									Unblock: true,
+								},
+								B: repo.ReceiverBody{
+									B: []byte(
+										"888888888888888888888888888\r\n" +
+											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
+											"1\r\n" +
+											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
+											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n"),
+								},
+							},
+						},
+					},
+				}},
+				T: repo.Timeouts{Idle: time.Millisecond * 20},
+			},
+			wantRes: []byte("HTTP/1.1 408 Request Timeout\r\n" +
+				"Content-Length: 68\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"read pipe: i/o timeout\"}"),
+		},
+		{
+			name: "len(header) > 512",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Cookie: session00=0123456789abcdef0123456789abcdef; session01=0123456789abcdef0123456789abcdef; session02=0123456789abcdef0123456789abcdef; session03=0123456789abcdef0123456789abcdef; session04=0123456789abcdef0123456789abcdef; session05=0123456789abcdef0123456789abcdef; session06=0123456789abcdef0123456789abcdef; session07=0123456789abcdef0123456789abcdef; session08=0123456789abcdef0123456789abcdef; session09=0123456789abcdef0123456789abcdef; session10=0123456789abcdef0123456789abcdef; session11=0123456789abcdef0123456789abcdef; session12=0123456789abcdef0123456789abcdef; session13=0123456789abcdef0123456789abcdef; session14=0123456789abcdef0123456789abcdef; session15=0123456789abcdef0123456789abcdef; session16=0123456789abcdef0123456789abcdef; session17=0123456789abcdef0123456789abcdef; session18=0123456789abcdef0123456789abcdef; session19=0123456789abcdef0123456789abcdef\r\n" +
+				"Content-Length: 143\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"--------------------------c61fd8e07a9d3f9b\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{
+						calls: 1,
+						params: []repo.AppUnit{
+							repo.ReceiverUnit{
+								H: repo.ReceiverHeader{
+									TS:      "qqq",
+									Part:    0,
+									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\""),
 									Unblock: true,
 								},
 								B: repo.ReceiverBody{
-									B: []byte("6")}},
+									B: []byte(
+										"\r\n" +
+											"--------------------------c61fd8e07a9d3f9b\r\n" +
+											"Content-Disposition: form-data; name=\"alice\"\r\n" +
+											"\r\n" +
+											"azaza\r\n" +
+											"--------------------------c61fd8e07a9d3f9b--"),
+								},
+							},
 						},
 					},
-				},
+				}},
 			},
 			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+				"Content-Length: 35\r\n" +
+				"Content-Type: application/json\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[]}"),
+		},
+		{
+			name: "method is not POST",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "GET / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 405 Method Not Allowed\r\n" +
+				"Content-Length: 101\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check method not allowed: \\\"GET\\\"\"}"),
+		},
+		{
+			name: "content type is not supported",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: text/plain\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 415 Unsupported Media Type\r\n" +
+				"Content-Length: 112\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check unsupported media type: \\\"text/plain\\\"\"}"),
 		},
-
 		{
-			name: "len(req) == 2048",
-			R: &tpsReceiverStruct{
-				A: &application.App{
+			name: "no boundary in content type",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{},
-				},
+				}},
 			},
 			req: "POST / HTTP/1.1\r\n" +
 				"Host: localhost\r\n" +
 				"User-Agent: curl/7.75.0\r\n" +
 				"Accept: */*\r\n" +
-				"Content-Length: 5250\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 138\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check malformed request header: no boundary in \\\"multipart/form-data\\\"\"}"),
+		},
+		{
+			name: "chunked body",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
 				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
 				"\r\n" +
+				"2bc\r\n" +
 				"--------------------------c61fd8e07a9d3f9b\r\n" +
 				"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 				"Content-Type: text/plain\r\n" +
@@ -360,18 +655,26 @@ func (s *tpsSuite) TestHandleRequest() {
 				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
 				"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 				"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
-				"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
+				"55555555555555555555555555555555555555555555555555555\r\n" +
+				"12c;name=value\r\n" +
+				"5555555555555555555555555555555555555555555555\r\n" +
 				"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
 				"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
-				"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+				"88888888888888888888888888888888888888888888888888\r\n" +
+				"1ca\r\n" +
+				"8888888888888888888888888888888888888888888888888\r\n" +
 				"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 				"1\r\n" +
 				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
 				"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
-				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n",
+				"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n" +
+				"\r\n" +
+				"0\r\n" +
+				"Checksum: azaza\r\n" +
+				"\r\n",
 			TS: "qqq",
-			wantR: &tpsReceiverStruct{
-				A: &application.App{
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
 					A: a,
 					L: &SpyLogger{
 						calls: 2,
@@ -381,17 +684,13 @@ func (s *tpsSuite) TestHandleRequest() {
 									TS:      "qqq",
 									Part:    0,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: false,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"POST / HTTP/1.1\r\n" +
-											"Host: localhost\r\n" +
-											"User-Agent: curl/7.75.0\r\n" +
-											"Accept: */*\r\n" +
-											"Content-Length: 5250\r\n" +
-											"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
-											"\r\n" +
+										"\r\n" +
 											"--------------------------c61fd8e07a9d3f9b\r\n" +
 											"Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\n" +
 											"Content-Type: text/plain\r\n" +
@@ -403,56 +702,526 @@ func (s *tpsSuite) TestHandleRequest() {
 											"333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333333\r\n" +
 											"444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444\r\n" +
 											"555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555555\r\n" +
-											"6666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666")}},
+											"666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666666\r\n" +
+											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
+											"888888888888888888888888888888888888888888888888888888888888888888888888"),
+								},
+							},
 							repo.ReceiverUnit{
 								H: repo.ReceiverHeader{
 									TS:      "qqq",
 									Part:    1,
 									Bou:     repo.Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+									Client:  repo.Client{Addr: "pipe"},
+									Parts:   partsOf("Content-Disposition: form-data; name=\"alice\"; filename=\"long.txt\"\r\nContent-Type: text/plain"),
 									Unblock: true,
 								},
 								B: repo.ReceiverBody{
 									B: []byte(
-										"66666\r\n" +
-											"777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777777\r\n" +
-											"888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888888\r\n" +
+										"888888888888888888888888888\r\n" +
 											"999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999\r\n" +
 											"1\r\n" +
 											"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\r\n" +
 											"111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111\r\n" +
-											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n")}},
+											"222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222\r\n"),
+								},
+							},
 						},
 					},
-				},
+				}},
 			},
-			wantRes: []byte("HTTP/1.1 200 OK\r\n" +
-				"Content-Length: 6\r\n" +
-				"Content-Type: text/html\r\n" +
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in receiver.serve malformed request body: closing boundary is missing\"}"),
+		},
+		{
+			name: "chunked body, malformed chunk size",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"zz\r\n" +
+				"----------\r\n" +
+				"0\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 400 Bad Request\r\n" +
+				"Content-Length: 175\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.AnalyzeBits request body is empty: part 0: in repo.chunkedReader.nextChunk malformed request body: chunk size line \\\"zz\\\"\"}"),
+		},
+		{
+			name: "transfer coding is not chunked",
+			R: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			req: "POST / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"User-Agent: curl/7.75.0\r\n" +
+				"Accept: */*\r\n" +
+				"Transfer-Encoding: gzip\r\n" +
+				"Content-Length: 0\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n",
+			TS: "qqq",
+			wantR: &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: &SpyLogger{},
+				}},
+			},
+			wantRes: []byte("HTTP/1.1 501 Not Implemented\r\n" +
+				"Content-Length: 115\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Connection: close\r\n" +
 				"\r\n" +
-				"200 OK"),
+				"{\"ts\":\"qqq\",\"fields\":[],\"files\":[],\"error\":\"in repo.RequestHeader.Check transfer coding not implemented: \\\"gzip\\\"\"}"),
 		},
 	}
 	for _, v := range tt {
 		s.Run(v.name, func() {
 
-			v.cl, v.sr = net.Pipe()
+			var u receiver.Upgrade
+			v.cl, v.sr, u = tlsPipe()
+			v.R.(*receiver.Receiver).U = u
 
-			v.wg.Add(1)
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
 
-			go v.R.HandleRequest(v.sr, v.TS, &v.wg)
+			go v.R.HandleRequest(v.sr, v.TS, wg)
 
 			fmt.Fprint(v.cl, v.req)
 			time.Sleep(time.Millisecond * 50)
 			s.Equal(v.wantRes, GetResponse(v.cl))
 
-			v.wg.Wait()
-			s.Equal(v.wantR, v.R)
-			v.cl.Close()
+			v.cl.Close() // connection is kept alive until client closes it
+			wg.Wait()
+			s.Equal(v.wantR.(*receiver.Receiver).A, v.R.(*receiver.Receiver).A)
+			for len(a.C.ChanIn) > 0 { // no workers are running, ChanIn should not be filled up
+				<-a.C.ChanIn
+			}
 			v.sr.Close()
 		})
 	}
 }
 
+// TestKeepAlive tests serving of several requests through single connection
+func (s *tpsSuite) TestKeepAlive() {
+	req := func(proto, connection string) string {
+		body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+			"Content-Disposition: form-data; name=\"alice\"\r\n" +
+			"\r\n" +
+			"azaza\r\n" +
+			"--------------------------c61fd8e07a9d3f9b--"
+		if len(connection) > 0 {
+			connection = "Connection: " + connection + "\r\n"
+		}
+		return "POST / " + proto + "\r\n" +
+			"Host: localhost\r\n" +
+			connection +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+			"\r\n" +
+			body
+	}
+	res := func(ts, connection string) string {
+		if len(connection) > 0 {
+			connection = "Connection: " + connection + "\r\n"
+		}
+		body := fmt.Sprintf("{\"ts\":%q,\"fields\":[],\"files\":[]}", ts)
+		return "HTTP/1.1 200 OK\r\n" +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"Content-Type: application/json\r\n" +
+			connection +
+			"\r\n" +
+			body
+	}
+
+	tt := []struct {
+		name      string
+		T         repo.Timeouts
+		req       string
+		wantConn  []string // Connection header of every response
+		wantCalls int
+	}{
+		{
+			name:      "pipelined requests, the last closes connection",
+			req:       req("HTTP/1.1", "") + req("HTTP/1.0", "keep-alive") + req("HTTP/1.1", "close"),
+			wantConn:  []string{"", "keep-alive", "close"},
+			wantCalls: 3,
+		},
+
+		{
+			name:      "HTTP/1.0 request closes connection by default",
+			req:       req("HTTP/1.0", "") + req("HTTP/1.1", ""),
+			wantConn:  []string{"close"},
+			wantCalls: 1,
+		},
+
+		{
+			name:      "idle connection is closed by timeout",
+			T:         repo.Timeouts{Idle: time.Millisecond * 20},
+			req:       req("HTTP/1.1", ""),
+			wantConn:  []string{""},
+			wantCalls: 1,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				T: v.T,
+			}
+			cl, sr, u := tlsPipe()
+			R.U = u
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(cl, v.req)
+
+			got, _ := io.ReadAll(cl) // connection is closed by server
+			wg.Wait()
+			s.Equal(v.wantCalls, spy.calls)
+
+			ts, wantRes := make(map[string]bool), ""
+			for i, u := range spy.params {
+				ts[u.(repo.ReceiverUnit).H.TS] = true
+				s.True(u.(repo.ReceiverUnit).H.Unblock)
+				wantRes += res(u.(repo.ReceiverUnit).H.TS, v.wantConn[i])
+			}
+			s.Len(ts, v.wantCalls)
+			s.True(ts["qqq"])
+			s.Equal(wantRes, string(got))
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			cl.Close()
+		})
+	}
+}
+
+// newCA returns self-signed CA and client certificate with given CN signed by it
+func newCA(cn string) (*x509.Certificate, tls.Certificate, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	return ca, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func (s *tpsSuite) TestTLSConfig() {
+	dir := s.T().TempDir()
+
+	ca, _, err := newCA("partner")
+	s.NoError(err)
+	bundle := filepath.Join(dir, "ca.pem")
+	s.NoError(os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0644))
+	empty := filepath.Join(dir, "empty.pem")
+	s.NoError(os.WriteFile(empty, []byte("no certificates here"), 0644))
+
+	c := config.HTTPS{}
+	cs, err := NewCertStore("../../../../tls/cert.pem", "../../../../tls/key.pem", "")
+	s.NoError(err)
+
+	tt := []struct {
+		name       string
+		auth       string
+		ca         string
+		wantAuth   tls.ClientAuthType
+		wantCAs    bool
+		wantErrStr string
+	}{
+		{
+			name:     "client certificate is not requested",
+			auth:     config.ClientAuthNone,
+			wantAuth: tls.NoClientCert,
+		},
+
+		{
+			name:     "request",
+			auth:     config.ClientAuthRequest,
+			ca:       bundle,
+			wantAuth: tls.VerifyClientCertIfGiven,
+			wantCAs:  true,
+		},
+
+		{
+			name:     "require",
+			auth:     config.ClientAuthRequire,
+			ca:       bundle,
+			wantAuth: tls.RequireAndVerifyClientCert,
+			wantCAs:  true,
+		},
+
+		{
+			name:       "unhappy CA bundle is missing",
+			auth:       config.ClientAuthRequire,
+			ca:         filepath.Join(dir, "missing.pem"),
+			wantErrStr: "in tps.TLSConfig cannot load client CA bundle",
+		},
+
+		{
+			name:       "unhappy CA bundle has no certificates",
+			auth:       config.ClientAuthRequire,
+			ca:         empty,
+			wantErrStr: "in tps.TLSConfig no certificates found in client CA bundle",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			c.ClientAuth, c.ClientCA = v.auth, v.ca
+
+			got, err := TLSConfig(c, cs)
+			if len(v.wantErrStr) > 0 {
+				s.Error(err)
+				s.Contains(err.Error(), v.wantErrStr)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantAuth, got.ClientAuth)
+			s.Equal(v.wantCAs, got.ClientCAs != nil)
+		})
+	}
+}
+
+// TestClientCert tests that verified client identity is passed to application and client without valid certificate is rejected
+func (s *tpsSuite) TestClientCert() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: close\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+		"\r\n" +
+		body
+
+	ca, partner, err := newCA("partner")
+	s.NoError(err)
+	_, stranger, err := newCA("stranger")
+	s.NoError(err)
+	leaf, err := x509.ParseCertificate(partner.Certificate[0])
+	s.NoError(err)
+	sum := sha256.Sum256(leaf.Raw)
+
+	tt := []struct {
+		name     string
+		auth     tls.ClientAuthType
+		cert     []tls.Certificate
+		wantCert []repo.ClientCert
+	}{
+		{
+			name: "required certificate is verified",
+			auth: tls.RequireAndVerifyClientCert,
+			cert: []tls.Certificate{partner},
+			wantCert: []repo.ClientCert{{
+				CN:          "partner",
+				SANs:        []string{"partner.example.com"},
+				Fingerprint: hex.EncodeToString(sum[:]),
+			}},
+		},
+
+		{
+			name:     "requested certificate is not sent",
+			auth:     tls.VerifyClientCertIfGiven,
+			wantCert: []repo.ClientCert{{}},
+		},
+
+		{
+			name: "unhappy required certificate is not sent",
+			auth: tls.RequireAndVerifyClientCert,
+		},
+
+		{
+			name: "unhappy certificate signed by unknown CA",
+			auth: tls.VerifyClientCertIfGiven,
+			cert: []tls.Certificate{stranger},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			pool := x509.NewCertPool()
+			pool.AddCert(ca)
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				U: receiver.TLS(&tls.Config{Certificates: []tls.Certificate{cer}, ClientAuth: v.auth, ClientCAs: pool}),
+			}
+			// failed handshake deadlocks on net.Pipe since both sides write, loopback connection is used
+			li, err := net.Listen("tcp", "127.0.0.1:0")
+			s.NoError(err)
+			defer li.Close()
+			cl, err := net.Dial("tcp", li.Addr().String())
+			s.NoError(err)
+			sr, err := li.Accept()
+			s.NoError(err)
+			tc := tls.Client(cl, &tls.Config{InsecureSkipVerify: true, Certificates: v.cert})
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(tc, req)
+
+			got, _ := io.ReadAll(tc) // connection is closed by server
+			wg.Wait()
+
+			gotCert := []repo.ClientCert{}
+			for _, u := range spy.params {
+				gotCert = append(gotCert, u.(repo.ReceiverUnit).H.Client.Cert)
+			}
+			s.Equal(len(v.wantCert), len(gotCert))
+			for i := range v.wantCert {
+				s.Equal(v.wantCert[i], gotCert[i])
+			}
+			s.Equal(len(v.wantCert) > 0, strings.HasPrefix(string(got), "HTTP/1.1 200 OK\r\n"))
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			tc.Close()
+		})
+	}
+}
+
+// TestH2 tests that HTTP/2 is negotiated by ALPN and concurrent streams become independent requests
+func (s *tpsSuite) TestH2() {
+	cert, key, _, err := certDir(s.T().TempDir())
+	s.NoError(err)
+	cs, err := NewCertStore(cert, key, "")
+	s.NoError(err)
+	tc, err := TLSConfig(config.HTTPS{ClientAuth: config.ClientAuthNone}, cs)
+	s.NoError(err)
+
+	spy := &syncSpy{}
+	li, err := net.Listen("tcp", "127.0.0.1:0")
+	s.NoError(err)
+	R := receiver.New(&doneApp{&application.App{
+		A: a,
+		L: spy,
+	}}, li, receiver.TLS(tc), repo.Timeouts{})
+	go R.Run()
+
+	c := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	names := []string{"alice", "bob"}
+	res := make([]repo.Result, len(names))
+	cw := sync.WaitGroup{}
+	for i, n := range names {
+		cw.Add(1)
+		go func(i int, n string) {
+			defer cw.Done()
+
+			body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+				"Content-Disposition: form-data; name=\"" + n + "\"\r\n" +
+				"\r\n" +
+				n + "-value\r\n" +
+				"--------------------------c61fd8e07a9d3f9b--"
+			resp, err := c.Post("https://"+li.Addr().String(), "multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b", strings.NewReader(body))
+			s.NoError(err)
+			defer resp.Body.Close()
+
+			s.Equal(2, resp.ProtoMajor)
+			res[i].Status = resp.StatusCode
+			s.NoError(json.NewDecoder(resp.Body).Decode(&res[i]))
+		}(i, n)
+	}
+	cw.Wait()
+
+	// every stream has its own TS and its own data
+	bodies := map[string]string{}
+	spy.mu.Lock()
+	for _, u := range spy.params {
+		ru := u.(repo.ReceiverUnit)
+		bodies[ru.H.TS] += string(ru.B.B)
+		s.Equal("127.0.0.1", strings.Split(ru.H.Client.Addr, ":")[0])
+	}
+	spy.mu.Unlock()
+	s.Len(bodies, len(names))
+	for i, n := range names {
+		s.Equal(http.StatusOK, res[i].Status)
+		s.Contains(bodies[res[i].TS], "name=\""+n+"\"\r\n\r\n"+n+"-value\r\n")
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	R.Stop(wg)
+	wg.Wait()
+
+	for len(a.C.ChanIn) > 0 {
+		<-a.C.ChanIn
+	}
+}
+
 type SpyLogger struct {
 	calls  int
 	params []repo.AppUnit
@@ -463,8 +1232,21 @@ func (s *SpyLogger) LogStuff(ru repo.AppUnit) {
 	s.params = append(s.params, ru)
 }
 
+// syncSpy is SpyLogger which may be used by concurrent requests
+type syncSpy struct {
+	mu sync.Mutex
+	SpyLogger
+}
+
+func (s *syncSpy) LogStuff(au repo.AppUnit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SpyLogger.LogStuff(au)
+}
+
 func GetResponse(conn net.Conn) []byte {
-	r := make([]byte, 200)
+	r := make([]byte, 1024)
 	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 25))
 	n, _ := io.ReadFull(conn, r)
 	if n < len(r) {
@@ -472,3 +1254,25 @@ func GetResponse(conn net.Conn) []byte {
 	}
 	return r
 }
+
+// doneApp is application test double which does not wait for request handling to be finished
+type doneApp struct {
+	*application.App
+}
+
+func (d *doneApp) Await(ts string, t time.Duration) repo.Result {
+	d.Forget(ts)
+	return repo.NewResult(ts)
+}
+
+// partsOf returns headers of parts the way receiver keeps them, every header is given by its lines
+func partsOf(headers ...string) *repo.Parts {
+	b, bou := "", repo.Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	for _, h := range headers {
+		b += "--azaza\r\n" + h + "\r\n\r\n\r\n"
+	}
+	p := new(repo.Parts)
+	io.Copy(io.Discard, repo.NewFlatReader(strings.NewReader(b+"--azaza--\r\n"), bou, p, true))
+
+	return p
+}
@@ -0,0 +1,143 @@
+package tps
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/logger"
+)
+
+// CertReloader keeps x509 pair loaded from files and reloads it when files change or SIGHUP is received.
+// Pair which cannot be loaded is rejected, the last good one stays in use
+type CertReloader struct {
+	Cert, Key string
+	Interval  time.Duration // how often files are checked for changes
+
+	mu    sync.RWMutex
+	c     *tls.Certificate
+	stats [2]os.FileInfo // cert and key files when they were loaded last time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCertReloader returns reloader holding pair loaded from cert and key files
+func NewCertReloader(cert, key string) (*CertReloader, error) {
+	cr := &CertReloader{
+		Cert:     cert,
+		Key:      key,
+		Interval: time.Second * 5,
+	}
+	cr.stats = cr.stat()
+	if err := cr.Reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetCertificate returns current pair, it is used as tls.Config.GetCertificate
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	return cr.c, nil
+}
+
+// Reload loads pair from files. Current pair is kept if new one is invalid.
+// Tested in reload_test.go
+func (cr *CertReloader) Reload() error {
+	c, err := tls.LoadX509KeyPair(cr.Cert, cr.Key)
+	if err != nil {
+		return fmt.Errorf("in tps.Reload cannot load x509 pair %q, %q: %w", cr.Cert, cr.Key, err)
+	}
+	if c.Leaf, err = x509.ParseCertificate(c.Certificate[0]); err != nil {
+		return fmt.Errorf("in tps.Reload cannot parse certificate %q: %w", cr.Cert, err)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cr.c = &c
+
+	return nil
+}
+
+// Watch starts reloading pair on files change and on SIGHUP until Stop is called
+func (cr *CertReloader) Watch() {
+	cr.stop, cr.done = make(chan struct{}), make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer close(cr.done)
+		defer signal.Stop(hup)
+
+		t := time.NewTicker(cr.Interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-cr.stop:
+				return
+			case <-hup:
+				cr.stats = cr.stat()
+				cr.reload("SIGHUP")
+			case <-t.C:
+				if s := cr.stat(); changed(cr.stats, s) {
+					cr.stats = s
+					cr.reload("file change")
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends watching started by Watch
+func (cr *CertReloader) Stop() {
+	if cr.stop == nil {
+		return
+	}
+	close(cr.stop)
+	<-cr.done
+}
+
+// reload reloads pair and logs outcome
+func (cr *CertReloader) reload(reason string) {
+	if err := cr.Reload(); err != nil {
+		logger.L.Errorf("in tps.reload on %s keeping previous certificate: %v\n", reason, err)
+		return
+	}
+	logger.L.Infof("in tps.reload on %s certificate %q is reloaded\n", reason, cr.Cert)
+}
+
+// stat returns current state of cert and key files, nil for file which cannot be accessed
+func (cr *CertReloader) stat() [2]os.FileInfo {
+	var s [2]os.FileInfo
+	for i, f := range []string{cr.Cert, cr.Key} {
+		s[i], _ = os.Stat(f)
+	}
+	return s
+}
+
+// changed returns true if any file is replaced or modified
+func changed(old, cur [2]os.FileInfo) bool {
+	for i := range old {
+		if (old[i] == nil) != (cur[i] == nil) {
+			return true
+		}
+		if old[i] == nil {
+			continue
+		}
+		if !os.SameFile(old[i], cur[i]) || !old[i].ModTime().Equal(cur[i].ModTime()) || old[i].Size() != cur[i].Size() {
+			return true
+		}
+	}
+	return false
+}
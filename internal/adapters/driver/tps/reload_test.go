@@ -0,0 +1,132 @@
+package tps
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// writePair writes x509 pair having given CN into cert and key files
+func writePair(cert, key, cn string) error {
+	_, c, err := newCA(cn)
+	if err != nil {
+		return err
+	}
+	k, err := x509.MarshalECPrivateKey(c.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate[0]}), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: k}), 0600)
+}
+
+// currentCN returns CN of certificate cr gives to new handshakes
+func currentCN(cr *CertReloader) string {
+	c, err := cr.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		return err.Error()
+	}
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		return err.Error()
+	}
+	return leaf.Subject.CommonName
+}
+
+func (s *tpsSuite) TestReload() {
+	tt := []struct {
+		name    string
+		update  func(cert, key string) error
+		wantCN  string
+		wantErr bool
+	}{
+		{
+			name: "new pair",
+			update: func(cert, key string) error {
+				return writePair(cert, key, "new")
+			},
+			wantCN: "new",
+		},
+
+		{
+			name: "unhappy key does not match certificate",
+			update: func(cert, key string) error {
+				return writePair(cert, filepath.Join(filepath.Dir(key), "other.pem"), "new")
+			},
+			wantCN:  "old",
+			wantErr: true,
+		},
+
+		{
+			name: "unhappy garbage in certificate file",
+			update: func(cert, key string) error {
+				return os.WriteFile(cert, []byte("garbage"), 0644)
+			},
+			wantCN:  "old",
+			wantErr: true,
+		},
+
+		{
+			name: "unhappy files are removed",
+			update: func(cert, key string) error {
+				return os.Remove(key)
+			},
+			wantCN:  "old",
+			wantErr: true,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			dir := s.T().TempDir()
+			cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+			s.NoError(writePair(cert, key, "old"))
+
+			cr, err := NewCertReloader(cert, key)
+			s.NoError(err)
+			s.Equal("old", currentCN(cr))
+
+			s.NoError(v.update(cert, key))
+
+			err = cr.Reload()
+			s.Equal(v.wantErr, err != nil, err)
+			s.Equal(v.wantCN, currentCN(cr))
+		})
+	}
+}
+
+func (s *tpsSuite) TestWatch() {
+	dir := s.T().TempDir()
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	s.NoError(writePair(cert, key, "first"))
+
+	_, err := NewCertReloader(filepath.Join(dir, "missing.pem"), key)
+	s.Error(err)
+
+	cr, err := NewCertReloader(cert, key)
+	s.NoError(err)
+	cr.Interval = time.Millisecond * 10
+	cr.Watch()
+	defer cr.Stop()
+
+	// files are replaced the way secret managers do it
+	tmpCert, tmpKey := filepath.Join(dir, "cert.tmp"), filepath.Join(dir, "key.tmp")
+	s.NoError(writePair(tmpCert, tmpKey, "second"))
+	s.NoError(os.Rename(tmpCert, cert))
+	s.NoError(os.Rename(tmpKey, key))
+	s.Eventually(func() bool { return currentCN(cr) == "second" }, time.Second, time.Millisecond*10)
+
+	// SIGHUP reloads pair even if files look unchanged
+	cr.Interval = time.Hour
+	cr.Stop()
+	cr.Watch()
+	s.NoError(writePair(cert, key, "third"))
+	s.NoError(syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	s.Eventually(func() bool { return currentCN(cr) == "third" }, time.Second, time.Millisecond*10)
+}
@@ -0,0 +1,159 @@
+package tps
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+)
+
+// CertStore keeps default x509 pair and pairs picked by SNI server name
+type CertStore struct {
+	Default *CertReloader
+	Pairs   []*CertReloader
+}
+
+// NewCertStore returns store with default pair loaded from cert and key files.
+// Every subdirectory of dir holding cert.pem and key.pem gives one more pair, empty dir means no more pairs.
+// Tested in sni_test.go
+func NewCertStore(cert, key, dir string) (*CertStore, error) {
+	def, err := NewCertReloader(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	cs := &CertStore{Default: def}
+
+	if len(dir) == 0 {
+		return cs, nil
+	}
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("in tps.NewCertStore cannot read certificate directory: %w", err)
+	}
+	for _, de := range des {
+		if !de.IsDir() {
+			continue
+		}
+		cr, err := NewCertReloader(filepath.Join(dir, de.Name(), "cert.pem"), filepath.Join(dir, de.Name(), "key.pem"))
+		if err != nil {
+			return nil, err
+		}
+		cs.Pairs = append(cs.Pairs, cr)
+	}
+	return cs, nil
+}
+
+// GetCertificate returns pair matching server name requested by client, exact names are preferred to wildcards.
+// Default pair is returned if no pair matches. It is used as tls.Config.GetCertificate
+func (cs *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c, _, err := cs.pick(hello)
+
+	return c, err
+}
+
+// pick returns pair matching server name requested by client and name of certificate it was picked by.
+// The first name of default certificate is returned if no pair matches
+func (cs *CertStore) pick(hello *tls.ClientHelloInfo) (*tls.Certificate, string, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	if len(name) > 0 {
+		var wildcard *tls.Certificate
+		var pattern string
+		for _, cr := range cs.Pairs {
+			c, _ := cr.GetCertificate(hello)
+			for _, n := range names(c) {
+				switch {
+				case n == name:
+					return c, n, nil
+				case wildcard == nil && matchWildcard(n, name):
+					wildcard, pattern = c, n
+				}
+			}
+		}
+		if wildcard != nil {
+			return wildcard, pattern, nil
+		}
+	}
+	c, err := cs.Default.GetCertificate(hello)
+	if err != nil {
+		return nil, "", err
+	}
+	if ns := names(c); len(ns) > 0 {
+		return c, ns[0], nil
+	}
+	return c, "", nil
+}
+
+// TLS returns upgrade wrapping connection into TLS server side configured by config.
+// Server certificate is taken from cs, name of certificate served is kept by connection
+func (cs *CertStore) TLS(config *tls.Config) receiver.Upgrade {
+	return func(conn net.Conn) (net.Conn, error) {
+		sc := &servedConn{}
+		c := config.Clone()
+		c.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			crt, name, err := cs.pick(hello)
+			sc.name = name
+			return crt, err
+		}
+		sc.Conn = tls.Server(conn, c)
+
+		return sc, nil
+	}
+}
+
+// servedConn is TLS connection knowing name of certificate served
+type servedConn struct {
+	*tls.Conn
+	name string
+}
+
+// ServedName returns name of certificate served, it is known after handshake
+func (c *servedConn) ServedName() string {
+	return c.name
+}
+
+// Watch starts reloading all pairs on change
+func (cs *CertStore) Watch() {
+	cs.Default.Watch()
+	for _, cr := range cs.Pairs {
+		cr.Watch()
+	}
+}
+
+// Stop ends watching of all pairs
+func (cs *CertStore) Stop() {
+	cs.Default.Stop()
+	for _, cr := range cs.Pairs {
+		cr.Stop()
+	}
+}
+
+// names returns server names certificate is valid for. CN is taken if certificate has no DNS names
+func names(c *tls.Certificate) []string {
+	if c == nil || c.Leaf == nil {
+		return nil
+	}
+	ns := c.Leaf.DNSNames
+	if len(ns) == 0 && len(c.Leaf.Subject.CommonName) > 0 {
+		ns = []string{c.Leaf.Subject.CommonName}
+	}
+	res := make([]string, 0, len(ns))
+	for _, n := range ns {
+		res = append(res, strings.ToLower(n))
+	}
+	return res
+}
+
+// matchWildcard returns true if pattern like *.example.com matches name by its first label
+func matchWildcard(pattern, name string) bool {
+	if !strings.HasPrefix(pattern, "*.") {
+		return false
+	}
+	i := strings.IndexByte(name, '.')
+
+	return i > 0 && name[i:] == pattern[1:]
+}
@@ -0,0 +1,202 @@
+package tps
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+	"github.com/vynovikov/postParser/internal/repo"
+)
+
+// certDir writes default pair and directory of pairs with given CNs, returns default pair files and directory
+func certDir(root string, cns ...string) (string, string, string, error) {
+	cert, key := filepath.Join(root, "cert.pem"), filepath.Join(root, "key.pem")
+	if err := writePair(cert, key, "default"); err != nil {
+		return "", "", "", err
+	}
+	dir := filepath.Join(root, "tenants")
+	for i, cn := range cns {
+		d := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return "", "", "", err
+		}
+		if err := writePair(filepath.Join(d, "cert.pem"), filepath.Join(d, "key.pem"), cn); err != nil {
+			return "", "", "", err
+		}
+	}
+	return cert, key, dir, nil
+}
+
+func (s *tpsSuite) TestCertStore() {
+	cert, key, dir, err := certDir(s.T().TempDir(), "*", "a")
+	s.NoError(err)
+	s.NoError(os.WriteFile(filepath.Join(dir, "README"), []byte("files are skipped"), 0644))
+
+	cs, err := NewCertStore(cert, key, dir)
+	s.NoError(err)
+	s.Len(cs.Pairs, 2)
+
+	tt := []struct {
+		name       string
+		serverName string
+		wantCN     string
+		wantName   string
+	}{
+		{
+			name:       "exact name is preferred to wildcard",
+			serverName: "a.example.com",
+			wantCN:     "a",
+			wantName:   "a.example.com",
+		},
+
+		{
+			name:       "name is case insensitive and may end with dot",
+			serverName: "A.Example.COM.",
+			wantCN:     "a",
+			wantName:   "a.example.com",
+		},
+
+		{
+			name:       "wildcard",
+			serverName: "b.example.com",
+			wantCN:     "*",
+			wantName:   "*.example.com",
+		},
+
+		{
+			name:       "wildcard matches single label only",
+			serverName: "b.c.example.com",
+			wantCN:     "default",
+			wantName:   "default.example.com",
+		},
+
+		{
+			name:       "unknown name",
+			serverName: "example.org",
+			wantCN:     "default",
+			wantName:   "default.example.com",
+		},
+
+		{
+			name:     "no SNI",
+			wantCN:   "default",
+			wantName: "default.example.com",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			c, name, err := cs.pick(&tls.ClientHelloInfo{ServerName: v.serverName})
+			s.NoError(err)
+			s.Equal(v.wantCN, c.Leaf.Subject.CommonName)
+			s.Equal(v.wantName, name)
+		})
+	}
+}
+
+func (s *tpsSuite) TestNewCertStore() {
+	root := s.T().TempDir()
+	cert, key, dir, err := certDir(root, "a")
+	s.NoError(err)
+	s.NoError(os.MkdirAll(filepath.Join(dir, "empty"), 0755))
+
+	_, err = NewCertStore(cert, key, dir)
+	s.Error(err)
+	s.Contains(err.Error(), "empty")
+
+	_, err = NewCertStore(cert, key, filepath.Join(root, "missing"))
+	s.Error(err)
+	s.Contains(err.Error(), "in tps.NewCertStore cannot read certificate directory")
+}
+
+// TestServerName tests that name of certificate served is recorded on request
+func (s *tpsSuite) TestServerName() {
+	body := "--------------------------c61fd8e07a9d3f9b\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"azaza\r\n" +
+		"--------------------------c61fd8e07a9d3f9b--"
+	req := "POST / HTTP/1.1\r\n" +
+		"Host: b.example.com\r\n" +
+		"Connection: close\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+		"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+		"\r\n" +
+		body
+
+	cert, key, dir, err := certDir(s.T().TempDir(), "*", "a")
+	s.NoError(err)
+	cs, err := NewCertStore(cert, key, dir)
+	s.NoError(err)
+
+	tt := []struct {
+		name       string
+		serverName string
+		wantCN     string
+		wantName   string
+	}{
+		{
+			name:       "exact name",
+			serverName: "A.example.com",
+			wantCN:     "a",
+			wantName:   "a.example.com",
+		},
+
+		{
+			name:       "wildcard",
+			serverName: "b.example.com",
+			wantCN:     "*",
+			wantName:   "*.example.com",
+		},
+
+		{
+			name:       "fallback to default",
+			serverName: "example.org",
+			wantCN:     "default",
+			wantName:   "default.example.com",
+		},
+
+		{
+			name:     "no SNI",
+			wantCN:   "default",
+			wantName: "default.example.com",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			spy := &SpyLogger{}
+			R := &receiver.Receiver{
+				A: &doneApp{&application.App{
+					A: a,
+					L: spy,
+				}},
+				U: cs.TLS(&tls.Config{}),
+			}
+			cl, sr := net.Pipe()
+			tc := tls.Client(cl, &tls.Config{InsecureSkipVerify: true, ServerName: v.serverName})
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			go R.HandleRequest(sr, "qqq", wg)
+			go fmt.Fprint(tc, req)
+
+			_, err = io.ReadAll(tc) // connection is closed by server
+			s.NoError(err)
+			wg.Wait()
+
+			s.Equal(v.wantCN, tc.ConnectionState().PeerCertificates[0].Subject.CommonName)
+			s.Equal(1, spy.calls)
+			s.Equal(v.wantName, spy.params[0].(repo.ReceiverUnit).H.Client.ServerName)
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+			tc.Close()
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Unix domain socket receiver.
+//
+// Socket file is created with given permission and is removed on stop.
+// It is created inside private directory and is moved to its path when permission is set already,
+// so nobody can connect to socket having default permission
+package tpu
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/adapters/driver/receiver"
+	"github.com/vynovikov/postParser/internal/logger"
+	"github.com/vynovikov/postParser/internal/repo"
+)
+
+var ErrSocketInUse = errors.New("socket is in use")
+
+type TpuReceiver interface {
+	Run()
+	HandleRequest(net.Conn, string, *sync.WaitGroup)
+	Stop(*sync.WaitGroup)
+}
+
+// NewTpuReceiver returns receiver listening Unix socket at path, connections are restricted by t.
+// Stale socket file left by previous run is removed. If proxy is true, every connection should start with PROXY protocol header.
+// Tested in unix_test.go
+func NewTpuReceiver(a application.Application, path string, mode os.FileMode, proxy bool, t repo.Timeouts) (*receiver.Receiver, error) {
+
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+
+	li, err := listen(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	logger.L.Infof("listening %s\n", path)
+
+	var u receiver.Upgrade
+	if proxy {
+		u = receiver.Proxy(t.Idle)
+	}
+
+	return receiver.New(a, li, u, t), nil
+}
+
+// listen creates socket having mode at path. Socket is created in directory accessible to owner only
+// and is moved to path after its mode is set. Socket file is removed when listener is closed
+func listen(path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("in tpu.listen cannot create private directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	li, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, fmt.Errorf("in tpu.listen cannot listen %q: %w", path, err)
+	}
+	li.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err = os.Chmod(tmp, mode); err != nil {
+		li.Close()
+		return nil, fmt.Errorf("in tpu.listen cannot set mode %v to %q: %w", mode, path, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		li.Close()
+		return nil, fmt.Errorf("in tpu.listen cannot move socket to %q: %w", path, err)
+	}
+	return &socketListener{Listener: li, path: path}, nil
+}
+
+// socketListener is Unix socket listener removing socket file on close
+type socketListener struct {
+	net.Listener
+	path string
+	once sync.Once
+}
+
+// Close stops listening and removes socket file once
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() {
+		if rmErr := os.Remove(l.path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) && err == nil {
+			err = rmErr
+		}
+	})
+	return err
+}
+
+// removeStale removes socket file nobody listens to. Files other than sockets are left untouched
+func removeStale(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("in tpu.removeStale %w", err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("in tpu.removeStale %q exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("in tpu.removeStale %w: %q", ErrSocketInUse, path)
+	}
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("in tpu.removeStale %w", err)
+	}
+	logger.L.Infof("in tpu.removeStale removed stale socket %q\n", path)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package tpu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/adapters/application"
+	"github.com/vynovikov/postParser/internal/repo"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tpuSuite struct {
+	suite.Suite
+}
+
+func TestTpuSuite(t *testing.T) {
+	suite.Run(t, new(tpuSuite))
+}
+
+var a *application.AppService
+
+func (s *tpuSuite) SetupTest() {
+	a = application.NewAppService(make(chan struct{}))
+}
+
+func (s *tpuSuite) TestNewTpuReceiver() {
+	tt := []struct {
+		name    string
+		prepare func(path string)
+		wantErr string
+	}{
+		{
+			name:    "no socket file",
+			prepare: func(path string) {},
+		},
+
+		{
+			name: "stale socket file",
+			prepare: func(path string) {
+				li, err := net.Listen("unix", path)
+				s.NoError(err)
+				li.(*net.UnixListener).SetUnlinkOnClose(false)
+				li.Close()
+			},
+		},
+
+		{
+			name: "socket is in use",
+			prepare: func(path string) {
+				li, err := net.Listen("unix", path)
+				s.NoError(err)
+				s.T().Cleanup(func() { li.Close() })
+			},
+			wantErr: "socket is in use",
+		},
+
+		{
+			name: "regular file",
+			prepare: func(path string) {
+				s.NoError(os.WriteFile(path, []byte("azaza"), 0644))
+			},
+			wantErr: "exists and is not a socket",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			path := filepath.Join(s.T().TempDir(), "pp.sock")
+			v.prepare(path)
+
+			R, err := NewTpuReceiver(&doneApp{&application.App{A: a}}, path, 0600, false, repo.Timeouts{})
+			if len(v.wantErr) > 0 {
+				s.Error(err)
+				s.Contains(err.Error(), v.wantErr)
+				return
+			}
+			s.NoError(err)
+
+			fi, err := os.Stat(path)
+			s.NoError(err)
+			s.Equal(os.FileMode(0600), fi.Mode().Perm())
+
+			des, err := os.ReadDir(filepath.Dir(path))
+			s.NoError(err)
+			s.Len(des, 1) // private directory socket was created in is removed
+
+			go R.Run()
+
+			conn, err := net.Dial("unix", path)
+			s.NoError(err)
+			body := "--bRoot\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--bRoot--"
+			fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\nContent-Length: %d\r\nContent-Type: multipart/form-data; boundary=bRoot\r\n\r\n%s", len(body), body)
+
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			s.NoError(err)
+			s.Equal(http.StatusOK, res.StatusCode)
+			conn.Close()
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			R.Stop(wg)
+			wg.Wait()
+
+			_, err = os.Stat(path)
+			s.True(errors.Is(err, os.ErrNotExist))
+
+			for len(a.C.ChanIn) > 0 {
+				<-a.C.ChanIn
+			}
+		})
+	}
+}
+
+// doneApp is application test double which does not wait for request handling to be finished
+type doneApp struct {
+	*application.App
+}
+
+func (d *doneApp) Await(ts string, t time.Duration) repo.Result {
+	d.Forget(ts)
+	return repo.NewResult(ts)
+}
@@ -0,0 +1,494 @@
+// Configuration of receivers.
+//
+// Defaults are overridden by config file, then by environment variables, then by command line flags
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/repo"
+)
+
+var ErrInvalid = errors.New("invalid configuration")
+
+type Config struct {
+	HTTP   HTTP              `json:"http"`
+	HTTPS  HTTPS             `json:"https"`
+	Unix   Unix              `json:"unix"`
+	Routes repo.Routes       `json:"routes"` // set by config file only
+	Auth   Auth              `json:"auth"`
+	Limits Limits            `json:"limits"`
+	Upload repo.UploadLimits `json:"upload"`
+	Decode repo.Decoding     `json:"decode"`
+
+	Timeouts Timeouts `json:"timeouts"`
+
+	MetricsAddr string `json:"metricsAddr"` // listen address of expvar metrics, disabled if empty
+}
+
+// HTTP is configuration of HTTP receiver
+type HTTP struct {
+	Enabled bool   `json:"enabled"`
+	Addr    string `json:"addr"`
+	Proxy   bool   `json:"proxy"` // connections start with PROXY protocol header
+}
+
+// HTTPS is configuration of HTTPS receiver, default x509 pair is loaded from Cert and Key files.
+// Pairs picked by SNI server name are loaded from subdirectories of CertDir.
+// Client certificates are verified by CA bundle loaded from ClientCA file, ClientAuth is one of none, request, require
+type HTTPS struct {
+	Enabled    bool   `json:"enabled"`
+	Addr       string `json:"addr"`
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+	CertDir    string `json:"certDir"`
+	Proxy      bool   `json:"proxy"`
+	ClientCA   string `json:"clientCA"`
+	ClientAuth string `json:"clientAuth"`
+}
+
+// Client certificate verify modes
+const (
+	ClientAuthNone    = "none"    // client certificate is not requested
+	ClientAuthRequest = "request" // client certificate is verified if client sends one
+	ClientAuthRequire = "require" // client without valid certificate is rejected
+)
+
+// Auth is configuration of request authentication, requests are not authenticated if no method is set.
+// Secrets are set by config file only
+type Auth struct {
+	Basic       map[string]string `json:"basic"`       // user name to password
+	TokenFile   string            `json:"tokenFile"`   // bearer tokens, "principal token" per line
+	JWTKey      string            `json:"jwtKey"`      // PEM public key verifying bearer JWT
+	JWTIssuer   string            `json:"jwtIssuer"`   // required iss claim, any if empty
+	JWTAudience string            `json:"jwtAudience"` // required aud claim, any if empty
+	HMAC        map[string]string `json:"hmac"`        // key id to shared secret of request signatures
+	HMACHeaders []string          `json:"hmacHeaders"` // headers every signature should cover
+	Realm       string            `json:"realm"`       // realm of WWW-Authenticate challenges, "postParser" if empty
+}
+
+// Enabled returns true if any authentication method is set
+func (a Auth) Enabled() bool {
+	return len(a.Basic) > 0 || len(a.TokenFile) > 0 || len(a.JWTKey) > 0 || len(a.HMAC) > 0
+}
+
+// Limits restricts load coming from clients, zero value means no restriction.
+// Connection limits are counts of concurrent connections, the rest are rates per second.
+// Rates are enforced by token buckets holding one second worth of tokens
+type Limits struct {
+	Conns         int     `json:"conns"`
+	ConnsPerIP    int     `json:"connsPerIP"`
+	Requests      float64 `json:"requests"`
+	RequestsPerIP float64 `json:"requestsPerIP"`
+	Bytes         float64 `json:"bytes"` // of request bodies
+	BytesPerIP    float64 `json:"bytesPerIP"`
+}
+
+// Enabled returns true if any limit is set
+func (l Limits) Enabled() bool {
+	return l.Conns > 0 || l.ConnsPerIP > 0 || l.Requests > 0 || l.RequestsPerIP > 0 || l.Bytes > 0 || l.BytesPerIP > 0
+}
+
+// Unix is configuration of Unix domain socket receiver. Mode is octal permission of socket file
+type Unix struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	Mode    string `json:"mode"`
+	Proxy   bool   `json:"proxy"`
+}
+
+// FileMode returns permission of socket file. Mode should be checked before
+func (u Unix) FileMode() os.FileMode {
+	m, _ := strconv.ParseUint(u.Mode, 8, 32)
+
+	return os.FileMode(m)
+}
+
+// Timeouts restricts waiting for clients and for request handling. Values are durations like 30s, zero means no restriction
+type Timeouts struct {
+	Idle     string `json:"idle"`     // max pause between data arrivals, TLS handshake included
+	Request  string `json:"request"`  // max duration of whole request
+	Response string `json:"response"` // max wait for request handling to be finished
+}
+
+// Durations returns timeouts as durations. Timeouts should be checked before.
+// Tested in config_test.go
+func (t Timeouts) Durations() repo.Timeouts {
+	idle, _ := time.ParseDuration(t.Idle)
+	request, _ := time.ParseDuration(t.Request)
+	response, _ := time.ParseDuration(t.Response)
+
+	return repo.Timeouts{Idle: idle, Request: request, Response: response}
+}
+
+// New returns default configuration. HTTP and HTTPS receivers are enabled, Unix socket receiver is disabled
+func New() Config {
+	return Config{
+		HTTP: HTTP{
+			Enabled: true,
+			Addr:    ":3000",
+		},
+		HTTPS: HTTPS{
+			Enabled:    true,
+			Addr:       ":443",
+			Cert:       "tls/cert.pem",
+			Key:        "tls/key.pem",
+			ClientAuth: ClientAuthNone,
+		},
+		Unix: Unix{
+			Path: "/tmp/postParser.sock",
+			Mode: "0660",
+		},
+		Timeouts: Timeouts{
+			Idle:     "30s",
+			Request:  "0s",
+			Response: "30s",
+		},
+	}
+}
+
+// Load returns configuration built from defaults, config file, environment variables and command line args.
+// Config file is JSON, its path is set by -config flag or CONFIG_FILE environment variable.
+// Tested in config_test.go
+func Load(args []string) (Config, error) {
+	c := New()
+
+	fs := flag.NewFlagSet("postParser", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fc := New() // values of flags, only flags set explicitly are taken
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "path to JSON config file")
+	fs.BoolVar(&fc.HTTP.Enabled, "http", fc.HTTP.Enabled, "enable HTTP receiver")
+	fs.StringVar(&fc.HTTP.Addr, "http-addr", fc.HTTP.Addr, "HTTP listen address")
+	fs.BoolVar(&fc.HTTP.Proxy, "http-proxy", fc.HTTP.Proxy, "expect PROXY protocol header on HTTP connections")
+	fs.BoolVar(&fc.HTTPS.Enabled, "https", fc.HTTPS.Enabled, "enable HTTPS receiver")
+	fs.StringVar(&fc.HTTPS.Addr, "https-addr", fc.HTTPS.Addr, "HTTPS listen address")
+	fs.StringVar(&fc.HTTPS.Cert, "tls-cert", fc.HTTPS.Cert, "x509 certificate file")
+	fs.StringVar(&fc.HTTPS.Key, "tls-key", fc.HTTPS.Key, "x509 key file")
+	fs.StringVar(&fc.HTTPS.CertDir, "tls-cert-dir", fc.HTTPS.CertDir, "directory of x509 pairs picked by SNI server name")
+	fs.BoolVar(&fc.HTTPS.Proxy, "https-proxy", fc.HTTPS.Proxy, "expect PROXY protocol header on HTTPS connections")
+	fs.StringVar(&fc.HTTPS.ClientCA, "tls-client-ca", fc.HTTPS.ClientCA, "CA bundle verifying client certificates")
+	fs.StringVar(&fc.HTTPS.ClientAuth, "tls-client-auth", fc.HTTPS.ClientAuth, "client certificate verify mode: none, request or require")
+	fs.BoolVar(&fc.Unix.Enabled, "unix", fc.Unix.Enabled, "enable Unix socket receiver")
+	fs.StringVar(&fc.Unix.Path, "unix-path", fc.Unix.Path, "Unix socket path")
+	fs.StringVar(&fc.Unix.Mode, "unix-mode", fc.Unix.Mode, "Unix socket file permission, octal")
+	fs.BoolVar(&fc.Unix.Proxy, "unix-proxy", fc.Unix.Proxy, "expect PROXY protocol header on Unix socket connections")
+	fs.StringVar(&fc.Auth.TokenFile, "auth-token-file", fc.Auth.TokenFile, "file of bearer tokens")
+	fs.StringVar(&fc.Auth.JWTKey, "auth-jwt-key", fc.Auth.JWTKey, "PEM public key verifying bearer JWT")
+	fs.IntVar(&fc.Limits.Conns, "limit-conns", fc.Limits.Conns, "concurrent connections, unlimited if zero")
+	fs.IntVar(&fc.Limits.ConnsPerIP, "limit-conns-per-ip", fc.Limits.ConnsPerIP, "concurrent connections of single client IP")
+	fs.Float64Var(&fc.Limits.Requests, "limit-requests", fc.Limits.Requests, "requests per second")
+	fs.Float64Var(&fc.Limits.RequestsPerIP, "limit-requests-per-ip", fc.Limits.RequestsPerIP, "requests per second of single client IP")
+	fs.Float64Var(&fc.Limits.Bytes, "limit-bytes", fc.Limits.Bytes, "request body bytes per second")
+	fs.Float64Var(&fc.Limits.BytesPerIP, "limit-bytes-per-ip", fc.Limits.BytesPerIP, "request body bytes per second of single client IP")
+	fs.Int64Var(&fc.Upload.MaxBodySize, "max-body-size", fc.Upload.MaxBodySize, "bytes of request body, unlimited if zero")
+	fs.Int64Var(&fc.Upload.MaxParts, "max-parts", fc.Upload.MaxParts, "parts of request body")
+	fs.Int64Var(&fc.Upload.MaxFieldSize, "max-field-size", fc.Upload.MaxFieldSize, "bytes of text field")
+	fs.Int64Var(&fc.Upload.MaxFileSize, "max-file-size", fc.Upload.MaxFileSize, "bytes of file")
+	fs.StringVar(&fc.Timeouts.Idle, "idle-timeout", fc.Timeouts.Idle, "max pause between data arrivals")
+	fs.StringVar(&fc.Timeouts.Request, "request-timeout", fc.Timeouts.Request, "max duration of whole request, unlimited if zero")
+	fs.StringVar(&fc.Timeouts.Response, "response-timeout", fc.Timeouts.Response, "max wait for request handling to be finished")
+	fs.StringVar(&fc.MetricsAddr, "metrics-addr", fc.MetricsAddr, "listen address of expvar metrics")
+	jsonFiles := fs.String("json-files", "", "comma separated top-level JSON properties holding files")
+	fs.BoolVar(&fc.Decode.KeepTransferEncoding, "keep-transfer-encoding", fc.Decode.KeepTransferEncoding, "pass content of parts having Content-Transfer-Encoding without decoding")
+
+	if err := fs.Parse(args); err != nil {
+		return c, fmt.Errorf("in config.Load %w: %v", ErrInvalid, err)
+	}
+
+	if len(*file) > 0 {
+		if err := c.readFile(*file); err != nil {
+			return c, err
+		}
+	}
+
+	if err := c.readEnv(); err != nil {
+		return c, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http":
+			c.HTTP.Enabled = fc.HTTP.Enabled
+		case "http-addr":
+			c.HTTP.Addr = fc.HTTP.Addr
+		case "http-proxy":
+			c.HTTP.Proxy = fc.HTTP.Proxy
+		case "https":
+			c.HTTPS.Enabled = fc.HTTPS.Enabled
+		case "https-addr":
+			c.HTTPS.Addr = fc.HTTPS.Addr
+		case "tls-cert":
+			c.HTTPS.Cert = fc.HTTPS.Cert
+		case "tls-key":
+			c.HTTPS.Key = fc.HTTPS.Key
+		case "tls-cert-dir":
+			c.HTTPS.CertDir = fc.HTTPS.CertDir
+		case "https-proxy":
+			c.HTTPS.Proxy = fc.HTTPS.Proxy
+		case "tls-client-ca":
+			c.HTTPS.ClientCA = fc.HTTPS.ClientCA
+		case "tls-client-auth":
+			c.HTTPS.ClientAuth = fc.HTTPS.ClientAuth
+		case "unix":
+			c.Unix.Enabled = fc.Unix.Enabled
+		case "unix-path":
+			c.Unix.Path = fc.Unix.Path
+		case "unix-mode":
+			c.Unix.Mode = fc.Unix.Mode
+		case "unix-proxy":
+			c.Unix.Proxy = fc.Unix.Proxy
+		case "auth-token-file":
+			c.Auth.TokenFile = fc.Auth.TokenFile
+		case "auth-jwt-key":
+			c.Auth.JWTKey = fc.Auth.JWTKey
+		case "limit-conns":
+			c.Limits.Conns = fc.Limits.Conns
+		case "limit-conns-per-ip":
+			c.Limits.ConnsPerIP = fc.Limits.ConnsPerIP
+		case "limit-requests":
+			c.Limits.Requests = fc.Limits.Requests
+		case "limit-requests-per-ip":
+			c.Limits.RequestsPerIP = fc.Limits.RequestsPerIP
+		case "limit-bytes":
+			c.Limits.Bytes = fc.Limits.Bytes
+		case "limit-bytes-per-ip":
+			c.Limits.BytesPerIP = fc.Limits.BytesPerIP
+		case "max-body-size":
+			c.Upload.MaxBodySize = fc.Upload.MaxBodySize
+		case "max-parts":
+			c.Upload.MaxParts = fc.Upload.MaxParts
+		case "max-field-size":
+			c.Upload.MaxFieldSize = fc.Upload.MaxFieldSize
+		case "max-file-size":
+			c.Upload.MaxFileSize = fc.Upload.MaxFileSize
+		case "idle-timeout":
+			c.Timeouts.Idle = fc.Timeouts.Idle
+		case "request-timeout":
+			c.Timeouts.Request = fc.Timeouts.Request
+		case "response-timeout":
+			c.Timeouts.Response = fc.Timeouts.Response
+		case "metrics-addr":
+			c.MetricsAddr = fc.MetricsAddr
+		case "json-files":
+			c.Decode.JSONFiles = splitList(*jsonFiles)
+		case "keep-transfer-encoding":
+			c.Decode.KeepTransferEncoding = fc.Decode.KeepTransferEncoding
+		}
+	})
+
+	return c, c.Check()
+}
+
+// readFile overrides c by fields present in JSON file
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("in config.readFile %w: %v", ErrInvalid, err)
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(c); err != nil {
+		return fmt.Errorf("in config.readFile %w: %q: %v", ErrInvalid, path, err)
+	}
+	return nil
+}
+
+// readEnv overrides c by environment variables which are set
+func (c *Config) readEnv() error {
+	for _, v := range []struct {
+		name string
+		dst  *bool
+	}{
+		{"HTTP_ENABLED", &c.HTTP.Enabled},
+		{"HTTPS_ENABLED", &c.HTTPS.Enabled},
+		{"UNIX_ENABLED", &c.Unix.Enabled},
+		{"HTTP_PROXY_PROTOCOL", &c.HTTP.Proxy}, // HTTP_PROXY is taken by Go HTTP clients
+		{"HTTPS_PROXY_PROTOCOL", &c.HTTPS.Proxy},
+		{"UNIX_PROXY_PROTOCOL", &c.Unix.Proxy},
+		{"KEEP_TRANSFER_ENCODING", &c.Decode.KeepTransferEncoding},
+	} {
+		s, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("in config.readEnv %w: %s=%q", ErrInvalid, v.name, s)
+		}
+		*v.dst = b
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *string
+	}{
+		{"HTTP_ADDR", &c.HTTP.Addr},
+		{"HTTPS_ADDR", &c.HTTPS.Addr},
+		{"TLS_CERT", &c.HTTPS.Cert},
+		{"TLS_KEY", &c.HTTPS.Key},
+		{"TLS_CERT_DIR", &c.HTTPS.CertDir},
+		{"TLS_CLIENT_CA", &c.HTTPS.ClientCA},
+		{"TLS_CLIENT_AUTH", &c.HTTPS.ClientAuth},
+		{"UNIX_PATH", &c.Unix.Path},
+		{"UNIX_MODE", &c.Unix.Mode},
+		{"AUTH_TOKEN_FILE", &c.Auth.TokenFile},
+		{"AUTH_JWT_KEY", &c.Auth.JWTKey},
+		{"METRICS_ADDR", &c.MetricsAddr},
+		{"IDLE_TIMEOUT", &c.Timeouts.Idle},
+		{"REQUEST_TIMEOUT", &c.Timeouts.Request},
+		{"RESPONSE_TIMEOUT", &c.Timeouts.Response},
+	} {
+		if s, ok := os.LookupEnv(v.name); ok {
+			*v.dst = s
+		}
+	}
+
+	if s, ok := os.LookupEnv("JSON_FILES"); ok {
+		c.Decode.JSONFiles = splitList(s)
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *int
+	}{
+		{"LIMIT_CONNS", &c.Limits.Conns},
+		{"LIMIT_CONNS_PER_IP", &c.Limits.ConnsPerIP},
+	} {
+		s, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("in config.readEnv %w: %s=%q", ErrInvalid, v.name, s)
+		}
+		*v.dst = n
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *int64
+	}{
+		{"MAX_BODY_SIZE", &c.Upload.MaxBodySize},
+		{"MAX_PARTS", &c.Upload.MaxParts},
+		{"MAX_FIELD_SIZE", &c.Upload.MaxFieldSize},
+		{"MAX_FILE_SIZE", &c.Upload.MaxFileSize},
+	} {
+		s, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("in config.readEnv %w: %s=%q", ErrInvalid, v.name, s)
+		}
+		*v.dst = n
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"LIMIT_REQUESTS", &c.Limits.Requests},
+		{"LIMIT_REQUESTS_PER_IP", &c.Limits.RequestsPerIP},
+		{"LIMIT_BYTES", &c.Limits.Bytes},
+		{"LIMIT_BYTES_PER_IP", &c.Limits.BytesPerIP},
+	} {
+		s, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("in config.readEnv %w: %s=%q", ErrInvalid, v.name, s)
+		}
+		*v.dst = f
+	}
+	return nil
+}
+
+// splitList returns non-empty items of comma separated list s
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// Check returns error if no receiver is enabled or enabled receiver lacks settings
+func (c Config) Check() error {
+	switch {
+	case !c.HTTP.Enabled && !c.HTTPS.Enabled && !c.Unix.Enabled:
+		return fmt.Errorf("in config.Check %w: all receivers are disabled", ErrInvalid)
+	case c.HTTP.Enabled && len(c.HTTP.Addr) == 0:
+		return fmt.Errorf("in config.Check %w: HTTP address is empty", ErrInvalid)
+	case c.HTTPS.Enabled && len(c.HTTPS.Addr) == 0:
+		return fmt.Errorf("in config.Check %w: HTTPS address is empty", ErrInvalid)
+	case c.HTTPS.Enabled && (len(c.HTTPS.Cert) == 0 || len(c.HTTPS.Key) == 0):
+		return fmt.Errorf("in config.Check %w: HTTPS certificate or key file is not set", ErrInvalid)
+	case c.HTTPS.Enabled && c.HTTPS.ClientAuth != ClientAuthNone && c.HTTPS.ClientAuth != ClientAuthRequest && c.HTTPS.ClientAuth != ClientAuthRequire:
+		return fmt.Errorf("in config.Check %w: unknown client certificate verify mode %q", ErrInvalid, c.HTTPS.ClientAuth)
+	case c.HTTPS.Enabled && c.HTTPS.ClientAuth != ClientAuthNone && len(c.HTTPS.ClientCA) == 0:
+		return fmt.Errorf("in config.Check %w: client CA bundle is not set", ErrInvalid)
+	case c.Unix.Enabled && len(c.Unix.Path) == 0:
+		return fmt.Errorf("in config.Check %w: Unix socket path is empty", ErrInvalid)
+	case c.Limits.Conns < 0 || c.Limits.ConnsPerIP < 0 || c.Limits.Requests < 0 || c.Limits.RequestsPerIP < 0 || c.Limits.Bytes < 0 || c.Limits.BytesPerIP < 0:
+		return fmt.Errorf("in config.Check %w: negative limit", ErrInvalid)
+	case c.Upload.MaxBodySize < 0 || c.Upload.MaxParts < 0 || c.Upload.MaxFieldSize < 0 || c.Upload.MaxFileSize < 0:
+		return fmt.Errorf("in config.Check %w: negative upload limit", ErrInvalid)
+	}
+	for _, t := range []string{c.Timeouts.Idle, c.Timeouts.Request, c.Timeouts.Response} {
+		if d, err := time.ParseDuration(t); err != nil || d < 0 {
+			return fmt.Errorf("in config.Check %w: timeout %q is not non-negative duration", ErrInvalid, t)
+		}
+	}
+	if m, err := strconv.ParseUint(c.Unix.Mode, 8, 32); c.Unix.Enabled && (err != nil || m > 0777) {
+		return fmt.Errorf("in config.Check %w: Unix socket mode %q is not octal permission", ErrInvalid, c.Unix.Mode)
+	}
+	return c.checkRoutes()
+}
+
+// checkRoutes returns error if any route lacks name or path, or routes are ambiguous
+func (c Config) checkRoutes() error {
+	names, keys := make(map[string]bool), make(map[string]bool)
+
+	for _, r := range c.Routes {
+		m := r.Method
+		if len(m) == 0 {
+			m = http.MethodPost
+		}
+		key := strings.ToUpper(m) + " " + strings.TrimSuffix(r.Path, "/")
+		switch {
+		case len(r.Name) == 0:
+			return fmt.Errorf("in config.checkRoutes %w: route for %q has no name", ErrInvalid, r.Path)
+		case names[r.Name]:
+			return fmt.Errorf("in config.checkRoutes %w: route name %q is repeated", ErrInvalid, r.Name)
+		case !strings.HasPrefix(r.Path, "/"):
+			return fmt.Errorf("in config.checkRoutes %w: path %q of route %q should start with /", ErrInvalid, r.Path, r.Name)
+		case keys[key]:
+			return fmt.Errorf("in config.checkRoutes %w: route %q repeats method and path of another route", ErrInvalid, r.Name)
+		case r.MaxBodySize < 0:
+			return fmt.Errorf("in config.checkRoutes %w: negative body size limit of route %q", ErrInvalid, r.Name)
+		case len(r.Principals) > 0 && !c.Auth.Enabled():
+			return fmt.Errorf("in config.checkRoutes %w: route %q restricts principals but authentication is not set", ErrInvalid, r.Name)
+		}
+		names[r.Name], keys[key] = true, true
+	}
+	return nil
+}
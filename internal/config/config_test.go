@@ -0,0 +1,353 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vynovikov/postParser/internal/repo"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type configSuite struct {
+	suite.Suite
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(configSuite))
+}
+
+func (s *configSuite) TestLoad() {
+	dir := s.T().TempDir()
+
+	file := filepath.Join(dir, "config.json")
+	s.NoError(os.WriteFile(file, []byte(`{"http":{"addr":":8080"},"https":{"addr":":8443","cert":"c.pem"}}`), 0644))
+
+	unknown := filepath.Join(dir, "unknown.json")
+	s.NoError(os.WriteFile(unknown, []byte(`{"http":{"port":8080}}`), 0644))
+
+	routes := filepath.Join(dir, "routes.json")
+	s.NoError(os.WriteFile(routes, []byte(`{"routes":[`+
+		`{"name":"avatars","path":"/avatars","maxBodySize":1048576,"contentTypes":["multipart/form-data"],"saver":"avatars:3100","tags":{"kind":"image"}},`+
+		`{"name":"documents","method":"POST","path":"/documents"}]}`), 0644))
+
+	principals := filepath.Join(dir, "principals.json")
+	s.NoError(os.WriteFile(principals, []byte(`{"routes":[{"name":"a","path":"/avatars","principals":["alice"]}]}`), 0644))
+
+	auth := filepath.Join(dir, "auth.json")
+	s.NoError(os.WriteFile(auth, []byte(`{"auth":{"basic":{"alice":"secret"},"hmac":{"partner":"shared"},"hmacHeaders":["date"],"realm":"uploads"}}`), 0644))
+
+	timed := filepath.Join(dir, "timed.json")
+	s.NoError(os.WriteFile(timed, []byte(`{"timeouts":{"idle":"5s","request":"1m"}}`), 0644))
+
+	timeouts := New().Timeouts
+
+	repeated := filepath.Join(dir, "repeated.json")
+	s.NoError(os.WriteFile(repeated, []byte(`{"routes":[{"name":"a","path":"/avatars"},{"name":"b","method":"post","path":"/avatars/"}]}`), 0644))
+
+	tt := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    Config
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			want: New(),
+		},
+
+		{
+			name: "config file",
+			args: []string{"-config", file},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":8080"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":8443", Cert: "c.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name: "environment overrides config file",
+			env:  map[string]string{"CONFIG_FILE": file, "HTTP_ADDR": "127.0.0.1:3000", "HTTPS_ENABLED": "false"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: "127.0.0.1:3000"},
+				HTTPS:    HTTPS{Enabled: false, Addr: ":8443", Cert: "c.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name: "flags override environment",
+			args: []string{"-https=false", "-http-addr", ":3001", "-tls-key", "k.pem"},
+			env:  map[string]string{"HTTPS_ENABLED": "true", "HTTP_ADDR": ":3002", "TLS_KEY": "key.pem"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3001"},
+				HTTPS:    HTTPS{Enabled: false, Addr: ":443", Cert: "tls/cert.pem", Key: "k.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name: "Unix socket receiver only",
+			args: []string{"-http=false", "-https=false", "-unix", "-unix-path", "/run/pp.sock"},
+			env:  map[string]string{"UNIX_MODE": "0600"},
+			want: Config{
+				HTTP:     HTTP{Enabled: false, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: false, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Enabled: true, Path: "/run/pp.sock", Mode: "0600"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name: "PROXY protocol",
+			args: []string{"-https-proxy"},
+			env:  map[string]string{"HTTP_PROXY_PROTOCOL": "true", "HTTP_PROXY": "http://proxy:3128"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000", Proxy: true},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", Proxy: true, ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name: "client certificates are required",
+			args: []string{"-tls-client-auth", "require", "-tls-cert-dir", "tls/tenants"},
+			env:  map[string]string{"TLS_CLIENT_CA": "ca.pem", "TLS_CERT_DIR": "tenants"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", CertDir: "tls/tenants", ClientCA: "ca.pem", ClientAuth: ClientAuthRequire},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+			},
+		},
+
+		{
+			name:    "unhappy unknown client certificate verify mode",
+			args:    []string{"-tls-client-auth", "verify"},
+			env:     map[string]string{"TLS_CLIENT_CA": "ca.pem"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy client CA bundle is not set",
+			args:    []string{"-tls-client-auth", "request"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy all receivers are disabled",
+			args:    []string{"-http=false", "-https=false"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy unknown flag",
+			args:    []string{"-port", "3000"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy environment variable is not bool",
+			env:     map[string]string{"HTTP_ENABLED": "maybe"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy config file is missing",
+			args:    []string{"-config", filepath.Join(dir, "missing.json")},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy unknown field in config file",
+			args:    []string{"-config", unknown},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy Unix socket mode is not octal",
+			args:    []string{"-unix", "-unix-mode", "0999"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name: "routes",
+			args: []string{"-config", routes},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+				Routes: repo.Routes{
+					{Name: "avatars", Path: "/avatars", MaxBodySize: 1048576, ContentTypes: []string{"multipart/form-data"}, Saver: "avatars:3100", Tags: map[string]string{"kind": "image"}},
+					{Name: "documents", Method: "POST", Path: "/documents"},
+				},
+			},
+		},
+
+		{
+			name: "authentication",
+			args: []string{"-config", auth, "-auth-jwt-key", "jwt.pem"},
+			env:  map[string]string{"AUTH_TOKEN_FILE": "tokens"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+				Auth: Auth{
+					Basic:       map[string]string{"alice": "secret"},
+					TokenFile:   "tokens",
+					JWTKey:      "jwt.pem",
+					HMAC:        map[string]string{"partner": "shared"},
+					HMACHeaders: []string{"date"},
+					Realm:       "uploads",
+				},
+			},
+		},
+
+		{
+			name: "limits and metrics",
+			args: []string{"-limit-conns", "100", "-limit-requests-per-ip", "2.5", "-metrics-addr", ":9090"},
+			env:  map[string]string{"LIMIT_CONNS": "10", "LIMIT_CONNS_PER_IP": "4", "LIMIT_BYTES_PER_IP": "1048576"},
+			want: Config{
+				HTTP:        HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:       HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:        Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts:    timeouts,
+				Limits:      Limits{Conns: 100, ConnsPerIP: 4, RequestsPerIP: 2.5, BytesPerIP: 1048576},
+				MetricsAddr: ":9090",
+			},
+		},
+
+		{
+			name: "upload limits",
+			args: []string{"-max-body-size", "1048576", "-max-file-size", "524288"},
+			env:  map[string]string{"MAX_PARTS": "10", "MAX_FIELD_SIZE": "1024", "MAX_FILE_SIZE": "1"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+				Upload:   repo.UploadLimits{MaxBodySize: 1048576, MaxParts: 10, MaxFieldSize: 1024, MaxFileSize: 524288},
+			},
+		},
+
+		{
+			name: "JSON files",
+			args: []string{"-json-files", "avatar, cover,"},
+			env:  map[string]string{"JSON_FILES": "document"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+				Decode:   repo.Decoding{JSONFiles: []string{"avatar", "cover"}},
+			},
+		},
+
+		{
+			name: "keep transfer encoding",
+			env:  map[string]string{"KEEP_TRANSFER_ENCODING": "true"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: timeouts,
+				Decode:   repo.Decoding{KeepTransferEncoding: true},
+			},
+		},
+
+		{
+			name: "timeouts",
+			args: []string{"-config", timed, "-response-timeout", "1m30s"},
+			env:  map[string]string{"REQUEST_TIMEOUT": "2m", "RESPONSE_TIMEOUT": "1m"},
+			want: Config{
+				HTTP:     HTTP{Enabled: true, Addr: ":3000"},
+				HTTPS:    HTTPS{Enabled: true, Addr: ":443", Cert: "tls/cert.pem", Key: "tls/key.pem", ClientAuth: ClientAuthNone},
+				Unix:     Unix{Path: "/tmp/postParser.sock", Mode: "0660"},
+				Timeouts: Timeouts{Idle: "5s", Request: "2m", Response: "1m30s"},
+			},
+		},
+
+		{
+			name:    "unhappy timeout is not duration",
+			env:     map[string]string{"IDLE_TIMEOUT": "30"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy negative timeout",
+			args:    []string{"-request-timeout", "-1s"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy negative upload limit",
+			env:     map[string]string{"MAX_PARTS": "-1"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy limit is not a number",
+			env:     map[string]string{"LIMIT_REQUESTS": "many"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy negative limit",
+			args:    []string{"-limit-conns-per-ip", "-1"},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy route restricts principals without authentication",
+			args:    []string{"-config", principals},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy routes repeat method and path",
+			args:    []string{"-config", repeated},
+			wantErr: ErrInvalid,
+		},
+
+		{
+			name:    "unhappy empty certificate",
+			env:     map[string]string{"TLS_CERT": ""},
+			wantErr: ErrInvalid,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			for _, name := range []string{"CONFIG_FILE", "HTTP_ENABLED", "HTTP_ADDR", "HTTPS_ENABLED", "HTTPS_ADDR", "TLS_CERT", "TLS_KEY", "UNIX_ENABLED", "UNIX_PATH", "UNIX_MODE", "HTTP_PROXY_PROTOCOL", "HTTPS_PROXY_PROTOCOL", "UNIX_PROXY_PROTOCOL", "TLS_CLIENT_CA", "TLS_CLIENT_AUTH", "TLS_CERT_DIR", "AUTH_TOKEN_FILE", "AUTH_JWT_KEY", "LIMIT_CONNS", "LIMIT_CONNS_PER_IP", "LIMIT_REQUESTS", "LIMIT_REQUESTS_PER_IP", "LIMIT_BYTES", "LIMIT_BYTES_PER_IP", "METRICS_ADDR", "MAX_BODY_SIZE", "MAX_PARTS", "MAX_FIELD_SIZE", "MAX_FILE_SIZE", "JSON_FILES", "KEEP_TRANSFER_ENCODING", "IDLE_TIMEOUT", "REQUEST_TIMEOUT", "RESPONSE_TIMEOUT"} {
+				s.T().Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for k, e := range v.env {
+				s.T().Setenv(k, e)
+			}
+
+			got, err := Load(v.args)
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr))
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.want, got)
+		})
+	}
+}
+
+func (s *configSuite) TestDurations() {
+	s.Equal(repo.Timeouts{Idle: time.Second * 30, Response: time.Second * 30}, New().Timeouts.Durations())
+	s.Equal(repo.Timeouts{Idle: time.Millisecond * 1500, Request: time.Minute}, Timeouts{Idle: "1.5s", Request: "1m", Response: "0"}.Durations())
+}
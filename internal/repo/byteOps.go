@@ -138,7 +138,7 @@ func GetCurrentLineRight(b []byte, fromIndex, limit int) ([]byte, error) {
 		}
 		i++
 	}
-	if b[len(bb)+1] == 10 {
+	if i+1 < lenb && b[i+1] == 10 {
 		bb = append(bb, []byte("\r\n")...)
 		return bb, nil
 	}
@@ -553,11 +553,11 @@ func GetLinesRightBegin(b []byte, limit int, bou Boundary) ([][]byte, int, error
 	//logger.L.Infof("repo.GetLinesRightBegin ivoked with params b: %q, limit= %d, bou: %v\n", b, limit, bou)
 	lines, i, cut := make([][]byte, 0), 0, 0
 
-	r1, err := regexp.Compile("Content-Disposition:\\sform-data;\\sname=\"\\w+\"")
+	r1, err := regexp.Compile("Content-Disposition:\\sform-data;\\sname=\"[^\"]+\"")
 	if err != nil {
 		return lines, cut, errors.New("in repo.GetLinesLeft error while compiling regexp")
 	}
-	r2, err := regexp.Compile(".*\"?[a-zA-Z0-9_.-]+\"$")
+	r2, err := regexp.Compile(".*[^\"]\"$")
 	if err != nil {
 		return lines, cut, errors.New("in repo.GetLinesLeft error while compiling regexp")
 	}
@@ -659,7 +659,7 @@ func GetLinesRightMiddle(b []byte, limit int) ([][]byte, int, error) {
 					return lines, len(l), fmt.Errorf("in repo.GetLinesRightMiddle header \"%s\" is not full", l)
 				}
 			}
-			if !bytes.Contains(b, []byte("filename=\"")) {
+			if !bytes.Contains(b, []byte("filename=")) && !bytes.Contains(b, []byte("filename*=")) {
 				return lines, c + len(l) + 4, nil
 			}
 
@@ -939,17 +939,15 @@ func InOneLine(lines [][]byte) string {
 	return sb.String()
 }
 
-// GetFoFi returns formname and filename found in b
+// GetFoFi returns formname and filename found in b.
+// Names may be quoted strings, tokens or extended values, extended ones are preferred
 func GetFoFi(b []byte) (string, string) {
-	fo, fi, foPre, fiPre := "", "", []byte(" name=\""), []byte(" filename=\"")
-	if len(b) > 0 {
-		if bytes.Contains(b, foPre) {
-			fo = string(b[bytes.Index(b, foPre)+len(foPre) : FindNext(b, []byte("\""), bytes.Index(b, foPre)+len(foPre))])
-			if bytes.Contains(b, fiPre) {
-				fi = string(b[bytes.Index(b, fiPre)+len(fiPre) : FindNext(b, []byte("\""), bytes.Index(b, fiPre)+len(fiPre))])
-			}
-		}
-	}
+	p := newCDParser(cdLiteral).parse(cdLineOf(b))
+	p.over()
+
+	fo, _ := p.param("name")
+	fi, _ := p.param("filename")
+
 	return fo, fi
 }
 
@@ -1255,7 +1253,7 @@ func IsBoundary(p, n []byte, bou Boundary) bool {
 func IsLastBoundary(p, n []byte, bou Boundary) bool {
 	realBoundary := GenBoundary(bou)
 	combined := append(p, n...)
-	if len(combined) > len(realBoundary) &&
+	if len(combined) >= len(realBoundary)+2 &&
 		bytes.Contains(combined, realBoundary) &&
 		!bytes.Contains(combined[len(realBoundary):len(realBoundary)+2], []byte("\r\n")) {
 		return true
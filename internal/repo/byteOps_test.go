@@ -1749,3 +1749,35 @@ func (s *byteOpsSuite) TestIsLastBoundaryPart() {
 		})
 	}
 }
+
+func (s *byteOpsSuite) TestChunkEndingWithCR() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	notLF := errors.New("in repo.GetCurrentLineRight bb is not ending with LF")
+
+	s.Run("line right", func() {
+		var (
+			l   []byte
+			err error
+		)
+		s.NotPanics(func() { l, err = GetCurrentLineRight([]byte("11111\r"), 0, 6) })
+		s.Equal([]byte("11111"), l)
+		s.Equal(notLF, err)
+	})
+
+	s.Run("line right trimmed", func() {
+		var (
+			c   int
+			l   []byte
+			err error
+		)
+		s.NotPanics(func() { c, l, err = SingleLineRightTrimmed([]byte("\r\n11111\r"), 8) })
+		s.Equal(2, c)
+		s.Equal([]byte("11111"), l)
+		s.Equal(notLF, err)
+	})
+
+	s.Run("last boundary shorter than boundary and two bytes", func() {
+		s.NotPanics(func() { s.False(IsLastBoundary([]byte("\r\n--aza"), []byte("za"), bou)) })
+		s.NotPanics(func() { s.False(IsLastBoundary([]byte("\r\n--azaza"), []byte("\r"), bou)) })
+	})
+}
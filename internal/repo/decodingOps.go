@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Media types of request bodies receivers handle, body of any other type is single file
+const (
+	MultipartFormData = "multipart/form-data"
+	FormURLEncoded    = "application/x-www-form-urlencoded"
+	JSON              = "application/json"
+	OctetStream       = "application/octet-stream"
+	FormTextPlain     = "text/plain" // HTML form encoding which is not decoded, body of POST having it is not single file
+)
+
+// Form and file names of single file body which are not set by request
+const (
+	rawFormName = "file"
+	rawFileName = "upload"
+)
+
+// maxFormName is the longest form name which keeps Content-Disposition line within MaxHeaderLimit
+const maxFormName = MaxHeaderLimit - len("Content-Disposition: form-data; name=\"\"") - 2*len(Sep)
+
+// formNameEscaper escapes form name the way browsers do when they put it into multipart body
+var formNameEscaper = strings.NewReplacer("\"", "%22", "\r", "%0D", "\n", "%0A")
+
+// Decoding describes how bodies other than multipart are converted into multipart ones
+type Decoding struct {
+	JSONFiles []string `json:"jsonFiles"` // top-level JSON properties holding files as {"filename":..., "contentType":..., "data":"<base64>"}
+
+	KeepTransferEncoding bool `json:"keepTransferEncoding"` // content of multipart parts having Content-Transfer-Encoding is passed as is
+}
+
+// Multipart returns reader of multipart body framed by rh.Bou made of body of request having header rh.
+// Multipart body has its nested multipart/mixed parts flattened. Headers of parts are put into parts,
+// parts having base64 or quoted-printable Content-Transfer-Encoding are decoded unless d.KeepTransferEncoding is set
+func (d Decoding) Multipart(body io.Reader, rh RequestHeader, parts *Parts) io.Reader {
+	switch {
+	case rh.raw():
+		name, filename, ct := rh.rawFile()
+		body = NewFileReader(body, rh.Bou, name, filename, ct)
+	case rh.MediaType() == FormURLEncoded:
+		body = NewFormReader(body, rh.Bou)
+	case rh.MediaType() == JSON:
+		body = NewJSONReader(body, rh.Bou, d.JSONFiles)
+	case rh.MediaType() != MultipartFormData:
+		return body
+	}
+	return NewFlatReader(body, rh.Bou, parts, !d.KeepTransferEncoding)
+}
+
+// converted returns true if body of request having header rh is converted into multipart one
+func (rh RequestHeader) converted() bool {
+	mt := rh.MediaType()
+
+	return rh.raw() || mt == FormURLEncoded || mt == JSON
+}
+
+// raw returns true if body of request having header rh is single file.
+// PUT body is single file unless it is multipart one, which is sliced the ordinary way.
+// POST body is single file if its media type is set and is neither multipart, JSON nor any of HTML form encodings
+func (rh RequestHeader) raw() bool {
+	mt := rh.MediaType()
+	if strings.HasPrefix(mt, "multipart/") {
+		return false
+	}
+	switch rh.Method {
+	case http.MethodPut:
+		return true
+	case http.MethodPost:
+		return len(mt) > 0 && mt != FormURLEncoded && mt != FormTextPlain && mt != JSON
+	}
+	return false
+}
+
+// rawFile returns form name, file name and content type of single file body.
+// Names are taken from Content-Disposition header, file name defaults to the last segment of path
+func (rh RequestHeader) rawFile() (string, string, string) {
+	name, filename, ct := rawFormName, "", rh.ContentType
+	if len(rh.MediaType()) == 0 {
+		ct = OctetStream
+	}
+
+	if _, params, err := mime.ParseMediaType(rh.Fields.Get("Content-Disposition")); err == nil {
+		if len(params["name"]) > 0 {
+			name = params["name"]
+		}
+		filename = params["filename"]
+	}
+	if len(filename) == 0 {
+		p := rh.Path
+		if i := strings.IndexByte(p, '?'); i >= 0 {
+			p = p[:i]
+		}
+		if u, err := url.PathUnescape(p); err == nil {
+			p = u
+		}
+		if !strings.HasSuffix(p, "/") {
+			filename = path.Base(p)
+		}
+	}
+	if len(filename) == 0 || filename == "/" || filename == "." {
+		filename = rawFileName
+	}
+	return name, filename, ct
+}
+
+// NewFileReader returns reader of multipart body framed by boundary bou having body as the only file part.
+// Tested in decodingOps_test.go
+func NewFileReader(body io.Reader, bou Boundary, name, filename, contentType string) io.Reader {
+	f := &fileReader{r: body, partWriter: partWriter{bou: bou}}
+	f.err = f.open(name, filename, contentType)
+
+	return f
+}
+
+// fileReader wraps body into multipart one
+type fileReader struct {
+	partWriter
+	r    io.Reader
+	done bool // body is read, closing delimiter is written
+	err  error
+}
+
+func (f *fileReader) Read(b []byte) (int, error) {
+	if f.out.Len() > 0 {
+		return f.out.Read(b)
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.done {
+		return 0, io.EOF
+	}
+
+	n, err := f.r.Read(b)
+	switch {
+	case err == io.EOF:
+		f.done = true
+		f.close()
+	case err != nil:
+		f.err = err
+	}
+	if n == 0 && f.out.Len() > 0 {
+		return f.out.Read(b)
+	}
+	return n, nil
+}
+
+// partWriter writes parts of multipart body into out
+type partWriter struct {
+	bou   Boundary
+	out   bytes.Buffer // multipart bytes ready to be read
+	parts int
+}
+
+// open writes delimiter and header of part. Part is file if filename is not empty
+func (w *partWriter) open(name, filename, contentType string) error {
+	cd := "Content-Disposition: form-data; name=\"" + formNameEscaper.Replace(name) + "\""
+	if len(filename) > 0 {
+		cd += "; filename=\"" + formNameEscaper.Replace(filename) + "\""
+	}
+	if len(cd) > MaxHeaderLimit-2*len(Sep) {
+		return fmt.Errorf("in repo.partWriter.open %w: header of part %q exceeds %d bytes", ErrTooLarge, name, MaxHeaderLimit)
+	}
+
+	if w.parts > 0 {
+		w.out.WriteString(Sep)
+	}
+	w.out.Write(w.bou.Prefix)
+	w.out.Write(w.bou.Root)
+	w.out.WriteString(Sep + cd + Sep)
+	if len(contentType) > 0 {
+		w.out.WriteString("Content-Type: " + strings.NewReplacer("\r", "", "\n", "").Replace(contentType) + Sep)
+	}
+	w.out.WriteString(Sep)
+	w.parts++
+
+	return nil
+}
+
+// close writes closing delimiter
+func (w *partWriter) close() {
+	if w.parts > 0 {
+		w.out.WriteString(Sep)
+	}
+	w.out.Write(w.bou.Prefix)
+	w.out.Write(w.bou.Root)
+	w.out.WriteString("--" + Sep)
+}
@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type decodingOpsSuite struct {
+	suite.Suite
+}
+
+func TestDecodingOpsSuite(t *testing.T) {
+	suite.Run(t, new(decodingOpsSuite))
+}
+
+func (s *decodingOpsSuite) TestNewFileReader() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	header := "--azaza\r\nContent-Disposition: form-data; name=\"file\"; filename=\"report.pdf\"\r\nContent-Type: application/pdf\r\n\r\n"
+	long := strings.Repeat("0123456789", 500)
+
+	tt := []struct {
+		name    string
+		body    io.Reader
+		want    string
+		wantErr error
+	}{
+		{
+			name: "body",
+			body: iotest.HalfReader(strings.NewReader(long)),
+			want: header + long + "\r\n--azaza--\r\n",
+		},
+
+		{
+			name: "empty body",
+			body: strings.NewReader(""),
+			want: header + "\r\n--azaza--\r\n",
+		},
+
+		{
+			name:    "unhappy body error",
+			body:    io.MultiReader(strings.NewReader("01234"), iotest.ErrReader(ErrTooLarge)),
+			want:    header + "01234",
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(NewFileReader(v.body, bou, "file", "report.pdf", "application/pdf"))
+			s.Equal(v.want, string(got))
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
+
+func (s *decodingOpsSuite) TestRawFile() {
+	tt := []struct {
+		name         string
+		rh           RequestHeader
+		wantRaw      bool
+		wantName     string
+		wantFilename string
+		wantCT       string
+	}{
+		{
+			name:         "file name from path",
+			rh:           RequestHeader{Method: "PUT", Path: "/upload/annual%20report.pdf?v=2", ContentType: "application/pdf"},
+			wantRaw:      true,
+			wantName:     "file",
+			wantFilename: "annual report.pdf",
+			wantCT:       "application/pdf",
+		},
+
+		{
+			name: "names from Content-Disposition",
+			rh: RequestHeader{Method: "PUT", Path: "/upload/report.pdf", ContentType: "image/png",
+				Fields: textproto.MIMEHeader{"Content-Disposition": {"attachment; name=\"avatar\"; filename*=UTF-8''%D1%84.png"}}},
+			wantRaw:      true,
+			wantName:     "avatar",
+			wantFilename: "ф.png",
+			wantCT:       "image/png",
+		},
+
+		{
+			name:         "PUT without file name in path",
+			rh:           RequestHeader{Method: "PUT", Path: "/upload/", ContentType: "text/csv"},
+			wantRaw:      true,
+			wantName:     "file",
+			wantFilename: "upload",
+			wantCT:       "text/csv",
+		},
+
+		{
+			name: "PUT of form",
+			rh:   RequestHeader{Method: "PUT", Path: "/upload/", ContentType: "multipart/form-data; boundary=azaza"},
+		},
+
+		{
+			name:         "PUT without Content-Type",
+			rh:           RequestHeader{Method: "PUT", Path: "/"},
+			wantRaw:      true,
+			wantName:     "file",
+			wantFilename: "upload",
+			wantCT:       "application/octet-stream",
+		},
+
+		{
+			name: "POST of form",
+			rh:   RequestHeader{Method: "POST", Path: "/", ContentType: "multipart/form-data; boundary=azaza"},
+		},
+
+		{
+			name: "POST of JSON",
+			rh:   RequestHeader{Method: "POST", Path: "/", ContentType: "application/json"},
+		},
+
+		{
+			name:         "POST of PDF",
+			rh:           RequestHeader{Method: "POST", Path: "/upload/report.pdf", ContentType: "application/pdf"},
+			wantRaw:      true,
+			wantName:     "file",
+			wantFilename: "report.pdf",
+			wantCT:       "application/pdf",
+		},
+
+		{
+			name:         "POST of binary",
+			rh:           RequestHeader{Method: "POST", Path: "/upload", ContentType: "application/octet-stream"},
+			wantRaw:      true,
+			wantName:     "file",
+			wantFilename: "upload",
+			wantCT:       "application/octet-stream",
+		},
+
+		{
+			name: "POST of plain text form",
+			rh:   RequestHeader{Method: "POST", Path: "/", ContentType: "text/plain; charset=utf-8"},
+		},
+
+		{
+			name: "POST of urlencoded form",
+			rh:   RequestHeader{Method: "POST", Path: "/", ContentType: "application/x-www-form-urlencoded"},
+		},
+
+		{
+			name: "POST of mixed multipart",
+			rh:   RequestHeader{Method: "POST", Path: "/", ContentType: "multipart/mixed; boundary=azaza"},
+		},
+
+		{
+			name: "POST without Content-Type",
+			rh:   RequestHeader{Method: "POST", Path: "/"},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.wantRaw, v.rh.raw())
+			if !v.wantRaw {
+				return
+			}
+			name, filename, ct := v.rh.rawFile()
+			s.Equal(v.wantName, name)
+			s.Equal(v.wantFilename, filename)
+			s.Equal(v.wantCT, ct)
+		})
+	}
+}
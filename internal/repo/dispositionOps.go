@@ -0,0 +1,346 @@
+package repo
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cdLine is what Content-Disposition header line of form part begins with
+const cdLine = "Content-Disposition: form-data"
+
+// cdKeys are parameters form part may have, RFC 7578 4.2, extended ones are RFC 5987.
+// Index of part is set by flatReader, the one client sent is dropped, see Parts
+var cdKeys = []string{"name", "filename", "name*", "filename*", "index"}
+
+// cdQuoter escapes name to be put into quoted-string, line breaks are escaped the way browsers do
+var cdQuoter = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\r", "%0D", "\n", "%0A")
+
+// states of cdParser
+const (
+	cdLiteral = iota // in cdLine
+	cdOWS            // after cdLine or parameter value, before semicolon
+	cdParam          // after semicolon, before parameter name
+	cdKey            // in parameter name
+	cdValue          // after equals sign, before value
+	cdToken          // in token value
+	cdQuoted         // in quoted-string value
+	cdEscape         // after backslash in quoted-string value
+	cdFail
+)
+
+// cdParser parses Content-Disposition header line of form part byte by byte, RFC 6266 4.1.
+// Line may be cut, so parser may begin in any state and may stop in any state
+type cdParser struct {
+	state  int
+	i      int  // bytes of cdLine met
+	cut    bool // current parameter is cut from left
+	key    []byte
+	val    []byte
+	params map[string]string // parameters met, extended ones are decoded
+}
+
+// newCDParser returns parser beginning in state st
+func newCDParser(st int) *cdParser {
+	return &cdParser{state: st, cut: st != cdLiteral && st != cdOWS && st != cdParam, params: make(map[string]string)}
+}
+
+// parse moves p by bytes of b
+func (p *cdParser) parse(b []byte) *cdParser {
+	for i := 0; i < len(b) && p.state != cdFail; i++ {
+		p.step(b[i])
+	}
+	return p
+}
+
+// step moves p by byte c
+func (p *cdParser) step(c byte) {
+	switch p.state {
+	case cdLiteral:
+		if c != cdLine[p.i] {
+			p.state = cdFail
+			return
+		}
+		if p.i++; p.i == len(cdLine) {
+			p.state = cdOWS
+		}
+
+	case cdOWS:
+		switch {
+		case c == ';':
+			p.state = cdParam
+		case !isWS(c):
+			p.state = cdFail
+		}
+
+	case cdParam:
+		switch {
+		case isToken(c):
+			p.state, p.cut, p.key = cdKey, false, append(p.key[:0], lower(c))
+			p.checkKey(false)
+		case !isWS(c):
+			p.state = cdFail
+		}
+
+	case cdKey:
+		switch {
+		case c == '=':
+			p.checkKey(true)
+			if p.state != cdFail {
+				p.state, p.val = cdValue, p.val[:0]
+			}
+		case isToken(c):
+			p.key = append(p.key, lower(c))
+			p.checkKey(false)
+		default:
+			p.state = cdFail
+		}
+
+	case cdValue:
+		switch {
+		case c == '"':
+			p.state = cdQuoted
+		case isToken(c):
+			p.state, p.val = cdToken, append(p.val, c)
+		default:
+			p.state = cdFail
+		}
+
+	case cdToken:
+		switch {
+		case isToken(c):
+			p.val = append(p.val, c)
+		case c == ';':
+			p.keep()
+			p.state = cdParam
+		case isWS(c):
+			p.keep()
+			p.state = cdOWS
+		default:
+			p.state = cdFail
+		}
+
+	case cdQuoted:
+		switch {
+		case c == '"':
+			p.keep()
+			p.state = cdOWS
+		case c == '\\':
+			p.state = cdEscape
+		case isCTL(c):
+			p.state = cdFail
+		default:
+			p.val = append(p.val, c)
+		}
+
+	case cdEscape:
+		if isCTL(c) {
+			p.state = cdFail
+			return
+		}
+		if c != '"' && c != '\\' { // backslash of Windows path is not an escape
+			p.val = append(p.val, '\\')
+		}
+		p.val, p.state = append(p.val, c), cdQuoted
+	}
+}
+
+// checkKey fails p if parameter name met is not part of any known one, whole is true when name is over
+func (p *cdParser) checkKey(whole bool) {
+	for _, k := range cdKeys {
+		switch {
+		case p.cut && whole && strings.HasSuffix(k, string(p.key)),
+			p.cut && !whole && strings.Contains(k, string(p.key)),
+			!p.cut && whole && k == string(p.key),
+			!p.cut && !whole && strings.HasPrefix(k, string(p.key)):
+			return
+		}
+	}
+	p.state = cdFail
+}
+
+// keep saves parameter which value is over
+func (p *cdParser) keep() {
+	if p.cut {
+		p.cut = false
+		return
+	}
+	k, v := string(p.key), string(p.val)
+	if strings.HasSuffix(k, "*") {
+		var ok bool
+		if v, ok = decodeExtValue(v); !ok {
+			return
+		}
+	}
+	p.params[k] = v
+}
+
+// over returns true if line is whole when p stopped
+func (p *cdParser) over() bool {
+	if p.state == cdToken {
+		p.keep()
+		p.state = cdOWS
+	}
+	return p.state == cdOWS || p.state == cdParam
+}
+
+// param returns value of parameter k, extended value is preferred, RFC 6266 4.3
+func (p *cdParser) param(k string) (string, bool) {
+	if v, ok := p.params[k+"*"]; ok {
+		return v, true
+	}
+	v, ok := p.params[k]
+	return v, ok
+}
+
+// decodeExtValue decodes extended parameter value charset'language'pct-encoded, RFC 5987 3.2.
+// UTF-8 and ISO-8859-1 charsets are supported
+func decodeExtValue(v string) (string, bool) {
+	parts := strings.SplitN(v, "'", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+	s, err := url.PathUnescape(parts[2])
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parts[0]) {
+	case "utf-8", "us-ascii":
+		return s, utf8.ValidString(s)
+	case "iso-8859-1":
+		r := make([]rune, 0, len(s))
+		for i := 0; i < len(s); i++ {
+			r = append(r, rune(s[i]))
+		}
+		return string(r), true
+	}
+	return "", false
+}
+
+// ParseDisposition returns form name and file name of Content-Disposition header line l.
+// Names may be quoted strings, tokens or extended values, isFile is true if file name is set, ok is false if l is not whole line
+// Tested in dispositionOps_test.go
+func ParseDisposition(l []byte) (fo, fi string, isFile, ok bool) {
+	p := newCDParser(cdLiteral).parse(l)
+	if !p.over() {
+		return "", "", false, false
+	}
+	fo, _ = p.param("name")
+	fi, isFile = p.param("filename")
+
+	return fo, fi, isFile, true
+}
+
+// GetIndex returns index of part found in Content-Disposition header line of b, 0 if b has none.
+// Tested in dispositionOps_test.go
+func GetIndex(b []byte) int {
+	p := newCDParser(cdLiteral).parse(cdLineOf(b))
+	p.over()
+
+	i, _ := strconv.Atoi(p.params["index"])
+
+	return i
+}
+
+// hasIndex returns true if Content-Disposition header line found in header h has index parameter
+func hasIndex(h []byte) bool {
+	p := newCDParser(cdLiteral).parse(cdLineOf(h))
+	p.over()
+	_, ok := p.params["index"]
+
+	return ok
+}
+
+// dispositionNames returns form name and file name held by value v of Content-Disposition header field of any disposition type.
+// Value having parameters unknown to form part is parsed by mime package, ok is false if v is malformed
+func dispositionNames(v string) (fo, fi string, isFile, ok bool) {
+	typ, params, _ := strings.Cut(v, ";")
+	if len(strings.TrimSpace(typ)) == 0 {
+		return "", "", false, false
+	}
+	if p := newCDParser(cdParam).parse([]byte(params)); p.over() {
+		fo, _ = p.param("name")
+		fi, isFile = p.param("filename")
+
+		return fo, fi, isFile, true
+	}
+
+	_, m, err := mime.ParseMediaType(v)
+	if err != nil {
+		return "", "", false, false
+	}
+	fi, isFile = m["filename"]
+
+	return m["name"], fi, isFile, true
+}
+
+// dispositionLine returns Content-Disposition header line of form part having index i, index 0 is omitted
+func dispositionLine(fo, fi string, isFile bool, i int) string {
+	l := cdLine + "; name=\"" + cdQuoter.Replace(fo) + "\""
+	if isFile {
+		l += "; filename=\"" + cdQuoter.Replace(fi) + "\""
+	}
+	if i > 0 {
+		l += "; index=\"" + strconv.Itoa(i) + "\""
+	}
+	return l
+}
+
+// isCDSuffix returns true if b may be the end of Content-Disposition header line.
+// Every state line may be cut in is tried
+func isCDSuffix(b []byte) bool {
+	for i := 0; i < len(cdLine); i++ {
+		p := newCDParser(cdLiteral)
+		p.i = i
+		if p.parse(b).over() {
+			return true
+		}
+	}
+	for st := cdOWS; st < cdFail; st++ {
+		if newCDParser(st).parse(b).over() {
+			return true
+		}
+	}
+	return false
+}
+
+// isCDPrefix returns true if b may be the beginning of Content-Disposition header line
+func isCDPrefix(b []byte) bool {
+	return newCDParser(cdLiteral).parse(b).state != cdFail
+}
+
+// cdLineOf returns Content-Disposition header line found in header h
+func cdLineOf(h []byte) []byte {
+	i := bytes.Index(h, []byte(cdLine))
+	if i < 0 {
+		return nil
+	}
+	h = h[i:]
+	if j := bytes.Index(h, []byte(Sep)); j >= 0 {
+		h = h[:j]
+	}
+	return h
+}
+
+func isWS(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isCTL(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+// isToken returns true if c may be part of token, RFC 7230 3.2.6
+func isToken(c byte) bool {
+	return c > 0x20 && c < 0x7f && !strings.ContainsRune("()<>@,;:\\\"/[]?={}", rune(c))
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type dispositionOpsSuite struct {
+	suite.Suite
+}
+
+func TestDispositionOpsSuite(t *testing.T) {
+	suite.Run(t, new(dispositionOpsSuite))
+}
+
+func (s *dispositionOpsSuite) TestParseDisposition() {
+	tt := []struct {
+		name       string
+		l          string
+		wantFo     string
+		wantFi     string
+		wantIsFile bool
+		wantOk     bool
+	}{
+		{
+			name:   "text field",
+			l:      `Content-Disposition: form-data; name="alice"`,
+			wantFo: "alice",
+			wantOk: true,
+		},
+
+		{
+			name:       "file name having spaces, emoji and CJK",
+			l:          `Content-Disposition: form-data; name="alice"; filename="my 😀 文件.txt"`,
+			wantFo:     "alice",
+			wantFi:     "my 😀 文件.txt",
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name:       "escaped quote, backslash of path is kept",
+			l:          `Content-Disposition: form-data; name="a\"b"; filename="C:\dir\x.txt"`,
+			wantFo:     `a"b`,
+			wantFi:     `C:\dir\x.txt`,
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name:       "extended file name is preferred",
+			l:          `Content-Disposition: form-data; name="alice"; filename="file.txt"; filename*=UTF-8''%E6%96%87%E4%BB%B6.txt`,
+			wantFo:     "alice",
+			wantFi:     "文件.txt",
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name:       "ISO-8859-1 extended file name",
+			l:          `Content-Disposition: form-data; name=alice; FILENAME*=iso-8859-1'en'%A3%20rates.txt`,
+			wantFo:     "alice",
+			wantFi:     "£ rates.txt",
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name:       "extended file name of unknown charset is ignored",
+			l:          `Content-Disposition: form-data; name="alice"; filename="file.txt"; filename*=koi8-r''%C6`,
+			wantFo:     "alice",
+			wantFi:     "file.txt",
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name:       "empty file name",
+			l:          `Content-Disposition: form-data; name="alice"; filename=""`,
+			wantFo:     "alice",
+			wantIsFile: true,
+			wantOk:     true,
+		},
+
+		{
+			name: "unhappy cut line",
+			l:    `Content-Disposition: form-data; name="alice"; filename="my`,
+		},
+
+		{
+			name: "unhappy unknown parameter",
+			l:    `Content-Disposition: form-data; name="alice"; size=42`,
+		},
+
+		{
+			name: "unhappy line break in name",
+			l:    "Content-Disposition: form-data; name=\"ali\rce\"",
+		},
+
+		{
+			name: "unhappy other header",
+			l:    "Content-Type: text/plain",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			fo, fi, isFile, ok := ParseDisposition([]byte(v.l))
+			s.Equal(v.wantFo, fo)
+			s.Equal(v.wantFi, fi)
+			s.Equal(v.wantIsFile, isFile)
+			s.Equal(v.wantOk, ok)
+		})
+	}
+}
+
+func (s *dispositionOpsSuite) TestGetFoFi() {
+	tt := []struct {
+		name   string
+		b      string
+		wantFo string
+		wantFi string
+	}{
+		{
+			name:   "header of file",
+			b:      "\r\nContent-Disposition: form-data; name=\"alice\"; filename=\"мой файл.txt\"\r\nContent-Type: text/plain\r\n\r\n",
+			wantFo: "alice",
+			wantFi: "мой файл.txt",
+		},
+
+		{
+			name:   "extended file name",
+			b:      "Content-Disposition: form-data; name=\"alice\"; filename*=UTF-8''%F0%9F%98%80.txt\r\n",
+			wantFo: "alice",
+			wantFi: "😀.txt",
+		},
+
+		{
+			name:   "cut header",
+			b:      "Content-Disposition: form-data; name=\"alice\"; filename=\"my fi",
+			wantFo: "alice",
+		},
+
+		{
+			name: "no header",
+			b:    "azaza",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			fo, fi := GetFoFi([]byte(v.b))
+			s.Equal(v.wantFo, fo)
+			s.Equal(v.wantFi, fi)
+		})
+	}
+}
+
+func (s *dispositionOpsSuite) TestGetIndex() {
+	tt := []struct {
+		name string
+		b    string
+		want int
+	}{
+		{
+			name: "header having index",
+			b:    "\r\nContent-Disposition: form-data; name=\"alice\"; filename=\"a.csv\"; index=\"12\"\r\nContent-Type: text/csv\r\n\r\n",
+			want: 12,
+		},
+
+		{
+			name: "header having no index",
+			b:    "Content-Disposition: form-data; name=\"alice\"\r\n\r\n",
+		},
+
+		{
+			name: "cut index",
+			b:    "Content-Disposition: form-data; name=\"alice\"; index=\"1",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, GetIndex([]byte(v.b)))
+		})
+	}
+}
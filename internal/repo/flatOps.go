@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// MultipartMixed is media type of part holding several files of single form field, RFC 2388 4.6
+const MultipartMixed = "multipart/mixed"
+
+// NewFlatReader returns reader of multipart body framed by boundary bou having nested multipart/mixed parts flattened.
+// Every file of nested body becomes part of outer body named by form name of the field it belongs to, file name is preserved.
+// Nested bodies may be nested too. Headers of parts are put into parts, nil parts keeps none.
+// Content of part having base64 or quoted-printable Content-Transfer-Encoding is decoded if decode is true.
+// Tested in flatOps_test.go
+func NewFlatReader(body io.Reader, bou Boundary, parts *Parts, decode bool) io.Reader {
+	outer := GenBoundary(bou)
+
+	return &flatReader{r: body, outer: outer, parts: parts, decode: decode, m: len(Sep), skip: len(Sep)} // body is considered to be preceded by CRLF
+}
+
+// states of flatReader
+const (
+	flatPreamble = iota // before the first delimiter of outer body
+	flatHeader
+	flatContent
+	flatDiscard // in preamble or epilogue of nested body
+	flatEpilogue
+)
+
+// nested is multipart body nested in part of outer one
+type nested struct {
+	delim []byte // delimiter with CRLF in front
+	name  string // form name of field nested body belongs to
+}
+
+// flatReader converts multipart body having nested bodies into flat one having headers of parts normalized
+type flatReader struct {
+	r      io.Reader
+	in     []byte          // bytes read from r
+	out    bytes.Buffer    // flat bytes ready to be read
+	outer  []byte          // delimiter of outer body with CRLF in front
+	levels []nested        // nested bodies being read, the last is current one
+	parts  *Parts          // headers of parts met
+	decode bool            // content having Content-Transfer-Encoding is decoded
+	td     transferDecoder // decoder of current part content, nil if content is passed as is
+	dec    []byte          // bytes decoded
+	state  int
+	m      int    // delimiter bytes met
+	header []byte // header of current part with CRLF ending delimiter line in front
+	skip   int    // output bytes to be dropped
+	err    error
+}
+
+func (f *flatReader) Read(b []byte) (int, error) {
+	for f.out.Len() == 0 && f.err == nil {
+		if len(f.in) < len(b) {
+			f.in = make([]byte, len(b))
+		}
+		n, err := f.r.Read(f.in[:len(b)])
+		for i := 0; i < n && f.err == nil; i++ {
+			f.err = f.step(f.in[i])
+		}
+		if err != nil && f.err == nil {
+			f.flush()
+			f.err = err
+		}
+	}
+	n, _ := f.out.Read(b)
+	if f.out.Len() > 0 {
+		return n, nil
+	}
+	return n, f.err // error is returned along with the last bytes
+}
+
+// step moves f by byte c
+func (f *flatReader) step(c byte) error {
+	switch {
+	case f.state == flatHeader:
+		return f.headerByte(c)
+	case f.state == flatEpilogue:
+		f.write(c)
+		return nil
+	}
+
+	d := f.delim()
+	switch {
+	case c == d[f.m]:
+		if f.m++; f.m == len(d) { // next part or closing delimiter begins
+			f.end()
+			f.state, f.m, f.header = flatHeader, 0, f.header[:0]
+		}
+		return nil
+	case f.m > 0:
+		if f.state != flatDiscard {
+			f.content(d[:f.m]...)
+		}
+		if f.m = 0; c == d[0] {
+			f.m = 1
+			return nil
+		}
+	}
+	if f.state != flatDiscard {
+		f.content(c)
+	}
+	return nil
+}
+
+// headerByte adds c to header of current part, header is handled when it is over
+func (f *flatReader) headerByte(c byte) error {
+	f.header = append(f.header, c)
+
+	switch {
+	case bytes.Equal(f.header, []byte("--")): // closing delimiter
+		if len(f.levels) == 0 {
+			f.write(f.outer...)
+			f.write(f.header...)
+			f.state = flatEpilogue
+			return nil
+		}
+		f.levels, f.state = f.levels[:len(f.levels)-1], flatDiscard
+		return nil
+	case len(f.header) >= 2*len(Sep) && bytes.HasSuffix(f.header, []byte(Sep+Sep)):
+		return f.part()
+	case len(f.header) > MaxHeaderLimit*4:
+		if len(f.levels) > 0 {
+			return fmt.Errorf("in repo.flatReader %w: header of nested part exceeds %d bytes", ErrBodyMalformed, MaxHeaderLimit*4)
+		}
+		f.write(f.outer...)
+		f.write(f.header...) // malformed header is left to Slicer
+		f.state = flatContent
+	}
+	return nil
+}
+
+// part handles header of current part which is over.
+// Header of part is put into parts. Header Slicer does not understand is replaced by Content-Disposition line
+// followed by Content-Type line holding bare media type if part is file. Index client sent is never passed to Slicer.
+// Content-Transfer-Encoding of part which content is decoded is dropped
+func (f *flatReader) part() error {
+	lines := strings.Split(string(f.header[len(Sep):len(f.header)-len(Sep)]), Sep)
+	ph := NewPartHeader(lines)
+
+	fo, fi, isFile, ok := dispositionNames(ph.Fields["Content-Disposition"])
+	if len(f.levels) > 0 {
+		fo, ok = f.levels[len(f.levels)-1].name, true
+	}
+
+	if mt, params, err := mime.ParseMediaType(ph.Fields["Content-Type"]); err == nil && mt == MultipartMixed && len(params["boundary"]) > 0 {
+		f.levels = append(f.levels, nested{delim: []byte(Sep + "--" + params["boundary"]), name: fo})
+		f.state, f.m = flatDiscard, len(Sep) // nested body is considered to be preceded by CRLF
+		return nil
+	}
+
+	f.write(f.outer...)
+	f.state = flatContent
+	if !ok { // malformed header is left to Slicer
+		if hasIndex(f.header) {
+			return fmt.Errorf("in repo.flatReader %w: malformed header of part has index parameter", ErrBodyMalformed)
+		}
+		f.write(f.header...)
+		return nil
+	}
+
+	if td := newTransferDecoder(ph.Fields["Content-Transfer-Encoding"]); td != nil && f.decode {
+		f.td = td
+		delete(ph.Fields, "Content-Transfer-Encoding")
+	}
+
+	i := f.parts.add(fo, fi, ph)
+	if i == 0 && len(f.levels) == 0 && plain(lines, isFile) {
+		f.write(f.header...)
+		return nil
+	}
+
+	h := Sep + dispositionLine(fo, fi, isFile, i) + Sep
+	if isFile {
+		ct := OctetStream
+		if IsCTFull([]byte("Content-Type: " + ph.ContentType)) {
+			ct = ph.ContentType
+		}
+		h += "Content-Type: " + ct + Sep
+	}
+	f.write([]byte(h + Sep)...)
+
+	return nil
+}
+
+// plain returns true if Slicer understands header lines of part: Content-Disposition line having no index followed by Content-Type one if part is file
+func plain(lines []string, isFile bool) bool {
+	if _, _, _, ok := ParseDisposition([]byte(lines[0])); !ok || hasIndex([]byte(lines[0])) {
+		return false
+	}
+	if !isFile {
+		return len(lines) == 1
+	}
+	return len(lines) == 2 && IsCTFull([]byte(lines[1]))
+}
+
+// delim returns delimiter of body being read
+func (f *flatReader) delim() []byte {
+	if len(f.levels) == 0 {
+		return f.outer
+	}
+	return f.levels[len(f.levels)-1].delim
+}
+
+// flush writes bytes of outer body held by f when body is over
+func (f *flatReader) flush() {
+	if len(f.levels) > 0 {
+		return
+	}
+	switch f.state {
+	case flatHeader:
+		f.write(f.outer...)
+		f.write(f.header...)
+	case flatPreamble:
+		f.write(f.outer[:f.m]...)
+	case flatContent:
+		f.content(f.outer[:f.m]...)
+		f.end()
+	}
+	f.m = 0
+}
+
+// content puts bytes b of part content into output, decoding them if needed
+func (f *flatReader) content(b ...byte) {
+	if f.td == nil {
+		f.write(b...)
+		return
+	}
+	f.dec = f.dec[:0]
+	for _, c := range b {
+		f.dec = f.td.decode(f.dec, c)
+	}
+	f.write(f.dec...)
+}
+
+// end puts bytes held by decoder into output when part content is over
+func (f *flatReader) end() {
+	if f.td == nil {
+		return
+	}
+	f.write(f.td.end(f.dec[:0])...)
+	f.td = nil
+}
+
+// write puts bytes b into output, dropping bytes to be skipped
+func (f *flatReader) write(b ...byte) {
+	if f.skip > 0 {
+		n := Min(f.skip, len(b))
+		b, f.skip = b[n:], f.skip-n
+	}
+	f.out.Write(b)
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type flatOpsSuite struct {
+	suite.Suite
+}
+
+func TestFlatOpsSuite(t *testing.T) {
+	suite.Run(t, new(flatOpsSuite))
+}
+
+func (s *flatOpsSuite) TestNewFlatReader() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	field := func(name, value string) string {
+		return "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"\r\n\r\n" + value + "\r\n"
+	}
+	file := func(name, filename, value string) string {
+		return "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"; filename=\"" + filename + "\"\r\nContent-Type: text/plain\r\n\r\n" + value + "\r\n"
+	}
+	mixed := func(name, bou string) string {
+		return "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"\r\nContent-Type: multipart/mixed; boundary=" + bou + "\r\n\r\n"
+	}
+	inner := func(bou, filename, value string) string {
+		return "--" + bou + "\r\nContent-Disposition: file; filename=\"" + filename + "\"\r\nContent-Type: text/plain\r\n\r\n" + value + "\r\n"
+	}
+
+	tt := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "flat body is passed as is",
+			body: "preamble\r\n" + field("alice", "az\r\n--az--aza") + file("bob", "b.txt", "bzbzbz") + "--azaza--\r\nepilogue",
+			want: "preamble\r\n" + field("alice", "az\r\n--az--aza") + file("bob", "b.txt", "bzbzbz") + "--azaza--\r\nepilogue",
+		},
+
+		{
+			name: "nested files",
+			body: field("alice", "azaza") +
+				mixed("files", "bzbzbz") + inner("bzbzbz", "a.txt", "aaa") + inner("bzbzbz", "b.txt", "bbb\r\n--bzb") + "--bzbzbz--\r\n" +
+				field("cindy", "czczcz") + "--azaza--\r\n",
+			want: field("alice", "azaza") +
+				file("files", "a.txt", "aaa") + file("files", "b.txt", "bbb\r\n--bzb") +
+				field("cindy", "czczcz") + "--azaza--\r\n",
+		},
+
+		{
+			name: "preamble and epilogue of nested body are dropped",
+			body: mixed("files", "bzbzbz") + "preamble\r\n" + inner("bzbzbz", "a.txt", "aaa") + "--bzbzbz--\r\nepilogue\r\n" + "--azaza--\r\n",
+			want: file("files", "a.txt", "aaa") + "--azaza--\r\n",
+		},
+
+		{
+			name: "deeper nested files",
+			body: mixed("files", "bzbzbz") + inner("bzbzbz", "a.txt", "aaa") +
+				"--bzbzbz\r\nContent-Type: multipart/mixed; boundary=czczcz\r\n\r\n" + inner("czczcz", "c.txt", "ccc") + "--czczcz--\r\n" +
+				inner("bzbzbz", "b.txt", "bbb") + "--bzbzbz--\r\n" + "--azaza--\r\n",
+			want: file("files", "a.txt", "aaa") + file("files", "c.txt", "ccc") + file("files", "b.txt", "bbb") + "--azaza--\r\n",
+		},
+
+		{
+			name: "nested part without file name is text field",
+			body: mixed("files", "bzbzbz") + "--bzbzbz\r\n\r\naaa\r\n--bzbzbz--\r\n" + "--azaza--\r\n",
+			want: "--azaza\r\nContent-Disposition: form-data; name=\"files\"\r\n\r\naaa\r\n--azaza--\r\n",
+		},
+
+		{
+			name: "cut body is passed as is",
+			body: field("alice", "azaza") + "--aza",
+			want: field("alice", "azaza") + "--aza",
+		},
+
+		{
+			name: "cut header is passed as is",
+			body: field("alice", "azaza") + "--azaza\r\nContent-Disp",
+			want: field("alice", "azaza") + "--azaza\r\nContent-Disp",
+		},
+
+		{
+			name:    "unhappy malformed header having index",
+			body:    "--azaza\r\nContent-Disposition: form-data; name=\"bob\"; index=\"1\"\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\nbzbzbz\r\n--azaza--\r\n",
+			want:    "--azaza",
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy too long nested header",
+			body:    mixed("files", "bzbzbz") + "--bzbzbz\r\n" + strings.Repeat("a", MaxHeaderLimit*4),
+			wantErr: ErrBodyMalformed,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(NewFlatReader(iotest.OneByteReader(strings.NewReader(v.body)), bou, nil, true))
+			s.Equal(v.want, string(got))
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+
+			got, err = io.ReadAll(NewFlatReader(strings.NewReader(v.body), bou, nil, true))
+			s.NoError(err)
+			s.Equal(v.want, string(got))
+		})
+	}
+}
+
+func (s *flatOpsSuite) TestNewFlatReaderPassesErrors() {
+	_, err := io.ReadAll(NewFlatReader(iotest.TimeoutReader(strings.NewReader("--azaza\r\n")), Boundary{Prefix: []byte("--"), Root: []byte("azaza")}, nil, true))
+	s.ErrorIs(err, iotest.ErrTimeout)
+}
+
+func (s *flatOpsSuite) TestNewFlatReaderParts() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	part := func(header, value string) string {
+		return "--azaza\r\n" + header + "\r\n\r\n" + value + "\r\n"
+	}
+
+	tt := []struct {
+		name      string
+		body      string
+		want      string
+		wantParts map[FiFo]string // content type of part found by names and index
+	}{
+		{
+			name: "plain headers are passed as is",
+			body: part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"\r\nContent-Type: image/png", "bzbzbz") + "--azaza--\r\n",
+			want: part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"\r\nContent-Type: image/png", "bzbzbz") + "--azaza--\r\n",
+			wantParts: map[FiFo]string{NewFiFo("alice", ""): "", NewFiFo("bob", "b.png"): "image/png"},
+		},
+
+		{
+			name: "headers Slicer does not understand are replaced",
+			body: part("Content-Type: text/plain; charset=utf-8\r\ncontent-disposition: form-data; name=alice", "azaza") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.txt\"\r\nContent-Type: text/plain; charset=utf-8\r\nX-Origin: scanner", "bzbzbz") +
+				part("Content-Disposition: form-data; name=\"cindy\"; filename=\"c\\\"\\\\.bin\"; size=6", "czczcz") + "--azaza--\r\n",
+			want: part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.txt\"\r\nContent-Type: text/plain", "bzbzbz") +
+				part("Content-Disposition: form-data; name=\"cindy\"; filename=\"c\\\"\\\\.bin\"\r\nContent-Type: application/octet-stream", "czczcz") + "--azaza--\r\n",
+			wantParts: map[FiFo]string{NewFiFo("alice", ""): "text/plain", NewFiFo("bob", "b.txt"): "text/plain", NewFiFo("cindy", "c\"\\.bin"): ""},
+		},
+
+		{
+			name: "part sharing names with part having different header gets index",
+			body: part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"alice\"\r\nContent-Type: text/csv", "a,z") + "--azaza--\r\n",
+			want: part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"alice\"", "azaza") +
+				part("Content-Disposition: form-data; name=\"alice\"; index=\"2\"", "a,z") + "--azaza--\r\n",
+			wantParts: map[FiFo]string{NewFiFo("alice", ""): "", {FormName: "alice", Index: 2}: "text/csv"},
+		},
+
+		{
+			name: "index client sent is dropped",
+			body: part("Content-Disposition: form-data; name=\"alice\"\r\nContent-Type: text/csv", "a,z") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"; index=\"1\"\r\nContent-Type: image/png", "bzbzbz") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"; index=\"1\"\r\nContent-Type: image/gif", "bzbzbz") + "--azaza--\r\n",
+			want: part("Content-Disposition: form-data; name=\"alice\"", "a,z") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"\r\nContent-Type: image/png", "bzbzbz") +
+				part("Content-Disposition: form-data; name=\"bob\"; filename=\"b.png\"; index=\"3\"\r\nContent-Type: image/gif", "bzbzbz") + "--azaza--\r\n",
+			wantParts: map[FiFo]string{NewFiFo("alice", ""): "text/csv", NewFiFo("bob", "b.png"): "image/png", {FormName: "bob", FileName: "b.png", Index: 3}: "image/gif"},
+		},
+
+		{
+			name: "header of nested part is kept",
+			body: "--azaza\r\nContent-Disposition: form-data; name=\"files\"\r\nContent-Type: multipart/mixed; boundary=bzbzbz\r\n\r\n" +
+				"--bzbzbz\r\nContent-Disposition: file; filename=\"a.png\"\r\nContent-Type: image/png\r\n\r\naaa\r\n--bzbzbz--\r\n" + "--azaza--\r\n",
+			want:      part("Content-Disposition: form-data; name=\"files\"; filename=\"a.png\"\r\nContent-Type: image/png", "aaa") + "--azaza--\r\n",
+			wantParts: map[FiFo]string{NewFiFo("files", "a.png"): "image/png"},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			p := &Parts{}
+			got, err := io.ReadAll(NewFlatReader(iotest.OneByteReader(strings.NewReader(v.body)), bou, p, true))
+			s.NoError(err)
+			s.Equal(v.want, string(got))
+			for f, ct := range v.wantParts {
+				h := p.Get(f)
+				s.NotEmpty(h.Fields, f)
+				s.Equal(ct, h.ContentType, f)
+			}
+		})
+	}
+}
+
+func (s *flatOpsSuite) TestNewFlatReaderTransferEncoding() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	part := func(header, value string) string {
+		return "--azaza\r\n" + header + "\r\n\r\n" + value + "\r\n"
+	}
+	body := part("Content-Disposition: form-data; name=\"alice\"; filename=\"a.txt\"\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64", "YXph\r\nemE=") +
+		part("Content-Disposition: form-data; name=\"bob\"\r\nContent-Transfer-Encoding: quoted-printable", "b=3Dz\r\n--az=\r\nbz") +
+		part("Content-Disposition: form-data; name=\"cindy\"\r\nContent-Transfer-Encoding: 7bit", "czczcz") + "--azaza--\r\n"
+
+	tt := []struct {
+		name    string
+		decode  bool
+		want    string
+		wantCTE map[FiFo]string // Content-Transfer-Encoding passed to saver
+	}{
+		{
+			name:   "content is decoded",
+			decode: true,
+			want: part("Content-Disposition: form-data; name=\"alice\"; filename=\"a.txt\"\r\nContent-Type: text/plain", "azaza") +
+				part("Content-Disposition: form-data; name=\"bob\"", "b=z\r\n--azbz") +
+				part("Content-Disposition: form-data; name=\"cindy\"", "czczcz") + "--azaza--\r\n",
+			wantCTE: map[FiFo]string{NewFiFo("alice", "a.txt"): "", NewFiFo("bob", ""): "", NewFiFo("cindy", ""): "7bit"},
+		},
+
+		{
+			name: "content is kept",
+			want: part("Content-Disposition: form-data; name=\"alice\"; filename=\"a.txt\"\r\nContent-Type: text/plain", "YXph\r\nemE=") +
+				part("Content-Disposition: form-data; name=\"bob\"", "b=3Dz\r\n--az=\r\nbz") +
+				part("Content-Disposition: form-data; name=\"cindy\"", "czczcz") + "--azaza--\r\n",
+			wantCTE: map[FiFo]string{NewFiFo("alice", "a.txt"): "base64", NewFiFo("bob", ""): "quoted-printable", NewFiFo("cindy", ""): "7bit"},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			p := &Parts{}
+			got, err := io.ReadAll(NewFlatReader(iotest.OneByteReader(strings.NewReader(body)), bou, p, v.decode))
+			s.NoError(err)
+			s.Equal(v.want, string(got))
+			for f, cte := range v.wantCTE {
+				s.Equal(cte, p.Get(f).Fields["Content-Transfer-Encoding"], f)
+			}
+
+			got, err = io.ReadAll(NewFlatReader(strings.NewReader(body), bou, nil, v.decode))
+			s.NoError(err)
+			s.Equal(v.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// NewFormReader returns reader of multipart body framed by boundary bou made of urlencoded body.
+// Every name=value pair becomes text field part, value is decoded on the fly.
+// Tested in formOps_test.go
+func NewFormReader(body io.Reader, bou Boundary) io.Reader {
+	return &formReader{r: bufio.NewReader(body), partWriter: partWriter{bou: bou}}
+}
+
+// states of formReader
+const (
+	inPair = iota // between pairs
+	inName
+	inValue
+	inDone
+)
+
+// formReader converts urlencoded body into multipart one
+type formReader struct {
+	partWriter
+	r     *bufio.Reader
+	state int
+	name  []byte
+	size  int // size of escaped name
+	err   error
+}
+
+func (f *formReader) Read(b []byte) (int, error) {
+	for f.out.Len() < len(b) && f.state != inDone && f.err == nil {
+		f.err = f.step()
+	}
+	if f.out.Len() > 0 {
+		return f.out.Read(b)
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	return 0, io.EOF
+}
+
+// step decodes next byte of urlencoded body and moves f by it
+func (f *formReader) step() error {
+	c, err := f.r.ReadByte()
+	if err == io.EOF {
+		if f.state == inName {
+			if err = f.open(string(f.name), "", ""); err != nil {
+				return err
+			}
+		}
+		f.close()
+		f.state = inDone
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if f.state == inPair && c != '&' {
+		f.state, f.name, f.size = inName, f.name[:0], 0
+	}
+	switch {
+	case c == '&':
+		if f.state == inName {
+			err = f.open(string(f.name), "", "")
+		}
+		f.state = inPair
+		return err
+	case c == '=' && f.state == inName:
+		f.state, err = inValue, f.open(string(f.name), "", "")
+		return err
+	}
+
+	if c, err = f.decode(c); err != nil {
+		return err
+	}
+	if f.state == inValue {
+		f.out.WriteByte(c)
+		return nil
+	}
+	if f.size += len(formNameEscaper.Replace(string(c))); f.size > maxFormName {
+		return fmt.Errorf("in repo.formReader %w: form name exceeds %d bytes", ErrTooLarge, maxFormName)
+	}
+	f.name = append(f.name, c)
+	return nil
+}
+
+// decode returns byte encoded by c and bytes following it
+func (f *formReader) decode(c byte) (byte, error) {
+	switch c {
+	case '+':
+		return ' ', nil
+	case '%':
+		h, err := f.r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("in repo.formReader %w: incomplete escape", ErrBodyMalformed)
+		}
+		l, err := f.r.ReadByte()
+		if err != nil || !isHex(h) || !isHex(l) {
+			return 0, fmt.Errorf("in repo.formReader %w: invalid escape %q", ErrBodyMalformed, []byte{'%', h, l})
+		}
+		return unhex(h)<<4 | unhex(l), nil
+	}
+	return c, nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - 'a' + 10
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type formOpsSuite struct {
+	suite.Suite
+}
+
+func TestFormOpsSuite(t *testing.T) {
+	suite.Run(t, new(formOpsSuite))
+}
+
+func (s *formOpsSuite) TestNewFormReader() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	part := func(name, value string) string {
+		return "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"\r\n\r\n" + value + "\r\n"
+	}
+
+	tt := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "fields",
+			body: "alice=azaza&bob=bzbzbz",
+			want: part("alice", "azaza") + part("bob", "bzbzbz") + "--azaza--\r\n",
+		},
+
+		{
+			name: "escapes are decoded",
+			body: "full+name=Alice+Liddell&note=a%26b%3Dc%0D%0A%d1%84",
+			want: part("full name", "Alice Liddell") + part("note", "a&b=c\r\nф") + "--azaza--\r\n",
+		},
+
+		{
+			name: "empty values and pairs",
+			body: "alice=&&bob&=azaza&",
+			want: part("alice", "") + part("bob", "") + part("", "azaza") + "--azaza--\r\n",
+		},
+
+		{
+			name: "value having equals sign",
+			body: "alice=a=b",
+			want: part("alice", "a=b") + "--azaza--\r\n",
+		},
+
+		{
+			name: "quotes and line breaks in name are escaped",
+			body: "%22alice%22%0D%0A=azaza",
+			want: part("%22alice%22%0D%0A", "azaza") + "--azaza--\r\n",
+		},
+
+		{
+			name: "empty body",
+			want: "--azaza--\r\n",
+		},
+
+		{
+			name:    "unhappy invalid escape",
+			body:    "alice=az%zz",
+			want:    strings.TrimSuffix(part("alice", "az"), "\r\n"),
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy incomplete escape",
+			body:    "alice=az%4",
+			want:    strings.TrimSuffix(part("alice", "az"), "\r\n"),
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy too long name",
+			body:    strings.Repeat("a", maxFormName+1) + "=azaza",
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(NewFormReader(iotest.OneByteReader(strings.NewReader(v.body)), bou))
+			s.Equal(v.want, string(got))
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
+
+func (s *formOpsSuite) TestNewFormReaderPassesErrors() {
+	got, err := io.ReadAll(NewFormReader(iotest.TimeoutReader(strings.NewReader("alice=azaza")), Boundary{Prefix: []byte("--"), Root: []byte("azaza")}))
+	s.ErrorIs(err, iotest.ErrTimeout)
+	s.NotContains(string(got), "--azaza--")
+}
@@ -0,0 +1,458 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxJSONLiteral is the longest number literal taken as text field
+const maxJSONLiteral = 256
+
+// maxQuad is how many base64 bytes are decoded at once
+const maxQuad = 1024
+
+// NewJSONReader returns reader of multipart body framed by boundary bou made of JSON object body.
+// Top-level string, number and boolean properties become text fields, null and nested values are skipped.
+// Properties named in files become files, their values should be objects having "filename" and base64 "data",
+// optional "contentType" and filename should precede data. Strings and base64 data are decoded on the fly.
+// Tested in jsonOps_test.go
+func NewJSONReader(body io.Reader, bou Boundary, files []string) io.Reader {
+	j := &jsonReader{r: bufio.NewReader(body), partWriter: partWriter{bou: bou}, files: make(map[string]bool)}
+	for _, f := range files {
+		j.files[f] = true
+	}
+	return j
+}
+
+// states of jsonReader
+const (
+	jBegin = iota // before top-level object
+	jProp         // before top-level property or end of object
+	jText         // in string value of text field
+	jFile         // in file object, before its property or end
+	jData         // in base64 data of file
+	jEnd          // after top-level object
+	jDone
+)
+
+// jsonReader converts JSON object into multipart body
+type jsonReader struct {
+	partWriter
+	r     *bufio.Reader
+	files map[string]bool
+	state int
+	first bool // no property of current object is met yet
+	err   error
+
+	file     string // property holding current file
+	filename string
+	ctype    string
+	data     bool    // data of current file is met
+	quad     []byte  // base64 bytes waiting for decoding
+	rb       [4]byte // decoded string char
+}
+
+func (j *jsonReader) Read(b []byte) (int, error) {
+	for j.out.Len() < len(b) && j.state != jDone && j.err == nil {
+		j.err = j.step()
+		if j.err == io.EOF {
+			j.err = fmt.Errorf("in repo.jsonReader %w: JSON body is cut", ErrBodyMalformed)
+		}
+	}
+	if j.out.Len() > 0 {
+		return j.out.Read(b)
+	}
+	if j.err != nil {
+		return 0, j.err
+	}
+	return 0, io.EOF
+}
+
+// step moves j by next token of JSON body, or by next char of string being streamed
+func (j *jsonReader) step() error {
+	switch j.state {
+	case jBegin:
+		c, err := j.next()
+		if err != nil {
+			return err
+		}
+		if c != '{' {
+			return j.malformed("body is not object")
+		}
+		j.state, j.first = jProp, true
+
+	case jProp:
+		key, end, err := j.key()
+		if err != nil || end {
+			j.state = jEnd
+			return err
+		}
+		return j.value(key)
+
+	case jText:
+		c, end, err := j.char()
+		if err != nil {
+			return err
+		}
+		if end {
+			j.state = jProp
+			return nil
+		}
+		j.out.Write(c)
+
+	case jFile:
+		key, end, err := j.key()
+		if err != nil {
+			return err
+		}
+		if end {
+			if !j.data {
+				return j.malformed("file %q has no data", j.file)
+			}
+			j.state, j.first = jProp, false
+			return nil
+		}
+		return j.fileValue(key)
+
+	case jData:
+		c, end, err := j.char()
+		if err != nil {
+			return err
+		}
+		if end {
+			j.state = jFile
+			return j.decode(true)
+		}
+		return j.base64(c)
+
+	case jEnd:
+		if _, err := j.next(); err != io.EOF {
+			if err != nil {
+				return err
+			}
+			return j.malformed("data after object")
+		}
+		j.close()
+		j.state = jDone
+	}
+	return nil
+}
+
+// key returns name of next property of current object, end is true if object is over
+func (j *jsonReader) key() (string, bool, error) {
+	c, err := j.next()
+	if err != nil {
+		return "", false, err
+	}
+	if c == '}' {
+		return "", true, nil
+	}
+	if !j.first {
+		if c != ',' {
+			return "", false, j.malformed("%q instead of comma", c)
+		}
+		if c, err = j.next(); err != nil {
+			return "", false, err
+		}
+	}
+	if c != '"' {
+		return "", false, j.malformed("%q instead of property name", c)
+	}
+	key, err := j.str(maxFormName)
+	if err != nil {
+		return "", false, err
+	}
+	if c, err = j.next(); err != nil {
+		return "", false, err
+	}
+	if c != ':' {
+		return "", false, j.malformed("%q instead of colon", c)
+	}
+	j.first = false
+
+	return key, false, nil
+}
+
+// value handles value of top-level property key
+func (j *jsonReader) value(key string) error {
+	c, err := j.next()
+	if err != nil {
+		return err
+	}
+	switch {
+	case j.files[key] && c == '{':
+		j.state, j.first = jFile, true
+		j.file, j.filename, j.ctype, j.data = key, "", "", false
+		return nil
+	case j.files[key]:
+		return j.malformed("file %q is not object", key)
+	case c == '"':
+		j.state = jText
+		return j.open(key, "", "")
+	case c == '{' || c == '[':
+		return j.skip(c)
+	}
+
+	lit, err := j.literal(c)
+	if err != nil || lit == "null" {
+		return err
+	}
+	if err = j.open(key, "", ""); err != nil {
+		return err
+	}
+	j.out.WriteString(lit)
+
+	return nil
+}
+
+// fileValue handles value of property key of file object
+func (j *jsonReader) fileValue(key string) error {
+	c, err := j.next()
+	if err != nil {
+		return err
+	}
+	if key != "filename" && key != "contentType" && key != "data" {
+		return j.skip(c)
+	}
+	if j.data {
+		return j.malformed("%q follows data of file %q", key, j.file)
+	}
+	if c != '"' {
+		return j.malformed("%q of file %q is not string", key, j.file)
+	}
+
+	switch key {
+	case "filename":
+		j.filename, err = j.str(maxFormName)
+	case "contentType":
+		j.ctype, err = j.str(maxFormName)
+	case "data":
+		if len(j.filename) == 0 {
+			return j.malformed("filename of file %q should precede data", j.file)
+		}
+		j.state, j.data, j.quad = jData, true, j.quad[:0]
+		err = j.open(j.file, j.filename, j.ctype)
+	}
+	return err
+}
+
+// base64 takes chars c of file data and writes bytes decoded from every whole quantum
+func (j *jsonReader) base64(c []byte) error {
+	for _, b := range c {
+		switch b {
+		case '\r', '\n', ' ', '\t': // line breaks of MIME-style encoders
+			continue
+		case '-': // URL-safe alphabet
+			b = '+'
+		case '_':
+			b = '/'
+		}
+		j.quad = append(j.quad, b)
+	}
+	if len(j.quad) < maxQuad {
+		return nil
+	}
+	return j.decode(false)
+}
+
+// decode writes bytes decoded from whole quanta of j.quad, the rest is decoded too if last is true
+func (j *jsonReader) decode(last bool) error {
+	n := len(j.quad) / 4 * 4
+	if last {
+		n = len(j.quad)
+	}
+	if n == 0 {
+		return nil
+	}
+	enc := base64.StdEncoding
+	if n%4 != 0 {
+		enc = base64.RawStdEncoding
+	}
+	dst := make([]byte, enc.DecodedLen(n))
+	m, err := enc.Decode(dst, j.quad[:n])
+	if err != nil {
+		return j.malformed("data of file %q is not base64", j.file)
+	}
+	j.out.Write(dst[:m])
+	j.quad = append(j.quad[:0], j.quad[n:]...)
+
+	return nil
+}
+
+// next returns next byte which is not whitespace
+func (j *jsonReader) next() (byte, error) {
+	for {
+		c, err := j.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c, nil
+		}
+	}
+}
+
+// char returns bytes of next char of string whose opening quote is read, end is true at closing quote
+func (j *jsonReader) char() ([]byte, bool, error) {
+	c, err := j.r.ReadByte()
+	switch {
+	case err != nil:
+		return nil, false, err
+	case c == '"':
+		return nil, true, nil
+	case c < 0x20:
+		return nil, false, j.malformed("control character in string")
+	case c != '\\':
+		j.rb[0] = c
+		return j.rb[:1], false, nil
+	}
+
+	if c, err = j.r.ReadByte(); err != nil {
+		return nil, false, err
+	}
+	switch c {
+	case '"', '\\', '/':
+	case 'b':
+		c = '\b'
+	case 'f':
+		c = '\f'
+	case 'n':
+		c = '\n'
+	case 'r':
+		c = '\r'
+	case 't':
+		c = '\t'
+	case 'u':
+		r, err := j.rune()
+		if err != nil {
+			return nil, false, err
+		}
+		return j.rb[:utf8.EncodeRune(j.rb[:], r)], false, nil
+	default:
+		return nil, false, j.malformed("invalid escape %q", []byte{'\\', c})
+	}
+	j.rb[0] = c
+
+	return j.rb[:1], false, nil
+}
+
+// rune returns rune of \u escape whose prefix is read, surrogate pair is joined
+func (j *jsonReader) rune() (rune, error) {
+	r, err := j.hex4()
+	if err != nil || !utf16.IsSurrogate(r) {
+		return r, err
+	}
+	if b, err := j.r.Peek(2); err != nil || string(b) != "\\u" {
+		return utf8.RuneError, nil
+	}
+	j.r.Discard(2)
+	r2, err := j.hex4()
+	if err != nil {
+		return 0, err
+	}
+	return utf16.DecodeRune(r, r2), nil
+}
+
+// hex4 returns value of 4 hex digits
+func (j *jsonReader) hex4() (rune, error) {
+	var r rune
+	for i := 0; i < 4; i++ {
+		c, err := j.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isHex(c) {
+			return 0, j.malformed("invalid \\u escape")
+		}
+		r = r<<4 | rune(unhex(c))
+	}
+	return r, nil
+}
+
+// str returns string whose opening quote is read, string longer than max bytes is error
+func (j *jsonReader) str(max int) (string, error) {
+	s := make([]byte, 0, 32)
+	for {
+		c, end, err := j.char()
+		if err != nil {
+			return "", err
+		}
+		if end {
+			return string(s), nil
+		}
+		if len(s)+len(c) > max {
+			return "", fmt.Errorf("in repo.jsonReader %w: string exceeds %d bytes", ErrTooLarge, max)
+		}
+		s = append(s, c...)
+	}
+}
+
+// literal returns number, boolean or null beginning with c
+func (j *jsonReader) literal(c byte) (string, error) {
+	lit := []byte{c}
+	for {
+		b, err := j.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if b == ',' || b == '}' || b == ']' || b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			j.r.UnreadByte()
+			break
+		}
+		if len(lit) >= maxJSONLiteral {
+			return "", fmt.Errorf("in repo.jsonReader %w: literal exceeds %d bytes", ErrTooLarge, maxJSONLiteral)
+		}
+		lit = append(lit, b)
+	}
+	if !json.Valid(lit) {
+		return "", j.malformed("invalid literal %q", lit)
+	}
+	return string(lit), nil
+}
+
+// skip reads value beginning with c, nested values are skipped as a whole
+func (j *jsonReader) skip(c byte) error {
+	if c != '{' && c != '[' && c != '"' {
+		_, err := j.literal(c)
+		return err
+	}
+	depth := 0
+	for {
+		switch c {
+		case '"':
+			for {
+				_, end, err := j.char()
+				if err != nil {
+					return err
+				}
+				if end {
+					break
+				}
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if c, err = j.r.ReadByte(); err != nil {
+			return err
+		}
+	}
+}
+
+// malformed returns error of malformed JSON body
+func (j *jsonReader) malformed(format string, args ...interface{}) error {
+	return fmt.Errorf("in repo.jsonReader %w: "+format, append([]interface{}{ErrBodyMalformed}, args...)...)
+}
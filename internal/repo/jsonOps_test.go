@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type jsonOpsSuite struct {
+	suite.Suite
+}
+
+func TestJSONOpsSuite(t *testing.T) {
+	suite.Run(t, new(jsonOpsSuite))
+}
+
+func (s *jsonOpsSuite) TestNewJSONReader() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	field := func(name, value string) string {
+		return "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"\r\n\r\n" + value + "\r\n"
+	}
+	file := func(name, filename, ct, data string) string {
+		h := "--azaza\r\nContent-Disposition: form-data; name=\"" + name + "\"; filename=\"" + filename + "\"\r\n"
+		if len(ct) > 0 {
+			h += "Content-Type: " + ct + "\r\n"
+		}
+		return h + "\r\n" + data + "\r\n"
+	}
+	long := strings.Repeat("0123456789abcdef", 200)
+
+	tt := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "scalars",
+			body: `{"alice": "azaza", "bob": 42, "claire": true, "dan": null, "eve": -1.5e3}`,
+			want: field("alice", "azaza") + field("bob", "42") + field("claire", "true") + field("eve", "-1.5e3") + "--azaza--\r\n",
+		},
+
+		{
+			name: "escapes are decoded",
+			body: `{"full name": "Alice \"Liddell\"\nф😀\/"}`,
+			want: field("full name", "Alice \"Liddell\"\nф😀/") + "--azaza--\r\n",
+		},
+
+		{
+			name: "nested values are skipped",
+			body: `{"tags": ["a", {"b": "}]"}], "meta": {"c": [1, 2]}, "alice": "azaza"}`,
+			want: field("alice", "azaza") + "--azaza--\r\n",
+		},
+
+		{
+			name: "files",
+			body: `{"avatar": {"filename": "a.png", "contentType": "image/png", "size": 5, "data": "` + base64.StdEncoding.EncodeToString([]byte("12345")) + `"},` +
+				` "alice": "azaza",` +
+				` "cover": {"filename": "c.txt", "data": "` + base64.URLEncoding.EncodeToString([]byte(long+"\xff\xfe")) + `"}}`,
+			want: file("avatar", "a.png", "image/png", "12345") + field("alice", "azaza") + file("cover", "c.txt", "", long+"\xff\xfe") + "--azaza--\r\n",
+		},
+
+		{
+			name: "file data without padding and with line breaks",
+			body: `{"avatar": {"filename": "a.txt", "data": "MTIz\r\nNDU"}}`,
+			want: file("avatar", "a.txt", "", "12345") + "--azaza--\r\n",
+		},
+
+		{
+			name: "empty object",
+			body: " {} \n",
+			want: "--azaza--\r\n",
+		},
+
+		{
+			name:    "unhappy not object",
+			body:    `["alice"]`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy body is cut",
+			body:    `{"alice": "aza`,
+			want:    "--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\naza",
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy missing comma",
+			body:    `{"alice": 1 "bob": 2}`,
+			want:    field("alice", "1")[:len(field("alice", "1"))-2],
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy data after object",
+			body:    `{} {}`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy invalid literal",
+			body:    `{"alice": tru}`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy file is not object",
+			body:    `{"avatar": "azaza"}`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy data precedes filename",
+			body:    `{"avatar": {"data": "MTIz", "filename": "a.txt"}}`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy file without data",
+			body:    `{"avatar": {"filename": "a.txt"}}`,
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy data is not base64",
+			body:    `{"avatar": {"filename": "a.txt", "data": "MT*z"}}`,
+			want:    "--azaza\r\nContent-Disposition: form-data; name=\"avatar\"; filename=\"a.txt\"\r\n\r\n",
+			wantErr: ErrBodyMalformed,
+		},
+
+		{
+			name:    "unhappy too long property name",
+			body:    `{"` + strings.Repeat("a", maxFormName+1) + `": 1}`,
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(NewJSONReader(iotest.HalfReader(strings.NewReader(v.body)), bou, []string{"avatar", "cover"}))
+			s.Equal(v.want, string(got))
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Vocabulaty struct {
@@ -31,6 +34,17 @@ type ReceiverHeader struct {
 	TS      string
 	Bou     Boundary
 	Unblock bool
+	Client  Client
+	Route   string // name of route request is passed to
+	Parts   *Parts // headers of parts of request body
+}
+
+// Client describes who sent request and how it is addressed
+type Client struct {
+	Addr       string     // client address, real one if connection came through proxy
+	Cert       ClientCert // verified client certificate, zero if there is none
+	ServerName string     // name of server certificate picked by SNI, the first name of default certificate if none matched
+	Principal  string     // authenticated user, token owner or signing key id, empty if authentication is off
 }
 
 func NewReceiverHeader(ts string, p int, bou Boundary) ReceiverHeader {
@@ -107,6 +121,7 @@ type AppUnit interface {
 
 type AppFeederUnit struct {
 	R ReceiverUnit
+	W *sync.WaitGroup // units of request being sliced, set when unit is fed to application
 }
 
 func NewAppFeederUnit(r ReceiverUnit) AppFeederUnit {
@@ -186,6 +201,7 @@ func (sk StreamKey) DecPart() StreamKey {
 type FiFo struct {
 	FormName string
 	FileName string
+	Index    int // index of part header in Parts, 0 if names are enough to find it
 }
 
 func NewFiFo(fo, fi string) FiFo {
@@ -275,6 +291,15 @@ type AppDistributorHeader struct {
 
 	// Close info
 	C CloseData
+
+	// Client sent request
+	Client Client
+
+	// Route request is passed to
+	Route string
+
+	// Headers of parts of request body
+	Parts *Parts
 }
 
 func NewCurrentPieceHeader(ts string, p int) CurrentPieceHeader {
@@ -351,13 +376,21 @@ func NewAppDistributorUnit(h AppDistributorHeader, b AppDistributorBody) AppDist
 func (adu AppDistributorUnit) GetHeader() string {
 	return fmt.Sprint(adu.H)
 }
+
+// TS returns timestamp of request adu belongs to
+func (adu AppDistributorUnit) TS() string {
+	if adu.H.T == Unary {
+		return adu.H.U.UK.TS
+	}
+	return adu.H.S.SK.TS
+}
 func (adu AppDistributorUnit) GetBody() []byte {
 	return adu.B.B
 }
 
 func NewDistributorUnitStream(ask AppStoreValue, d DataPiece, m Message) AppDistributorUnit {
 	sk := NewStreamKey(d.TS(), d.Part(), false)
-	fifo := NewFiFo(ask.D.FormName, ask.D.FileName)
+	fifo := ask.D.FiFo()
 	b := ask.B
 	sm := m
 
@@ -394,7 +427,7 @@ func NewAppDistributorUnitUnary(d DataPiece, bou Boundary, m Message) AppDistrib
 	}
 
 	fo, fi := GetFoFi(h)
-	fifo := NewFiFo(fo, fi)
+	fifo := Disposition{FormName: fo, FileName: fi, H: h}.FiFo()
 
 	uk := NewUnaryKey(d.TS(), d.Part())
 
@@ -406,7 +439,7 @@ func NewAppDistributorUnitUnary(d DataPiece, bou Boundary, m Message) AppDistrib
 
 func NewAppDistributorUnitUnaryComposed(ask AppStoreValue, d DataPiece, m Message) AppDistributorUnit {
 	uk := NewUnaryKey(d.TS(), d.Part())
-	fifo := NewFiFo(ask.D.FormName, ask.D.FileName)
+	fifo := ask.D.FiFo()
 	sm := m
 
 	ud := NewUnaryData(uk, fifo, sm)
@@ -418,6 +451,131 @@ func NewAppDistributorUnitUnaryComposed(ask AppStoreValue, d DataPiece, m Messag
 	return adu
 }
 
+// Result is outcome of request handling, it is sent to client as JSON
+type Result struct {
+	TS     string        `json:"ts"`
+	Fields []ResultField `json:"fields"`
+	Files  []ResultFile  `json:"files"`
+	Error  string        `json:"error,omitempty"`
+	Status int           `json:"-"`
+
+	RetryAfter time.Duration `json:"-"` // sent as Retry-After header if positive
+	Challenges []string      `json:"-"` // sent as WWW-Authenticate headers
+}
+
+func NewResult(ts string) Result {
+	return Result{
+		TS:     ts,
+		Fields: make([]ResultField, 0),
+		Files:  make([]ResultFile, 0),
+		Status: http.StatusOK,
+	}
+}
+
+// NewResultErr returns result of request which failed with err
+func NewResultErr(ts string, err error) Result {
+	r := NewResult(ts)
+	r.Status, r.Error = StatusOf(err), err.Error()
+
+	var re *RetryError
+	if errors.As(err, &re) {
+		r.RetryAfter = re.After
+	}
+	var ce *ChallengeError
+	if errors.As(err, &ce) {
+		r.Challenges = ce.Challenges
+	}
+	return r
+}
+
+// retryAfter returns r.RetryAfter as whole seconds rounded up
+func (r Result) retryAfter() string {
+	return strconv.FormatInt(int64((r.RetryAfter+time.Second-1)/time.Second), 10)
+}
+
+// RetryError is error of request which may be repeated after After
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string { return e.Err.Error() }
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// ChallengeError is error of request failing authentication, Challenges tell client how to authenticate
+type ChallengeError struct {
+	Err        error
+	Challenges []string
+}
+
+func (e *ChallengeError) Error() string { return e.Err.Error() }
+
+func (e *ChallengeError) Unwrap() error { return e.Err }
+
+type ResultField struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+type ResultFile struct {
+	Field string `json:"field"`
+	Name  string `json:"name"`
+	Size  uint32 `json:"size"`
+}
+
+// TransmitResult is outcome of single ADU transmission
+type TransmitResult struct {
+	TS     string
+	Fields []ResultField
+	Files  []ResultFile
+	Errs   []error
+}
+
+// NewTransmitResult returns TransmitResult of adu. Unary ADU is counted as field or as file
+// Tested in models_test.go
+func NewTransmitResult(adu AppDistributorUnit) TransmitResult {
+	tr := TransmitResult{}
+
+	switch adu.H.T {
+	case Unary:
+		tr.TS = adu.H.U.UK.TS
+		if len(adu.H.U.F.FileName) > 0 {
+			tr.Files = append(tr.Files, ResultFile{Field: adu.H.U.F.FormName, Name: adu.H.U.F.FileName, Size: uint32(len(adu.B.B))})
+			break
+		}
+		tr.Fields = append(tr.Fields, ResultField{Name: adu.H.U.F.FormName, Size: len(adu.B.B)})
+	case ClientStream:
+		tr.TS = adu.H.S.SK.TS
+	}
+	return tr
+}
+
+// Add adds tr to r. File transmitted through several streams is counted once with the biggest size reported
+// Tested in models_test.go
+func (r *Result) Add(tr TransmitResult) {
+	r.Fields = append(r.Fields, tr.Fields...)
+
+	for _, f := range tr.Files {
+		found := false
+		for i := range r.Files {
+			if r.Files[i].Field == f.Field && r.Files[i].Name == f.Name {
+				found = true
+				if f.Size > r.Files[i].Size {
+					r.Files[i].Size = f.Size
+				}
+			}
+		}
+		if !found {
+			r.Files = append(r.Files, f)
+		}
+	}
+	if len(tr.Errs) > 0 && len(r.Error) == 0 {
+		err := errors.Join(tr.Errs...)
+		r.Status, r.Error = StatusOf(err), err.Error()
+	}
+}
+
 type disposition int
 
 const (
@@ -569,6 +727,14 @@ func NewDisposition() Disposition {
 	return Disposition{}
 }
 
+// FiFo returns form and file names of part d describes along with index of part header
+func (d Disposition) FiFo() FiFo {
+	f := NewFiFo(d.FormName, d.FileName)
+	f.Index = GetIndex(d.H)
+
+	return f
+}
+
 // NewDispositionFilled cuts header fron dataPiece, creates Disposition on its base
 func NewDispositionFilled(d DataPiece, bou Boundary) (Disposition, error) {
 
@@ -1335,9 +1501,10 @@ func IsOk(name string, want, got []GRequest) bool {
 }
 
 type WaitGroups struct {
-	M       map[AppStoreKeyGeneral]*sync.WaitGroup
+	M       map[AppStoreKeyGeneral]*sync.WaitGroup // units of requests being fed, guarded by application lock
 	Workers sync.WaitGroup
 	Sender  sync.WaitGroup
+	Sending sync.WaitGroup // transmissions in progress
 }
 
 type Channels struct {
@@ -2150,3 +2150,114 @@ func (s *modelsSuite) TestIsOk() {
 		})
 	}
 }
+
+func (s *modelsSuite) TestResultAdd() {
+	tt := []struct {
+		name string
+		r    Result
+		trs  []TransmitResult
+		want Result
+	}{
+		{
+			name: "fields and files",
+			r:    NewResult("qqq"),
+			trs: []TransmitResult{
+				{TS: "qqq", Fields: []ResultField{{Name: "alice", Size: 5}}},
+				{TS: "qqq", Files: []ResultFile{{Field: "bob", Name: "short.txt", Size: 10}}},
+				{TS: "qqq", Files: []ResultFile{{Field: "claire", Name: "long.txt", Size: 1000}}},
+			},
+			want: Result{
+				TS:     "qqq",
+				Fields: []ResultField{{Name: "alice", Size: 5}},
+				Files:  []ResultFile{{Field: "bob", Name: "short.txt", Size: 10}, {Field: "claire", Name: "long.txt", Size: 1000}},
+				Status: 200,
+			},
+		},
+
+		{
+			name: "file reported several times",
+			r:    NewResult("qqq"),
+			trs: []TransmitResult{
+				{TS: "qqq", Files: []ResultFile{{Field: "claire", Name: "long.txt", Size: 100}}},
+				{TS: "qqq", Files: []ResultFile{{Field: "claire", Name: "long.txt", Size: 1000}}},
+				{TS: "qqq", Files: []ResultFile{{Field: "claire", Name: "long.txt", Size: 500}}},
+			},
+			want: Result{
+				TS:     "qqq",
+				Fields: []ResultField{},
+				Files:  []ResultFile{{Field: "claire", Name: "long.txt", Size: 1000}},
+				Status: 200,
+			},
+		},
+
+		{
+			name: "the first error sets status",
+			r:    NewResult("qqq"),
+			trs: []TransmitResult{
+				{TS: "qqq", Fields: []ResultField{{Name: "alice", Size: 5}}},
+				{TS: "qqq", Errs: []error{ErrTooLarge}},
+				{TS: "qqq", Errs: []error{ErrSaver}},
+			},
+			want: Result{
+				TS:     "qqq",
+				Fields: []ResultField{{Name: "alice", Size: 5}},
+				Files:  []ResultFile{},
+				Error:  ErrTooLarge.Error(),
+				Status: 413,
+			},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			for _, tr := range v.trs {
+				v.r.Add(tr)
+			}
+			s.Equal(v.want, v.r)
+		})
+	}
+}
+
+func (s *modelsSuite) TestNewTransmitResult() {
+	tt := []struct {
+		name string
+		adu  AppDistributorUnit
+		want TransmitResult
+	}{
+		{
+			name: "unary field",
+			adu: AppDistributorUnit{
+				H: AppDistributorHeader{
+					T: Unary,
+					U: UnaryData{
+						UK: UnaryKey{TS: "qqq", Part: 0},
+						F:  FiFo{FormName: "alice"},
+						M:  Message{PreAction: Start, PostAction: Finish},
+					},
+				},
+				B: AppDistributorBody{B: []byte("azaza")},
+			},
+			want: TransmitResult{TS: "qqq", Fields: []ResultField{{Name: "alice", Size: 5}}},
+		},
+
+		{
+			name: "unary file",
+			adu: AppDistributorUnit{
+				H: AppDistributorHeader{
+					T: Unary,
+					U: UnaryData{
+						UK: UnaryKey{TS: "qqq", Part: 0},
+						F:  FiFo{FormName: "bob", FileName: "short.txt"},
+						M:  Message{PreAction: Start, PostAction: Continue},
+					},
+				},
+				B: AppDistributorBody{B: []byte("bzbzbz")},
+			},
+			want: TransmitResult{TS: "qqq", Files: []ResultFile{{Field: "bob", Name: "short.txt", Size: 6}}},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, NewTransmitResult(v.adu))
+		})
+	}
+}
@@ -1,99 +1,657 @@
 package repo
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net"
-	"os"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// AnalyzeHeader returns first 512 bytes of connection and boundary if found
-func AnalyzeHeader(conn net.Conn) (Boundary, []byte, error) {
-	header := make([]byte, 512)
-	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 15)) // tls handshake requires at least 9 ms timeout
-	n, err := io.ReadFull(conn, header)
-	if err != nil &&
-		(err != io.EOF && err != io.ErrUnexpectedEOF && !os.IsTimeout(err)) {
-		return Boundary{}, header, err
+const (
+	MaxRequestHeaderLimit = 8192 // request line and header fields together
+)
+
+var (
+	ErrHeaderMalformed      = errors.New("malformed request header")
+	ErrHeaderTooLarge       = errors.New("request header too large")
+	ErrMethodNotAllowed     = errors.New("method not allowed")
+	ErrNotFound             = errors.New("no route for request")
+	ErrUnauthorized         = errors.New("request is not authenticated")
+	ErrForbidden            = errors.New("request is forbidden")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrNotImplemented       = errors.New("transfer coding not implemented")
+	ErrExpectationFailed    = errors.New("expectation failed")
+	ErrBodyMalformed        = errors.New("malformed request body")
+	ErrBodyEmpty            = errors.New("request body is empty")
+	ErrTooLarge             = errors.New("request entity too large")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrSaver                = errors.New("saver failed")
+	ErrTimeout              = errors.New("request handling is not finished in time")
+)
+
+// RequestHeader contains request line and header fields of HTTP request
+type RequestHeader struct {
+	Method           string
+	Path             string
+	Proto            string
+	ContentType      string
+	ContentLength    int // -1 if not set
+	TransferEncoding string
+	Bou              Boundary
+	Fields           textproto.MIMEHeader
+}
+
+// ReadRequestHeader reads request line and header fields from r.
+// Reading stops right after empty line, r is left at the beginning of the body.
+// Tested in netOps_test.go
+func ReadRequestHeader(r *bufio.Reader) (RequestHeader, error) {
+	rh, read := RequestHeader{ContentLength: -1, Fields: make(textproto.MIMEHeader)}, 0
+
+	line, err := readHeaderLine(r, &read)
+	for err == nil && len(line) == 0 { // leading empty lines should be ignored, RFC 7230 3.5
+		line, err = readHeaderLine(r, &read)
+	}
+	if err != nil {
+		return rh, err
 	}
-	if n < len(header) {
-		header = header[:n]
+	rh.Method, rh.Path, rh.Proto, err = ParseRequestLine(line)
+	if err != nil {
+		return rh, err
+	}
+
+	for {
+		line, err = readHeaderLine(r, &read)
+		if err != nil {
+			return rh, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		ci := strings.Index(line, ":")
+		if ci < 1 || strings.ContainsAny(line[:ci], " \t") {
+			return rh, fmt.Errorf("in repo.ReadRequestHeader %w: field %q", ErrHeaderMalformed, line)
+		}
+		key := textproto.CanonicalMIMEHeaderKey(line[:ci])
+		rh.Fields.Add(key, strings.TrimSpace(line[ci+1:]))
 	}
-	bou := FindBoundary(header)
-	return bou, header, err
+
+	return rh, rh.fill()
 }
 
-// AnalyzeBits returns result of reading 1024 bytes from connection
-func AnalyzeBits(conn net.Conn, i, p int, h []byte) (ReceiverBody, error) {
-	rb, ending := NewReceiverBody(i), make([]byte, 0)
-	if p == 0 {
+// NewRequestHeader returns header of request which is already parsed, e.g. by HTTP/2 server
+func NewRequestHeader(method, path, proto string, fields textproto.MIMEHeader) (RequestHeader, error) {
+	rh := RequestHeader{
+		Method:        method,
+		Path:          path,
+		Proto:         proto,
+		ContentLength: -1,
+		Fields:        fields,
+	}
+	return rh, rh.fill()
+}
 
-		lenh := len(h)
-		if lenh < 512 {
-			ending = make([]byte, 1024-lenh)
-		} else {
-			ending = make([]byte, 512)
+// readHeaderLine returns next header line without CRLF, counting read bytes in n
+func readHeaderLine(r *bufio.Reader, n *int) (string, error) {
+	line, err := r.ReadSlice('\n')
+	*n += len(line)
+	if *n > MaxRequestHeaderLimit || err == bufio.ErrBufferFull {
+		return "", fmt.Errorf("in repo.readHeaderLine %w: more than %d bytes", ErrHeaderTooLarge, MaxRequestHeaderLimit)
+	}
+	if err != nil {
+		if err == io.EOF && *n > 0 {
+			return "", io.ErrUnexpectedEOF
 		}
+		return "", err
+	}
+	return string(bytes.TrimRight(line, Sep)), nil
+}
 
-		rb.B = h
+// ParseRequestLine splits request line into method, target path and protocol version.
+func ParseRequestLine(line string) (string, string, string, error) {
+	parts := strings.Split(line, " ")
+	if len(parts) != 3 || len(parts[0]) == 0 || len(parts[1]) == 0 ||
+		!strings.HasPrefix(parts[2], "HTTP/1.") {
+		return "", "", "", fmt.Errorf("in repo.ParseRequestLine %w: request line %q", ErrHeaderMalformed, line)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
 
-		if lenh < 512 {
-			return rb, io.EOF
-		}
-		conn.SetReadDeadline(time.Now().Add(time.Millisecond * 1))
-		n, err := io.ReadFull(conn, ending)
+// fill sets fields of rh which are derived from header fields
+func (rh *RequestHeader) fill() error {
+	rh.ContentType = rh.Fields.Get("Content-Type")
+	rh.TransferEncoding = strings.ToLower(rh.Fields.Get("Transfer-Encoding"))
 
-		if err != nil {
-			if err != io.EOF && err != io.ErrUnexpectedEOF && !os.IsTimeout(err) {
-				return rb, err
+	if cls := rh.Fields.Values("Content-Length"); len(cls) > 0 {
+		for _, v := range cls {
+			if v != cls[0] {
+				return fmt.Errorf("in repo.RequestHeader.fill %w: different Content-Length values %q", ErrHeaderMalformed, cls)
 			}
-			// EOF
-			if n > 0 && n <= len(ending) {
+		}
+		cl, err := strconv.Atoi(cls[0])
+		if err != nil || cl < 0 {
+			return fmt.Errorf("in repo.RequestHeader.fill %w: Content-Length %q", ErrHeaderMalformed, cls[0])
+		}
+		rh.ContentLength = cl
+	}
+
+	mt, params, err := mime.ParseMediaType(rh.ContentType)
+	switch {
+	case err == nil && mt == MultipartFormData && len(params["boundary"]) > 0:
+		rh.Bou = Boundary{
+			Prefix: []byte("--"),
+			Root:   []byte(params["boundary"]),
+		}
+	case rh.converted(): // body is converted into multipart one framed by random boundary
+		rh.Bou = Boundary{
+			Prefix: []byte("--"),
+			Root:   []byte(RandomString(32)),
+		}
+	}
+	return nil
+}
 
-				ending = ending[:n]
-				rb.B = append(rb.B, ending...)
+// MediaType returns media type of request body in lower case, empty if Content-Type is malformed
+func (rh RequestHeader) MediaType() string {
+	mt, _, _ := mime.ParseMediaType(rh.ContentType)
+
+	return mt
+}
+
+// Check returns error if request cannot be handled by receivers
+func (rh RequestHeader) Check() error {
+	if rh.Method != http.MethodPost && rh.Method != http.MethodPut {
+		return fmt.Errorf("in repo.RequestHeader.Check %w: %q", ErrMethodNotAllowed, rh.Method)
+	}
+	if rh.MediaType() != MultipartFormData && !rh.converted() {
+		return fmt.Errorf("in repo.RequestHeader.Check %w: %q", ErrUnsupportedMediaType, rh.ContentType)
+	}
+	if len(rh.Bou.Root) == 0 {
+		return fmt.Errorf("in repo.RequestHeader.Check %w: no boundary in %q", ErrHeaderMalformed, rh.ContentType)
+	}
+	if len(rh.TransferEncoding) > 0 && rh.TransferEncoding != "chunked" {
+		return fmt.Errorf("in repo.RequestHeader.Check %w: %q", ErrNotImplemented, rh.TransferEncoding)
+	}
+	if e := rh.Fields.Get("Expect"); len(e) > 0 && !strings.EqualFold(e, "100-continue") {
+		return fmt.Errorf("in repo.RequestHeader.Check %w: %q", ErrExpectationFailed, e)
+	}
+	return nil
+}
+
+// ExpectsContinue returns true if client waits for 100 Continue before sending body, RFC 7231 5.1.1.
+// Expectation of HTTP/1.0 client is ignored
+func (rh RequestHeader) ExpectsContinue() bool {
+	return strings.EqualFold(rh.Fields.Get("Expect"), "100-continue") && rh.Proto != "HTTP/1.0" && rh.ContentLength != 0
+}
 
-				return rb, err
+// StatusOf returns HTTP status code corresponding to err
+func StatusOf(err error) int {
+	var te interface{ Timeout() bool }
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrHeaderTooLarge):
+		return http.StatusRequestHeaderFieldsTooLarge
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrNotImplemented):
+		return http.StatusNotImplemented
+	case errors.Is(err, ErrExpectationFailed):
+		return http.StatusExpectationFailed
+	case errors.Is(err, ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrSaver):
+		return http.StatusBadGateway
+	case errors.Is(err, ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &te) && te.Timeout():
+		return http.StatusRequestTimeout
+	}
+	return http.StatusBadRequest
+}
+
+// AnalyzeHeader reads request header from r, which is buffered reader of connection
+func AnalyzeHeader(r *bufio.Reader) (RequestHeader, error) {
+	return ReadRequestHeader(r)
+}
+
+// AnalyzeBits returns result of reading i bytes of request body from r.
+// Zero part begins with CRLF which belongs to the first boundary.
+// Error of r is returned along with the last bytes of the body, it is wrapped with ErrBodyEmpty if body has no bytes.
+// Tested in netOps_test.go
+func AnalyzeBits(r io.Reader, i, p int) (ReceiverBody, error) {
+	rb, n := NewReceiverBody(i), 0
+	if p == 0 {
+		n = copy(rb.B, Sep)
+	}
+
+	for n < len(rb.B) {
+		nn, err := r.Read(rb.B[n:])
+		n += nn
+		if err != nil {
+			if p == 0 && n == len(Sep) {
+
+				return NewReceiverBody(0), fmt.Errorf("in repo.AnalyzeBits %w: part %d: %w", ErrBodyEmpty, p, err)
 			}
+			rb.B = rb.B[:n]
 
+			return rb, err
 		}
-		if n > 0 && n < len(ending) {
-			ending = ending[:n]
+	}
+
+	return rb, nil
+}
+
+// NewBodyReader returns reader of request body framed by chunked transfer coding or by Content-Length.
+// If neither is set, multipart body is framed by closing boundary, other bodies are empty, RFC 7230 3.3.3.
+// Reader returns io.EOF along with the last bytes of the body, bytes after the body are left in r
+func NewBodyReader(r *bufio.Reader, rh RequestHeader) io.Reader {
+	if rh.TransferEncoding == "chunked" { // overrides Content-Length, RFC 7230 3.3.3
+		return &chunkedReader{r: r}
+	}
+	if rh.ContentLength >= 0 {
+		return &lengthReader{r: r, n: rh.ContentLength}
+	}
+	if rh.converted() {
+		return &lengthReader{r: r}
+	}
+	return &boundaryReader{r: r, last: closingBoundary(rh.Bou), m: len(Sep)} // body is considered to be preceded by CRLF
+}
+
+// closingBoundary returns closing boundary with CRLF in front
+func closingBoundary(bou Boundary) []byte {
+	last := append([]byte(Sep), GenBoundary(bou)[len(Sep):]...)
+
+	return append(last, []byte("--")...)
+}
+
+// ClosingWatcher passes body through and notes whether closing boundary is met in it.
+// Tested in netOps_test.go
+type ClosingWatcher struct {
+	r    io.Reader
+	last []byte
+	m    int
+	met  bool
+}
+
+func NewClosingWatcher(r io.Reader, bou Boundary) *ClosingWatcher {
+	return &ClosingWatcher{r: r, last: closingBoundary(bou), m: len(Sep)}
+}
+
+func (c *ClosingWatcher) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+
+	for i := 0; i < n && !c.met; i++ {
+		switch {
+		case b[i] == c.last[c.m]:
+			c.m++
+		case b[i] == c.last[0]:
+			c.m = 1
+		default:
+			c.m = 0
 		}
-		rb.B = append(rb.B, ending...)
+		c.met = c.m == len(c.last)
+	}
+	return n, err
+}
+
+// Met reports whether closing boundary has been read
+func (c *ClosingWatcher) Met() bool {
+	return c.met
+}
 
-		return rb, nil
+// lengthReader reads n bytes from r
+type lengthReader struct {
+	r io.Reader
+	n int
+}
+
+func (l *lengthReader) Read(b []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, io.EOF
 	}
-	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 1))
-	n, err := io.ReadFull(conn, rb.B)
-	if err != nil {
+	if len(b) > l.n {
+		b = b[:l.n]
+	}
+	n, err := l.r.Read(b)
+	l.n -= n
+	if l.n == 0 {
+		return n, io.EOF
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
 
-		if err != io.EOF && err != io.ErrUnexpectedEOF && !os.IsTimeout(err) {
-			return rb, err
+// boundaryReader reads from r until closing boundary is met
+type boundaryReader struct {
+	r    *bufio.Reader
+	last []byte // closing boundary with CRLF in front
+	m    int    // number of closing boundary bytes already met
+	done bool
+}
+
+func (br *boundaryReader) Read(b []byte) (int, error) {
+	if br.done {
+		return 0, io.EOF
+	}
+	l := br.r.Buffered()
+	if l == 0 {
+		l = 1 // blocks until some bytes arrive
+	}
+	if l > len(b) {
+		l = len(b)
+	}
+	peeked, err := br.r.Peek(l)
+	n := 0
+	for n < len(peeked) {
+		switch {
+		case peeked[n] == br.last[br.m]:
+			br.m++
+		case peeked[n] == br.last[0]: // CR cannot be met inside boundary
+			br.m = 1
+		default:
+			br.m = 0
+		}
+		n++
+		if br.m == len(br.last) {
+			br.done = true
+			break
 		}
-		// EOF
-		if n == 0 {
+	}
+	copy(b, peeked[:n])
+	br.r.Discard(n)
+
+	if br.done {
+		return n, io.EOF
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// chunkedReader decodes chunked transfer coding from r.
+// Chunk extensions and trailer fields are ignored
+type chunkedReader struct {
+	r       *bufio.Reader
+	n       int // bytes left in current chunk
+	started bool
+	done    bool
+}
 
-			return NewReceiverBody(0), fmt.Errorf("in repo.AnalyzeBits request part %d is empty", p)
+func (c *chunkedReader) Read(b []byte) (int, error) {
+	if !c.started {
+		c.started = true
+		if err := c.nextChunk(); err != nil {
+			return 0, err
 		}
-		if n > 0 && n <= len(rb.B) {
+	}
+	if c.done {
+		return 0, io.EOF
+	}
+	if len(b) > c.n {
+		b = b[:c.n]
+	}
+	n, err := c.r.Read(b)
+	c.n -= n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return n, err
+	}
+	if c.n == 0 {
+		if err = c.endChunk(); err != nil {
+			return n, err
+		}
+		if err = c.nextChunk(); err != nil {
+			return n, err
+		}
+		if c.done {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
 
-			rb.B = rb.B[:n]
+// line returns next line of chunked body, counting read bytes in n
+func (c *chunkedReader) line(n *int) (string, error) {
+	line, err := readHeaderLine(c.r, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return line, err
+}
 
-			return rb, err
+// endChunk reads CRLF after chunk data
+func (c *chunkedReader) endChunk() error {
+	read := 0
+	line, err := c.line(&read)
+	if err != nil {
+		return err
+	}
+	if len(line) > 0 {
+		return fmt.Errorf("in repo.chunkedReader.endChunk %w: %q after chunk data", ErrBodyMalformed, line)
+	}
+	return nil
+}
+
+// nextChunk reads chunk size line. Trailer fields are read after the last chunk
+func (c *chunkedReader) nextChunk() error {
+	read := 0
+	line, err := c.line(&read)
+	if err != nil {
+		return err
+	}
+	size := line
+	if i := strings.Index(line, ";"); i >= 0 {
+		size = line[:i]
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(size), 16, 31)
+	if err != nil {
+		return fmt.Errorf("in repo.chunkedReader.nextChunk %w: chunk size line %q", ErrBodyMalformed, line)
+	}
+	c.n = int(n)
+	if c.n > 0 {
+		return nil
+	}
+
+	read = 0
+	for {
+		line, err = c.line(&read)
+		if err != nil {
+			return err
 		}
+		if len(line) == 0 {
+			break
+		}
+		if strings.Index(line, ":") < 1 {
+			return fmt.Errorf("in repo.chunkedReader.nextChunk %w: trailer field %q", ErrBodyMalformed, line)
+		}
+	}
+	c.done = true
+	return nil
+}
+
+// Timeouts restricts reading from connection. Zero value means no restriction
+type Timeouts struct {
+	Idle     time.Duration // max pause between data arrivals
+	Request  time.Duration // max duration of whole request
+	Response time.Duration // max wait for request handling to be finished
+}
+
+// DeadlineReader reads from connection, renewing read deadline before each reading
+type DeadlineReader struct {
+	conn net.Conn
+	t    Timeouts
+	end  time.Time
+}
 
+func NewDeadlineReader(conn net.Conn, t Timeouts) *DeadlineReader {
+	return &DeadlineReader{
+		conn: conn,
+		t:    t,
 	}
+}
 
-	return rb, nil
+// Start begins new request period
+func (d *DeadlineReader) Start() {
+	d.end = time.Time{}
+	if d.t.Request > 0 {
+		d.end = time.Now().Add(d.t.Request)
+	}
+}
+
+// Deadline returns end of request period, zero if request duration is unrestricted
+func (d *DeadlineReader) Deadline() time.Time {
+	return d.end
+}
 
+// End finishes request period, only idle timeout is applied until next Start
+func (d *DeadlineReader) End() {
+	d.end = time.Time{}
+}
+
+func (d *DeadlineReader) Read(b []byte) (int, error) {
+	dl := time.Time{}
+	if d.t.Idle > 0 {
+		dl = time.Now().Add(d.t.Idle)
+	}
+	if !d.end.IsZero() && (dl.IsZero() || d.end.Before(dl)) {
+		dl = d.end
+	}
+	d.conn.SetReadDeadline(dl)
+
+	return d.conn.Read(b)
+}
+
+// KeepAlive returns true if connection should be kept open after the request, RFC 7230 6.3
+func (rh RequestHeader) KeepAlive() bool {
+	tokens := make(map[string]bool)
+	for _, v := range rh.Fields.Values("Connection") {
+		for _, t := range strings.Split(v, ",") {
+			tokens[strings.ToLower(strings.TrimSpace(t))] = true
+		}
+	}
+	if tokens["close"] {
+		return false
+	}
+	if rh.Proto == "HTTP/1.0" {
+		return tokens["keep-alive"]
+	}
+	return true
 }
 
 // Respond responds to connection with successful code
 func Respond(conn net.Conn) {
+	RespondStatus(conn, http.StatusOK, "")
+}
+
+// RespondContinue tells client waiting for 100 Continue to send body
+func RespondContinue(conn net.Conn) {
+	fmt.Fprint(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+}
+
+// RespondStatus responds to connection with given status code.
+// Connection header field is added if connection is not empty
+func RespondStatus(conn net.Conn, code int, connection string) {
+
+	body := fmt.Sprintf("%d %s", code, http.StatusText(code))
+
+	if len(connection) > 0 {
+		connection = "Connection: " + connection + "\r\n"
+	}
+
+	fmt.Fprintf(conn, "HTTP/1.1 %s\r\nContent-Length: %d\r\nContent-Type: text/html\r\n%s\r\n%s", body, len(body), connection, body)
+}
+
+// RespondResult writes res to conn as JSON, response status is taken from res
+func RespondResult(conn net.Conn, res Result, connection string) {
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		RespondStatus(conn, http.StatusInternalServerError, "close")
+		return
+	}
+	if len(connection) > 0 {
+		connection = "Connection: " + connection + "\r\n"
+	}
+	if res.RetryAfter > 0 {
+		connection += "Retry-After: " + res.retryAfter() + "\r\n"
+	}
+	for _, c := range res.Challenges {
+		connection += "WWW-Authenticate: " + c + "\r\n"
+	}
+
+	fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Length: %d\r\nContent-Type: application/json\r\n%s\r\n%s", res.Status, http.StatusText(res.Status), len(body), connection, body)
+}
 
-	body := "200 OK"
+// WriteResult writes res to w as JSON, response status is taken from res
+func WriteResult(w http.ResponseWriter, res Result) {
 
-	fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nContent-Type: text/html\r\n\r\n%s", len(body), body)
+	body, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if res.RetryAfter > 0 {
+		w.Header().Set("Retry-After", res.retryAfter())
+	}
+	for _, c := range res.Challenges {
+		w.Header().Add("WWW-Authenticate", c)
+	}
+	w.WriteHeader(res.Status)
+	w.Write(body)
+}
+
+// ClientCert is identity of client verified by TLS
+type ClientCert struct {
+	CN          string   // subject common name
+	SANs        []string // subject alternative names
+	Fingerprint string   // hex encoded SHA-256 of certificate
+}
+
+// NewClientCert returns identity of client having certificate c.
+// Tested in netOps_test.go
+func NewClientCert(c *x509.Certificate) ClientCert {
+	sum := sha256.Sum256(c.Raw)
+	cc := ClientCert{
+		CN:          c.Subject.CommonName,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+	cc.SANs = append(cc.SANs, c.DNSNames...)
+	cc.SANs = append(cc.SANs, c.EmailAddresses...)
+	for _, ip := range c.IPAddresses {
+		cc.SANs = append(cc.SANs, ip.String())
+	}
+	for _, u := range c.URIs {
+		cc.SANs = append(cc.SANs, u.String())
+	}
+	return cc
 }
@@ -0,0 +1,677 @@
+package repo
+
+import (
+	"bufio"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type netOpsSuite struct {
+	suite.Suite
+}
+
+func TestNetOpsSuite(t *testing.T) {
+	suite.Run(t, new(netOpsSuite))
+}
+
+func (s *netOpsSuite) TestReadRequestHeader() {
+	tt := []struct {
+		name     string
+		req      string
+		wantRH   RequestHeader
+		wantRest string
+		wantErr  error
+	}{
+		{
+			name: "happy multipart",
+			req: "POST /upload HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"Content-Length: 5250\r\n" +
+				"Content-Type: multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b\r\n" +
+				"\r\n" +
+				"--------------------------c61fd8e07a9d3f9b\r\n",
+			wantRH: RequestHeader{
+				Method:        "POST",
+				Path:          "/upload",
+				Proto:         "HTTP/1.1",
+				ContentType:   "multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b",
+				ContentLength: 5250,
+				Bou:           Boundary{Prefix: []byte("--"), Root: []byte("------------------------c61fd8e07a9d3f9b")},
+				Fields: textproto.MIMEHeader{
+					"Host":           {"localhost"},
+					"Content-Length": {"5250"},
+					"Content-Type":   {"multipart/form-data; boundary=------------------------c61fd8e07a9d3f9b"},
+				},
+			},
+			wantRest: "--------------------------c61fd8e07a9d3f9b\r\n",
+		},
+
+		{
+			name: "happy quoted boundary, leading empty line, chunked",
+			req: "\r\n" +
+				"POST / HTTP/1.1\r\n" +
+				"transfer-encoding: Chunked\r\n" +
+				"content-type: multipart/form-data; boundary=\"azaza bzbzbz\"\r\n" +
+				"\r\n",
+			wantRH: RequestHeader{
+				Method:           "POST",
+				Path:             "/",
+				Proto:            "HTTP/1.1",
+				ContentType:      "multipart/form-data; boundary=\"azaza bzbzbz\"",
+				ContentLength:    -1,
+				TransferEncoding: "chunked",
+				Bou:              Boundary{Prefix: []byte("--"), Root: []byte("azaza bzbzbz")},
+				Fields: textproto.MIMEHeader{
+					"Transfer-Encoding": {"Chunked"},
+					"Content-Type":      {"multipart/form-data; boundary=\"azaza bzbzbz\""},
+				},
+			},
+		},
+
+		{
+			name:    "unhappy request line",
+			req:     "POST /\r\n\r\n",
+			wantErr: ErrHeaderMalformed,
+		},
+
+		{
+			name:    "unhappy field without colon",
+			req:     "POST / HTTP/1.1\r\nHost localhost\r\n\r\n",
+			wantErr: ErrHeaderMalformed,
+		},
+
+		{
+			name:    "unhappy different Content-Length",
+			req:     "POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\n",
+			wantErr: ErrHeaderMalformed,
+		},
+
+		{
+			name:    "unhappy too large",
+			req:     "POST / HTTP/1.1\r\n" + strings.Repeat("Cookie: "+strings.Repeat("a", 100)+"\r\n", 100) + "\r\n",
+			wantErr: ErrHeaderTooLarge,
+		},
+
+		{
+			name:    "unhappy header is cut",
+			req:     "POST / HTTP/1.1\r\nHost: localhost\r\n",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			r := bufio.NewReader(strings.NewReader(v.req))
+			rh, err := ReadRequestHeader(r)
+			if v.wantErr != nil {
+				s.ErrorIs(err, v.wantErr)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantRH, rh)
+			rest, _ := io.ReadAll(r)
+			s.Equal(v.wantRest, string(rest))
+		})
+	}
+}
+
+// TestFormBoundary tests that urlencoded bodies get random boundaries to be framed by after conversion
+func (s *netOpsSuite) TestFormBoundary() {
+	fields := textproto.MIMEHeader{"Content-Type": {"application/x-www-form-urlencoded"}}
+
+	rh1, err := NewRequestHeader("POST", "/", "HTTP/2.0", fields)
+	s.NoError(err)
+	rh2, err := NewRequestHeader("POST", "/", "HTTP/2.0", fields)
+	s.NoError(err)
+
+	s.Equal("--", string(rh1.Bou.Prefix))
+	s.Len(rh1.Bou.Root, 32)
+	s.NotEqual(rh1.Bou.Root, rh2.Bou.Root)
+	s.Equal(FormURLEncoded, rh1.MediaType())
+}
+
+func (s *netOpsSuite) TestCheck() {
+	tt := []struct {
+		name    string
+		rh      RequestHeader
+		wantErr error
+	}{
+		{
+			name: "happy",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "multipart/form-data; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "happy urlencoded",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "application/x-www-form-urlencoded; charset=UTF-8",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "unhappy method",
+			rh: RequestHeader{
+				Method:      "GET",
+				ContentType: "multipart/form-data; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+			wantErr: ErrMethodNotAllowed,
+		},
+
+		{
+			name: "happy JSON",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "application/json",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "happy PUT of single file",
+			rh: RequestHeader{
+				Method: "PUT",
+				Bou:    Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "happy POST of single file",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "application/pdf",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "unhappy mixed multipart",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "multipart/mixed; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+			wantErr: ErrUnsupportedMediaType,
+		},
+
+		{
+			name: "happy PUT of form",
+			rh: RequestHeader{
+				Method:      "PUT",
+				ContentType: "multipart/form-data; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+		},
+
+		{
+			name: "unhappy content type",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "text/plain",
+			},
+			wantErr: ErrUnsupportedMediaType,
+		},
+
+		{
+			name: "unhappy content type is not set",
+			rh: RequestHeader{
+				Method: "POST",
+			},
+			wantErr: ErrUnsupportedMediaType,
+		},
+
+		{
+			name: "unhappy transfer coding",
+			rh: RequestHeader{
+				Method:           "POST",
+				ContentType:      "multipart/form-data; boundary=azaza",
+				TransferEncoding: "gzip, chunked",
+				Bou:              Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+			},
+			wantErr: ErrNotImplemented,
+		},
+
+		{
+			name: "unhappy no boundary",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "multipart/form-data",
+			},
+			wantErr: ErrHeaderMalformed,
+		},
+
+		{
+			name: "100-continue",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "multipart/form-data; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+				Fields:      textproto.MIMEHeader{"Expect": {"100-Continue"}},
+			},
+		},
+
+		{
+			name: "unhappy unknown expectation",
+			rh: RequestHeader{
+				Method:      "POST",
+				ContentType: "multipart/form-data; boundary=azaza",
+				Bou:         Boundary{Prefix: []byte("--"), Root: []byte("azaza")},
+				Fields:      textproto.MIMEHeader{"Expect": {"200-ok"}},
+			},
+			wantErr: ErrExpectationFailed,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			err := v.rh.Check()
+			if v.wantErr != nil {
+				s.ErrorIs(err, v.wantErr)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
+
+func (s *netOpsSuite) TestNewBodyReader() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	tt := []struct {
+		name     string
+		rh       RequestHeader
+		req      string
+		wantBody string
+		wantRest string
+		wantErr  error
+	}{
+		{
+			name:     "happy Content-Length",
+			rh:       RequestHeader{ContentLength: 10, Bou: bou},
+			req:      "0123456789POST / HTTP/1.1\r\n",
+			wantBody: "0123456789",
+			wantRest: "POST / HTTP/1.1\r\n",
+		},
+
+		{
+			name:     "happy closing boundary",
+			rh:       RequestHeader{ContentLength: -1, Bou: bou},
+			req:      "--azaza\r\n\r\n--azaza-\r\r\n--azaza--\r\n",
+			wantBody: "--azaza\r\n\r\n--azaza-\r\r\n--azaza--",
+			wantRest: "\r\n",
+		},
+
+		{
+			name:     "happy closing boundary at the beginning",
+			rh:       RequestHeader{ContentLength: -1, Bou: bou},
+			req:      "--azaza--\r\n",
+			wantBody: "--azaza--",
+			wantRest: "\r\n",
+		},
+
+		{
+			name:     "happy urlencoded body without Content-Length is empty",
+			rh:       RequestHeader{ContentType: "application/x-www-form-urlencoded", ContentLength: -1, Bou: bou},
+			req:      "POST / HTTP/1.1\r\n",
+			wantBody: "",
+			wantRest: "POST / HTTP/1.1\r\n",
+		},
+
+		{
+			name:     "happy chunked with extension and trailer",
+			rh:       RequestHeader{ContentLength: 100, TransferEncoding: "chunked", Bou: bou},
+			req:      "5\r\n01234\r\nA;name=\"value\"\r\n56789abcde\r\n0\r\nChecksum: azaza\r\n\r\nPOST / HTTP/1.1\r\n",
+			wantBody: "0123456789abcde",
+			wantRest: "POST / HTTP/1.1\r\n",
+		},
+
+		{
+			name:     "happy chunked empty",
+			rh:       RequestHeader{ContentLength: -1, TransferEncoding: "chunked", Bou: bou},
+			req:      "0\r\n\r\n",
+			wantBody: "",
+		},
+
+		{
+			name:     "unhappy chunk size",
+			rh:       RequestHeader{ContentLength: -1, TransferEncoding: "chunked", Bou: bou},
+			req:      "5z\r\n01234\r\n0\r\n\r\n",
+			wantBody: "",
+			wantErr:  ErrBodyMalformed,
+		},
+
+		{
+			name:     "unhappy no CRLF after chunk data",
+			rh:       RequestHeader{ContentLength: -1, TransferEncoding: "chunked", Bou: bou},
+			req:      "5\r\n012345\r\n0\r\n\r\n",
+			wantBody: "01234",
+			wantErr:  ErrBodyMalformed,
+		},
+
+		{
+			name:     "unhappy chunked body is cut",
+			rh:       RequestHeader{ContentLength: -1, TransferEncoding: "chunked", Bou: bou},
+			req:      "5\r\n01234\r\n",
+			wantBody: "01234",
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+
+		{
+			name:     "unhappy body is shorter than Content-Length",
+			rh:       RequestHeader{ContentLength: 20, Bou: bou},
+			req:      "0123456789",
+			wantBody: "0123456789",
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+
+		{
+			name:     "unhappy no closing boundary",
+			rh:       RequestHeader{ContentLength: -1, Bou: bou},
+			req:      "--azaza\r\n\r\n--azaza",
+			wantBody: "--azaza\r\n\r\n--azaza",
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			r := bufio.NewReader(strings.NewReader(v.req))
+			body, err := io.ReadAll(NewBodyReader(r, v.rh))
+			s.Equal(v.wantBody, string(body))
+			if v.wantErr != nil {
+				s.ErrorIs(err, v.wantErr)
+				return
+			}
+			s.NoError(err)
+			rest, _ := io.ReadAll(r)
+			s.Equal(v.wantRest, string(rest))
+		})
+	}
+}
+
+func (s *netOpsSuite) TestKeepAlive() {
+	tt := []struct {
+		name string
+		rh   RequestHeader
+		want bool
+	}{
+		{
+			name: "HTTP/1.1 default",
+			rh:   RequestHeader{Proto: "HTTP/1.1", Fields: textproto.MIMEHeader{}},
+			want: true,
+		},
+
+		{
+			name: "HTTP/1.1 close among other tokens",
+			rh:   RequestHeader{Proto: "HTTP/1.1", Fields: textproto.MIMEHeader{"Connection": {"TE, Close"}}},
+			want: false,
+		},
+
+		{
+			name: "HTTP/1.0 default",
+			rh:   RequestHeader{Proto: "HTTP/1.0", Fields: textproto.MIMEHeader{}},
+			want: false,
+		},
+
+		{
+			name: "HTTP/1.0 keep-alive",
+			rh:   RequestHeader{Proto: "HTTP/1.0", Fields: textproto.MIMEHeader{"Connection": {"Keep-Alive"}}},
+			want: true,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, v.rh.KeepAlive())
+		})
+	}
+}
+
+func (s *netOpsSuite) TestExpectsContinue() {
+	tt := []struct {
+		name string
+		rh   RequestHeader
+		want bool
+	}{
+		{
+			name: "no expectation",
+			rh:   RequestHeader{Proto: "HTTP/1.1", ContentLength: 100},
+		},
+
+		{
+			name: "100-continue",
+			rh:   RequestHeader{Proto: "HTTP/1.1", ContentLength: -1, Fields: textproto.MIMEHeader{"Expect": {"100-continue"}}},
+			want: true,
+		},
+
+		{
+			name: "HTTP/1.0 client is not answered",
+			rh:   RequestHeader{Proto: "HTTP/1.0", ContentLength: 100, Fields: textproto.MIMEHeader{"Expect": {"100-continue"}}},
+		},
+
+		{
+			name: "empty body",
+			rh:   RequestHeader{Proto: "HTTP/1.1", ContentLength: 0, Fields: textproto.MIMEHeader{"Expect": {"100-continue"}}},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, v.rh.ExpectsContinue())
+		})
+	}
+}
+
+func (s *netOpsSuite) TestClosingWatcher() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("bRoot")}
+	tt := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{
+			name: "closing boundary at the end",
+			body: "--bRoot\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--bRoot--",
+			want: true,
+		},
+
+		{
+			name: "closing boundary followed by epilogue",
+			body: "--bRoot\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--bRoot--\r\n" +
+				"epilogue",
+			want: true,
+		},
+
+		{
+			name: "only boundary",
+			body: "--bRoot--",
+			want: true,
+		},
+
+		{
+			name: "body is cut",
+			body: "--bRoot\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza\r\n" +
+				"--bRoot",
+			want: false,
+		},
+
+		{
+			name: "closing boundary without CRLF in front",
+			body: "--bRoot\r\n" +
+				"Content-Disposition: form-data; name=\"alice\"\r\n" +
+				"\r\n" +
+				"azaza--bRoot--",
+			want: false,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			c := NewClosingWatcher(iotest.OneByteReader(strings.NewReader(v.body)), bou)
+			got, err := io.ReadAll(c)
+
+			s.NoError(err)
+			s.Equal(v.body, string(got))
+			s.Equal(v.want, c.Met())
+		})
+	}
+}
+
+func (s *netOpsSuite) TestAnalyzeBits() {
+	tt := []struct {
+		name      string
+		r         io.Reader
+		p         int
+		want      string
+		wantEmpty bool
+		wantErr   error
+	}{
+		{
+			name:    "the first part gets separator",
+			r:       strings.NewReader("azaza"),
+			want:    "\r\nazaza",
+			wantErr: io.EOF,
+		},
+
+		{
+			name:    "next part",
+			r:       strings.NewReader("azaza"),
+			p:       1,
+			want:    "azaza",
+			wantErr: io.EOF,
+		},
+
+		{
+			name:      "empty body",
+			r:         strings.NewReader(""),
+			wantEmpty: true,
+			wantErr:   io.EOF,
+		},
+
+		{
+			name:      "empty body because of error",
+			r:         iotest.ErrReader(ErrTooLarge),
+			wantEmpty: true,
+			wantErr:   ErrTooLarge,
+		},
+
+		{
+			name:    "empty next part is not empty body",
+			r:       strings.NewReader(""),
+			p:       1,
+			wantErr: io.EOF,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := AnalyzeBits(v.r, 8, v.p)
+			s.Equal(v.want, string(got.B))
+			s.True(errors.Is(err, v.wantErr), err)
+			s.Equal(v.wantEmpty, errors.Is(err, ErrBodyEmpty))
+		})
+	}
+}
+
+func (s *netOpsSuite) TestNewClientCert() {
+	u, _ := url.Parse("spiffe://example.com/partner")
+	tt := []struct {
+		name string
+		c    *x509.Certificate
+		want ClientCert
+	}{
+		{
+			name: "CN only",
+			c:    &x509.Certificate{Raw: []byte("raw"), Subject: pkix.Name{CommonName: "partner"}},
+			want: ClientCert{
+				CN:          "partner",
+				Fingerprint: "d7439bee24773bcbfa2d0a97947ee36227b10d1022b1a55847e928965bb6bfde",
+			},
+		},
+
+		{
+			name: "all kinds of SANs",
+			c: &x509.Certificate{
+				Raw:            []byte("raw"),
+				Subject:        pkix.Name{CommonName: "partner"},
+				DNSNames:       []string{"partner.example.com"},
+				EmailAddresses: []string{"upload@example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("192.0.2.1")},
+				URIs:           []*url.URL{u},
+			},
+			want: ClientCert{
+				CN:          "partner",
+				SANs:        []string{"partner.example.com", "upload@example.com", "192.0.2.1", "spiffe://example.com/partner"},
+				Fingerprint: "d7439bee24773bcbfa2d0a97947ee36227b10d1022b1a55847e928965bb6bfde",
+			},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, NewClientCert(v.c))
+		})
+	}
+}
+
+func (s *netOpsSuite) TestRespondResult() {
+	tt := []struct {
+		name       string
+		res        Result
+		connection string
+		want       string
+	}{
+		{
+			name:       "keep-alive",
+			res:        NewResult("qqq"),
+			connection: "keep-alive",
+			want:       "HTTP/1.1 200 OK\r\nContent-Length: 35\r\nContent-Type: application/json\r\nConnection: keep-alive\r\n\r\n" + `{"ts":"qqq","fields":[],"files":[]}`,
+		},
+
+		{
+			name:       "Retry-After is rounded up to seconds",
+			res:        NewResultErr("qqq", &RetryError{Err: ErrTooManyRequests, After: 1500 * time.Millisecond}),
+			connection: "close",
+			want:       "HTTP/1.1 429 Too Many Requests\r\nContent-Length: 63\r\nContent-Type: application/json\r\nConnection: close\r\nRetry-After: 2\r\n\r\n" + `{"ts":"qqq","fields":[],"files":[],"error":"too many requests"}`,
+		},
+
+		{
+			name:       "WWW-Authenticate per challenge",
+			res:        NewResultErr("qqq", &ChallengeError{Err: ErrUnauthorized, Challenges: []string{`Basic realm="upload"`, `Bearer realm="upload"`}}),
+			connection: "close",
+			want: "HTTP/1.1 401 Unauthorized\r\nContent-Length: 74\r\nContent-Type: application/json\r\nConnection: close\r\n" +
+				"WWW-Authenticate: Basic realm=\"upload\"\r\nWWW-Authenticate: Bearer realm=\"upload\"\r\n\r\n" + `{"ts":"qqq","fields":[],"files":[],"error":"request is not authenticated"}`,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			c1, c2 := net.Pipe()
+			go func() {
+				RespondResult(c1, v.res, v.connection)
+				c1.Close()
+			}()
+			got, err := io.ReadAll(c2)
+			s.NoError(err)
+			s.Equal(v.want, string(got))
+		})
+	}
+}
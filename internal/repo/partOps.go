@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"mime"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// PartHeader is header of form part as client sent it
+type PartHeader struct {
+	ContentType string            // media type of part, empty if part has no Content-Type
+	Charset     string            // charset parameter of Content-Type, lowercase
+	Fields      map[string]string // every header field of part by canonical key, repeated fields are joined by comma
+}
+
+// NewPartHeader returns header made of header lines of part.
+// Tested in partOps_test.go
+func NewPartHeader(lines []string) PartHeader {
+	h := PartHeader{Fields: make(map[string]string)}
+
+	for _, l := range lines {
+		k, v, ok := strings.Cut(l, ":")
+		k = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k))
+		if !ok || len(k) == 0 {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if f, ok := h.Fields[k]; ok {
+			v = f + ", " + v
+		}
+		h.Fields[k] = v
+	}
+	if mt, params, err := mime.ParseMediaType(h.Fields["Content-Type"]); err == nil {
+		h.ContentType, h.Charset = mt, strings.ToLower(params["charset"])
+	}
+	return h
+}
+
+// Parts holds headers of parts of request body.
+// Header is found by form and file names of part, index of part is needed only when parts having the same names have different headers.
+// Headers are added while body is read and taken when units are transmitted, so Parts is safe for concurrent use
+type Parts struct {
+	mu    sync.Mutex
+	h     []PartHeader
+	named map[FiFo]int // index of header kept for the first part having names
+}
+
+// add keeps header h of part having form name fo and file name fi.
+// Returns index of h counted from 1 if names are not enough to find it, 0 otherwise. Nil Parts keeps nothing
+func (p *Parts) add(fo, fi string, h PartHeader) int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	k := NewFiFo(fo, fi)
+	i, ok := p.named[k]
+	if ok && h.equal(p.h[i-1]) {
+		return 0
+	}
+	p.h = append(p.h, h)
+	if ok {
+		return len(p.h)
+	}
+	if p.named == nil {
+		p.named = make(map[FiFo]int)
+	}
+	p.named[k] = len(p.h)
+
+	return 0
+}
+
+// Get returns header of part having names and index f, zero header if there is none.
+// Tested in partOps_test.go
+func (p *Parts) Get(f FiFo) PartHeader {
+	if p == nil {
+		return PartHeader{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := f.Index
+	if i == 0 {
+		i = p.named[NewFiFo(f.FormName, f.FileName)]
+	}
+	if i < 1 || i > len(p.h) {
+		return PartHeader{}
+	}
+	return p.h[i-1]
+}
+
+// equal returns true if h and o have the same fields
+func (h PartHeader) equal(o PartHeader) bool {
+	if len(h.Fields) != len(o.Fields) {
+		return false
+	}
+	for k, v := range h.Fields {
+		if w, ok := o.Fields[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
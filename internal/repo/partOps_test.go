@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type partOpsSuite struct {
+	suite.Suite
+}
+
+func TestPartOpsSuite(t *testing.T) {
+	suite.Run(t, new(partOpsSuite))
+}
+
+func (s *partOpsSuite) TestNewPartHeader() {
+	tt := []struct {
+		name  string
+		lines []string
+		want  PartHeader
+	}{
+		{
+			name:  "text field",
+			lines: []string{`Content-Disposition: form-data; name="alice"`},
+			want:  PartHeader{Fields: map[string]string{"Content-Disposition": `form-data; name="alice"`}},
+		},
+
+		{
+			name:  "file having charset and extra header",
+			lines: []string{`Content-Disposition: form-data; name="alice"; filename="a.txt"`, `content-type: Text/Plain; Charset="UTF-8"`, `X-Origin:scanner `},
+			want: PartHeader{
+				ContentType: "text/plain",
+				Charset:     "utf-8",
+				Fields:      map[string]string{"Content-Disposition": `form-data; name="alice"; filename="a.txt"`, "Content-Type": `Text/Plain; Charset="UTF-8"`, "X-Origin": "scanner"},
+			},
+		},
+
+		{
+			name:  "repeated header",
+			lines: []string{"X-Tag: a", "X-Tag: b"},
+			want:  PartHeader{Fields: map[string]string{"X-Tag": "a, b"}},
+		},
+
+		{
+			name:  "malformed content type and lines",
+			lines: []string{"Content-Type: /plain", "azaza", ": bzbzbz", ""},
+			want:  PartHeader{Fields: map[string]string{"Content-Type": "/plain"}},
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, NewPartHeader(v.lines))
+		})
+	}
+}
+
+func (s *partOpsSuite) TestGet() {
+	text := NewPartHeader([]string{`Content-Disposition: form-data; name="alice"`})
+	csv := NewPartHeader([]string{`Content-Disposition: form-data; name="alice"`, "Content-Type: text/csv"})
+	png := NewPartHeader([]string{`Content-Disposition: form-data; name="bob"; filename="b.png"`, "Content-Type: image/png"})
+
+	p := &Parts{}
+	s.Equal(0, p.add("alice", "", text))
+	s.Equal(0, p.add("bob", "b.png", png))
+	s.Equal(0, p.add("alice", "", text))
+	s.Equal(3, p.add("alice", "", csv))
+
+	tt := []struct {
+		name string
+		p    *Parts
+		f    FiFo
+		want PartHeader
+	}{
+		{
+			name: "found by names",
+			p:    p,
+			f:    NewFiFo("bob", "b.png"),
+			want: png,
+		},
+
+		{
+			name: "names shared by parts having different headers",
+			p:    p,
+			f:    NewFiFo("alice", ""),
+			want: text,
+		},
+
+		{
+			name: "found by index",
+			p:    p,
+			f:    FiFo{FormName: "alice", Index: 3},
+			want: csv,
+		},
+
+		{
+			name: "unknown names",
+			p:    p,
+			f:    NewFiFo("bob", ""),
+		},
+
+		{
+			name: "unknown index",
+			p:    p,
+			f:    FiFo{FormName: "alice", Index: 4},
+		},
+
+		{
+			name: "nil parts",
+			f:    NewFiFo("alice", ""),
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, v.p.Get(v.f))
+		})
+	}
+}
@@ -139,6 +139,30 @@ func (s *regexpSuite) TestIsCDRight() {
 			b:      []byte("Content-Disposition: name=\"azaza\"; filename=\"sdf.xyz \""),
 			wanted: false,
 		},
+
+		{
+			name:   "happy quoted name having spaces and emoji",
+			b:      []byte("Content-Disposition: form-data; name=\"azaza\"; filename=\"my 😀 file"),
+			wanted: true,
+		},
+
+		{
+			name:   "happy escaped quote",
+			b:      []byte("Content-Disposition: form-data; name=\"azaza\"; filename=\"a\\\"b"),
+			wanted: true,
+		},
+
+		{
+			name:   "happy extended file name",
+			b:      []byte("Content-Disposition: form-data; name=\"azaza\"; filename*=UTF-8''%E6%96%87"),
+			wanted: true,
+		},
+
+		{
+			name:   "unhappy unknown parameter",
+			b:      []byte("Content-Disposition: form-data; name=\"azaza\"; fliename=\"sdf"),
+			wanted: false,
+		},
 	}
 	for _, v := range tt {
 		s.Run(v.name, func() {
@@ -172,9 +196,9 @@ func (s *regexpSuite) TestIsCDLeft() {
 		},
 
 		{
-			name:   "unhappy_2 count(\")==1",
+			name:   "happy_3 count(\")==1 space in name",
 			b:      []byte("qasw1рпр \""),
-			wanted: false,
+			wanted: true,
 		},
 
 		{
@@ -208,9 +232,9 @@ func (s *regexpSuite) TestIsCDLeft() {
 		},
 
 		{
-			name:   "unhappy_2 count(\")==2",
+			name:   "happy_5 count(\")==2 space in name",
 			b:      []byte("filename=\"aZ1аЯ \""),
-			wanted: false,
+			wanted: true,
 		},
 
 		{
@@ -238,9 +262,9 @@ func (s *regexpSuite) TestIsCDLeft() {
 		},
 
 		{
-			name:   "unhappy_2 count(\")==3",
+			name:   "happy_3 count(\")==3 space in name",
 			b:      []byte("aZ1аЯ \"; filename=\"aZ1аЯ_\""),
-			wanted: false,
+			wanted: true,
 		},
 
 		{
@@ -262,9 +286,9 @@ func (s *regexpSuite) TestIsCDLeft() {
 		},
 
 		{
-			name:   "unhappy_1 count(\")==4",
+			name:   "happy_4 count(\")==4 space in name",
 			b:      []byte("\"aZ1аЯ \"; filename=\"aZ1аЯ_\""),
-			wanted: false,
+			wanted: true,
 		},
 
 		{
@@ -272,6 +296,42 @@ func (s *regexpSuite) TestIsCDLeft() {
 			b:      []byte("Cnotent-Disposition:  name=\"aZ1аЯ\"; filename=\"aZ1аЯ_\""),
 			wanted: false,
 		},
+
+		{
+			name:   "happy CJK file name",
+			b:      []byte("; filename=\"文件 1.txt\""),
+			wanted: true,
+		},
+
+		{
+			name:   "happy tail of extended file name",
+			b:      []byte("%96%87.txt"),
+			wanted: true,
+		},
+
+		{
+			name:   "happy extended file name",
+			b:      []byte("\"; filename*=UTF-8''%E6%96%87.txt"),
+			wanted: true,
+		},
+
+		{
+			name:   "happy tail of index",
+			b:      []byte("dex=\"2\""),
+			wanted: true,
+		},
+
+		{
+			name:   "unhappy line break in name",
+			b:      []byte("filename=\"aZ1\rаЯ\""),
+			wanted: false,
+		},
+
+		{
+			name:   "unhappy data after quoted name",
+			b:      []byte("\"aZ1аЯ\"azaza"),
+			wanted: false,
+		},
 	}
 	for _, v := range tt {
 		s.Run(v.name, func() {
@@ -469,3 +529,46 @@ func (s *regexpSuite) TestIsLastBoundary() {
 		s.True(IsLastBoundary(v.p, v.n, v.bou))
 	}
 }
+
+func (s *regexpSuite) TestSufficiency() {
+	tt := []struct {
+		name   string
+		b      []byte
+		wanted sufficiency
+	}{
+		{
+			name:   "text field",
+			b:      []byte("Content-Disposition: form-data; name=\"alice\""),
+			wanted: Sufficient,
+		},
+
+		{
+			name:   "file",
+			b:      []byte("Content-Disposition: form-data; name=\"alice\"; filename=\"my file.txt\""),
+			wanted: Insufficient,
+		},
+
+		{
+			name:   "file having extended name only",
+			b:      []byte("Content-Disposition: form-data; name=\"alice\"; filename*=UTF-8''%F0%9F%98%80.txt"),
+			wanted: Insufficient,
+		},
+
+		{
+			name:   "cut line",
+			b:      []byte("Content-Disposition: form-data; name=\"alice\"; filename=\"my"),
+			wanted: Incomplete,
+		},
+
+		{
+			name:   "other line",
+			b:      []byte("Content-Type: text/plain"),
+			wanted: Incomplete,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.wanted, Sufficiency(v.b))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Route binds requests having given method and path prefix to pipeline
+type Route struct {
+	Name         string            `json:"name"`
+	Method       string            `json:"method"`       // POST if empty
+	Path         string            `json:"path"`         // path prefix, matches whole path segments only
+	MaxBodySize  int64             `json:"maxBodySize"`  // bytes, zero means unrestricted
+	ContentTypes []string          `json:"contentTypes"` // allowed media types, empty means any supported one
+	Saver        string            `json:"saver"`        // saver address, default saver is used if empty
+	Tags         map[string]string `json:"tags"`         // sent to saver along with request data
+	Principals   []string          `json:"principals"`   // authenticated principals allowed to use route, anyone if empty
+}
+
+// Routes is routing table. Empty table routes every request to default pipeline
+type Routes []Route
+
+// Match returns route of request having given method and path.
+// The longest matching path prefix wins, error is returned if no route matches.
+// Tested in routeOps_test.go
+func (rs Routes) Match(method, path string) (Route, error) {
+	if len(rs) == 0 {
+		return Route{}, nil
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	best := -1
+	for i, r := range rs {
+		if !strings.EqualFold(r.method(), method) || !r.matches(path) {
+			continue
+		}
+		if best < 0 || len(r.Path) > len(rs[best].Path) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Route{}, fmt.Errorf("in repo.Routes.Match %w: %s %q", ErrNotFound, method, path)
+	}
+	return rs[best], nil
+}
+
+// Get returns route having given name, zero route means default pipeline
+func (rs Routes) Get(name string) Route {
+	for _, r := range rs {
+		if r.Name == name {
+			return r
+		}
+	}
+	return Route{}
+}
+
+// Check returns error if request having header rh cannot be passed to route r
+func (r Route) Check(rh RequestHeader) error {
+	if r.MaxBodySize > 0 && int64(rh.ContentLength) > r.MaxBodySize {
+		return fmt.Errorf("in repo.Route.Check %w: Content-Length %d exceeds %d allowed by route %q", ErrTooLarge, rh.ContentLength, r.MaxBodySize, r.Name)
+	}
+	if len(r.ContentTypes) == 0 {
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(rh.ContentType)
+	for _, ct := range r.ContentTypes {
+		if strings.EqualFold(ct, mt) {
+			return nil
+		}
+	}
+	return fmt.Errorf("in repo.Route.Check %w: %q is not allowed by route %q", ErrUnsupportedMediaType, mt, r.Name)
+}
+
+// Allows returns error if principal is not allowed to use route r
+func (r Route) Allows(principal string) error {
+	if len(r.Principals) == 0 {
+		return nil
+	}
+	for _, p := range r.Principals {
+		if p == principal {
+			return nil
+		}
+	}
+	return fmt.Errorf("in repo.Route.Allows %w: %q cannot use route %q", ErrForbidden, principal, r.Name)
+}
+
+// LimitBody returns reader of body which fails when more than r.MaxBodySize bytes are read
+func (r Route) LimitBody(body io.Reader) io.Reader {
+	if r.MaxBodySize <= 0 {
+		return body
+	}
+	return &limitReader{r: body, n: r.MaxBodySize, by: fmt.Sprintf("route %q", r.Name)}
+}
+
+// method returns method of requests passed to r
+func (r Route) method() string {
+	if len(r.Method) == 0 {
+		return http.MethodPost
+	}
+	return r.Method
+}
+
+// matches returns true if path starts with path prefix of r
+func (r Route) matches(path string) bool {
+	prefix := strings.TrimSuffix(r.Path, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+
+	return len(rest) == 0 || rest[0] == '/'
+}
+
+// limitReader reads from r failing after n bytes
+type limitReader struct {
+	r  io.Reader
+	n  int64
+	by string // what sets limit
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > l.n+1 { // one byte more is enough to know that limit is exceeded
+		b = b[:l.n+1]
+	}
+	n, err := l.r.Read(b)
+	if int64(n) > l.n {
+		n, l.n = int(l.n), 0
+		return n, fmt.Errorf("in repo.limitReader %w: body exceeds size allowed by %s", ErrTooLarge, l.by)
+	}
+	l.n -= int64(n)
+	return n, err
+}
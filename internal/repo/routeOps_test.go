@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type routeOpsSuite struct {
+	suite.Suite
+}
+
+func TestRouteOpsSuite(t *testing.T) {
+	suite.Run(t, new(routeOpsSuite))
+}
+
+func (s *routeOpsSuite) TestMatch() {
+	rs := Routes{
+		{Name: "root", Path: "/"},
+		{Name: "avatars", Path: "/avatars"},
+		{Name: "large", Path: "/avatars/large/"},
+		{Name: "put", Method: "PUT", Path: "/documents"},
+	}
+	tt := []struct {
+		name     string
+		rs       Routes
+		method   string
+		path     string
+		wantName string
+		wantErr  error
+	}{
+		{
+			name:   "empty table routes to default route",
+			method: "POST",
+			path:   "/anything",
+		},
+
+		{
+			name:     "exact path",
+			rs:       rs,
+			method:   "POST",
+			path:     "/avatars",
+			wantName: "avatars",
+		},
+
+		{
+			name:     "the longest prefix wins",
+			rs:       rs,
+			method:   "POST",
+			path:     "/avatars/large/1",
+			wantName: "large",
+		},
+
+		{
+			name:     "query is ignored",
+			rs:       rs,
+			method:   "POST",
+			path:     "/avatars?user=1",
+			wantName: "avatars",
+		},
+
+		{
+			name:     "prefix matches whole segments only",
+			rs:       rs,
+			method:   "POST",
+			path:     "/avatarsX",
+			wantName: "root",
+		},
+
+		{
+			name:     "method",
+			rs:       rs,
+			method:   "PUT",
+			path:     "/documents/1",
+			wantName: "put",
+		},
+
+		{
+			name:    "unhappy no route",
+			rs:      rs[1:],
+			method:  "POST",
+			path:    "/documents",
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			r, err := v.rs.Match(v.method, v.path)
+			if v.wantErr != nil {
+				s.ErrorIs(err, v.wantErr)
+				return
+			}
+			s.NoError(err)
+			s.Equal(v.wantName, r.Name)
+		})
+	}
+}
+
+func (s *routeOpsSuite) TestRouteCheck() {
+	tt := []struct {
+		name    string
+		r       Route
+		rh      RequestHeader
+		wantErr error
+	}{
+		{
+			name: "no restrictions",
+			rh:   RequestHeader{ContentType: "multipart/form-data; boundary=azaza", ContentLength: 100},
+		},
+
+		{
+			name: "allowed content type",
+			r:    Route{ContentTypes: []string{"multipart/form-data"}, MaxBodySize: 100},
+			rh:   RequestHeader{ContentType: "Multipart/Form-Data; boundary=azaza", ContentLength: 100},
+		},
+
+		{
+			name: "body size is not known in advance",
+			r:    Route{MaxBodySize: 100},
+			rh:   RequestHeader{ContentType: "multipart/form-data; boundary=azaza", ContentLength: -1},
+		},
+
+		{
+			name:    "unhappy content type",
+			r:       Route{ContentTypes: []string{"application/json"}},
+			rh:      RequestHeader{ContentType: "multipart/form-data; boundary=azaza", ContentLength: 100},
+			wantErr: ErrUnsupportedMediaType,
+		},
+
+		{
+			name:    "unhappy Content-Length",
+			r:       Route{MaxBodySize: 99},
+			rh:      RequestHeader{ContentType: "multipart/form-data; boundary=azaza", ContentLength: 100},
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			err := v.r.Check(v.rh)
+			if v.wantErr != nil {
+				s.ErrorIs(err, v.wantErr)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
+
+func (s *routeOpsSuite) TestLimitBody() {
+	tt := []struct {
+		name     string
+		max      int64
+		body     string
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name:     "unrestricted",
+			body:     "azaza",
+			wantBody: "azaza",
+		},
+
+		{
+			name:     "body fits",
+			max:      5,
+			body:     "azaza",
+			wantBody: "azaza",
+		},
+
+		{
+			name:     "unhappy body exceeds limit",
+			max:      4,
+			body:     "azaza",
+			wantBody: "azaz",
+			wantErr:  ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(Route{MaxBodySize: v.max}.LimitBody(strings.NewReader(v.body)))
+			s.Equal(v.wantBody, string(got))
+			s.Equal(v.wantErr != nil, err != nil)
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr))
+			}
+		})
+	}
+}
@@ -3,18 +3,15 @@ package repo
 import (
 	"math/rand"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
-// NewTS generates pseudo-random string based on current time
+var tsSeq = uint64(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000))
+
+// NewTS generates string based on current time. Suffix is increased on every call, so TS is unique within process
 func NewTS() string {
 	t := time.Now()
 
-	rand.Seed(t.UnixNano())
-
-	randSuffixInt := rand.Intn(1000)
-
-	randSuffixString := strconv.Itoa(randSuffixInt)
-
-	return t.Format("02.01.2006 15_04_05") + "." + randSuffixString
+	return t.Format("02.01.2006 15_04_05") + "." + strconv.FormatUint(atomic.AddUint64(&tsSeq, 1), 10)
 }
@@ -0,0 +1,146 @@
+package repo
+
+import "strings"
+
+// transferDecoder decodes content of part having Content-Transfer-Encoding byte by byte, RFC 2045 6
+type transferDecoder interface {
+	decode(dst []byte, c byte) []byte // appends bytes decoded by c to dst
+	end(dst []byte) []byte            // appends bytes held when content is over to dst
+}
+
+// newTransferDecoder returns decoder of content having Content-Transfer-Encoding cte, nil if content is not encoded.
+// Identity encodings 7bit, 8bit and binary need no decoding
+func newTransferDecoder(cte string) transferDecoder {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64":
+		return &base64Decoder{}
+	case "quoted-printable":
+		return &qpDecoder{}
+	}
+	return nil
+}
+
+// base64Alphabet holds value of every base64 character, 0xff for bytes which are not in alphabet
+var base64Alphabet = func() [256]byte {
+	var a [256]byte
+	for i := range a {
+		a[i] = 0xff
+	}
+	for i, c := range "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/" {
+		a[c] = byte(i)
+	}
+	return a
+}()
+
+// base64Decoder decodes base64 content, RFC 2045 6.8.
+// Line breaks and other characters out of alphabet are ignored, padding ends quantum
+type base64Decoder struct {
+	q [4]byte // values of quantum characters met
+	n int
+}
+
+func (d *base64Decoder) decode(dst []byte, c byte) []byte {
+	if c == '=' {
+		return d.end(dst)
+	}
+	v := base64Alphabet[c]
+	if v == 0xff {
+		return dst
+	}
+	d.q[d.n], d.n = v, d.n+1
+	if d.n < len(d.q) {
+		return dst
+	}
+	return d.end(dst)
+}
+
+// end appends bytes of quantum met, single character is not enough for any byte and is dropped
+func (d *base64Decoder) end(dst []byte) []byte {
+	bits := uint32(d.q[0])<<18 | uint32(d.q[1])<<12 | uint32(d.q[2])<<6 | uint32(d.q[3])
+	for i := 0; i < d.n-1; i++ {
+		dst = append(dst, byte(bits>>(16-8*i)))
+	}
+	d.q, d.n = [4]byte{}, 0
+
+	return dst
+}
+
+// states of qpDecoder
+const (
+	qpText = iota
+	qpEq   // after equals sign
+	qpHex  // after equals sign and hex digit
+	qpSoft // in soft line break after equals sign, before LF
+)
+
+// qpDecoder decodes quoted-printable content, RFC 2045 6.7.
+// Malformed escapes are kept as they are, whitespace at the end of line is dropped
+type qpDecoder struct {
+	state int
+	hex   byte   // the first hex digit of escape
+	ws    []byte // whitespace met in text, kept until the next byte shows it is not at the end of line
+}
+
+func (d *qpDecoder) decode(dst []byte, c byte) []byte {
+	switch d.state {
+	case qpText:
+		switch c {
+		case ' ', '\t':
+			d.ws = append(d.ws, c)
+			return dst
+		case '\r', '\n':
+			d.ws = d.ws[:0]
+			return append(dst, c)
+		}
+		dst, d.ws = append(dst, d.ws...), d.ws[:0]
+		if c == '=' {
+			d.state = qpEq
+			return dst
+		}
+		return append(dst, c)
+
+	case qpEq:
+		switch {
+		case isHex(c):
+			d.state, d.hex = qpHex, c
+		case c == '\n':
+			d.state = qpText
+		case c == '\r' || c == ' ' || c == '\t': // transport padding may precede line break
+			d.state = qpSoft
+		default:
+			d.state = qpText
+			return d.decode(append(dst, '='), c)
+		}
+		return dst
+
+	case qpHex:
+		d.state = qpText
+		if isHex(c) {
+			return append(dst, unhex(d.hex)<<4|unhex(c))
+		}
+		return d.decode(append(dst, '=', d.hex), c)
+
+	default: // qpSoft
+		switch c {
+		case '\n':
+			d.state = qpText
+		case '\r', ' ', '\t':
+		default:
+			d.state = qpText
+			return d.decode(dst, c)
+		}
+		return dst
+	}
+}
+
+func (d *qpDecoder) end(dst []byte) []byte {
+	switch d.state {
+	case qpEq:
+		dst = append(dst, '=')
+	case qpHex:
+		dst = append(dst, '=', d.hex)
+	}
+	d.state, d.ws = qpText, d.ws[:0]
+
+	return dst
+}
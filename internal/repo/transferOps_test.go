@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type transferOpsSuite struct {
+	suite.Suite
+}
+
+func TestTransferOpsSuite(t *testing.T) {
+	suite.Run(t, new(transferOpsSuite))
+}
+
+func (s *transferOpsSuite) TestTransferDecoder() {
+	tt := []struct {
+		name string
+		cte  string
+		in   string
+		want string
+	}{
+		{
+			name: "base64",
+			cte:  "base64",
+			in:   "YXphemEgYnpiemJ6\r\nIGN6Y3pjeg==",
+			want: "azaza bzbzbz czczcz",
+		},
+
+		{
+			name: "base64 padded",
+			cte:  " Base64 ",
+			in:   "YXphemE=",
+			want: "azaza",
+		},
+
+		{
+			name: "base64 not padded",
+			cte:  "base64",
+			in:   "YXphem",
+			want: "azaz",
+		},
+
+		{
+			name: "base64 out of alphabet",
+			cte:  "base64",
+			in:   "YX*ph\temE==",
+			want: "azaza",
+		},
+
+		{
+			name: "quoted-printable",
+			cte:  "quoted-printable",
+			in:   "caf=C3=a9 =\r\nna=\nive\r\nend",
+			want: "café naive\r\nend",
+		},
+
+		{
+			name: "quoted-printable trailing whitespace",
+			cte:  "Quoted-Printable",
+			in:   "azaza \t\r\nbzbzbz =  \r\nczczcz \t",
+			want: "azaza\r\nbzbzbz czczcz",
+		},
+
+		{
+			name: "quoted-printable malformed escapes",
+			cte:  "quoted-printable",
+			in:   "a=zb=4xc=",
+			want: "a=zb=4xc=",
+		},
+
+		{
+			name: "identity",
+			cte:  "8bit",
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			d := newTransferDecoder(v.cte)
+			if len(v.want) == 0 {
+				s.Nil(d)
+				return
+			}
+			var got []byte
+			for i := 0; i < len(v.in); i++ {
+				got = d.decode(got, v.in[i])
+			}
+			s.Equal(v.want, string(d.end(got)))
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// UploadLimits restricts size and structure of request bodies, zero means unrestricted.
+// Text field is part without filename, file is part having one. Part sizes are sizes of part contents
+type UploadLimits struct {
+	MaxBodySize  int64 `json:"maxBodySize"`
+	MaxParts     int64 `json:"maxParts"`
+	MaxFieldSize int64 `json:"maxFieldSize"`
+	MaxFileSize  int64 `json:"maxFileSize"`
+}
+
+// Check returns error if body of request having header rh is too large already by Content-Length
+func (u UploadLimits) Check(rh RequestHeader) error {
+	if u.MaxBodySize > 0 && int64(rh.ContentLength) > u.MaxBodySize {
+		return fmt.Errorf("in repo.UploadLimits.Check %w: Content-Length %d exceeds %d", ErrTooLarge, rh.ContentLength, u.MaxBodySize)
+	}
+	return nil
+}
+
+// LimitBody returns reader of body which fails with ErrTooLarge if body exceeds MaxBodySize
+func (u UploadLimits) LimitBody(body io.Reader) io.Reader {
+	if u.MaxBodySize <= 0 {
+		return body
+	}
+	return &limitReader{r: body, n: u.MaxBodySize, by: "upload limits"}
+}
+
+// Watch returns reader of multipart body framed by boundary bou.
+// Reader stops at the first byte exceeding part limits and fails with ErrTooLarge since then.
+// Tested in uploadOps_test.go
+func (u UploadLimits) Watch(body io.Reader, bou Boundary) io.Reader {
+	if u.MaxParts <= 0 && u.MaxFieldSize <= 0 && u.MaxFileSize <= 0 {
+		return body
+	}
+	return &partWatcher{r: body, u: u, delim: GenBoundary(bou), m: len(Sep)} // body is considered to be preceded by CRLF
+}
+
+// states of partWatcher
+const (
+	inPreamble = iota
+	inHeader
+	inContent
+	inEpilogue
+)
+
+// partWatcher counts parts and sizes of their contents while body is passed through
+type partWatcher struct {
+	r     io.Reader
+	u     UploadLimits
+	delim []byte // delimiter with CRLF in front
+	m     int    // delimiter bytes already met
+	state int
+
+	parts  int64
+	header []byte // header of current part, with CRLF ending delimiter line in front
+	file   bool   // current part is file
+	size   int64  // content bytes of current part, delimiter bytes met are counted too
+	err    error
+}
+
+func (p *partWatcher) Read(b []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	n, err := p.r.Read(b)
+
+	for i := 0; i < n && p.state != inEpilogue; i++ {
+		if p.err = p.step(b[i]); p.err != nil {
+			return i, p.err
+		}
+	}
+	return n, err
+}
+
+// step moves p by byte c, returns error if c exceeds limits
+func (p *partWatcher) step(c byte) error {
+	if p.state == inHeader {
+		p.header = append(p.header, c)
+		switch {
+		case bytes.Equal(p.header, []byte("--")): // closing delimiter
+			p.state = inEpilogue
+		case len(p.header) == 2:
+			p.parts++
+			if p.u.MaxParts > 0 && p.parts > p.u.MaxParts {
+				return fmt.Errorf("in repo.partWatcher %w: body has more than %d parts", ErrTooLarge, p.u.MaxParts)
+			}
+		case len(p.header) >= 2*len(Sep) && bytes.HasSuffix(p.header, []byte(Sep+Sep)):
+			p.state, p.file, p.size = inContent, isFile(p.header), 0
+		case len(p.header) > MaxHeaderLimit*4: // malformed header is left to Slicer
+			p.state, p.size = inContent, 0
+		}
+		return nil
+	}
+
+	switch {
+	case c == p.delim[p.m]:
+		p.m++
+	case c == p.delim[0]:
+		p.m = 1
+	default:
+		p.m = 0
+	}
+	if p.state == inContent {
+		p.size++
+	}
+
+	if p.m == len(p.delim) { // next part or closing delimiter begins
+		p.state, p.m, p.header = inHeader, 0, p.header[:0]
+		return nil
+	}
+	if p.state != inContent {
+		return nil
+	}
+
+	switch size := p.size - int64(p.m); {
+	case p.file && p.u.MaxFileSize > 0 && size > p.u.MaxFileSize:
+		return fmt.Errorf("in repo.partWatcher %w: file of part %d exceeds %d bytes", ErrTooLarge, p.parts, p.u.MaxFileSize)
+	case !p.file && p.u.MaxFieldSize > 0 && size > p.u.MaxFieldSize:
+		return fmt.Errorf("in repo.partWatcher %w: text field of part %d exceeds %d bytes", ErrTooLarge, p.parts, p.u.MaxFieldSize)
+	}
+	return nil
+}
+
+// isFile returns true if part header h has Content-Disposition with filename
+func isFile(h []byte) bool {
+	for _, l := range strings.Split(string(h), Sep) {
+		k, v, ok := strings.Cut(l, ":")
+		if !ok || textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k)) != "Content-Disposition" {
+			continue
+		}
+		if _, params, err := mime.ParseMediaType(v); err == nil {
+			_, ok = params["filename"]
+			return ok
+		}
+		return strings.Contains(strings.ToLower(v), "filename")
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type uploadOpsSuite struct {
+	suite.Suite
+}
+
+func TestUploadOpsSuite(t *testing.T) {
+	suite.Run(t, new(uploadOpsSuite))
+}
+
+func (s *uploadOpsSuite) TestWatch() {
+	bou := Boundary{Prefix: []byte("--"), Root: []byte("azaza")}
+	body := "preamble\r\n" +
+		"--azaza\r\n" +
+		"Content-Disposition: form-data; name=\"alice\"\r\n" +
+		"\r\n" +
+		"12345\r\n" +
+		"--azaza\r\n" +
+		"Content-Disposition: form-data; name=\"bob\"; filename=\"short.txt\"\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"1234567890\r\n" +
+		"--azaza--\r\n" +
+		"epilogue"
+
+	tt := []struct {
+		name     string
+		u        UploadLimits
+		body     string
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name:     "unrestricted",
+			body:     body,
+			wantBody: body,
+		},
+
+		{
+			name:     "limits are met exactly",
+			u:        UploadLimits{MaxBodySize: int64(len(body)), MaxParts: 2, MaxFieldSize: 5, MaxFileSize: 10},
+			body:     body,
+			wantBody: body,
+		},
+
+		{
+			name:     "partial delimiter in content is counted",
+			u:        UploadLimits{MaxFieldSize: 9},
+			body:     "--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\n12\r\n--aza\r\n--azaza--",
+			wantBody: "--azaza\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\n12\r\n--aza\r\n--azaza--",
+		},
+
+		{
+			name:     "unhappy body size",
+			u:        UploadLimits{MaxBodySize: 10},
+			body:     body,
+			wantBody: body[:10],
+			wantErr:  ErrTooLarge,
+		},
+
+		{
+			name:     "unhappy number of parts",
+			u:        UploadLimits{MaxParts: 1},
+			body:     body,
+			wantBody: body[:strings.Index(body, "--azaza\r\nContent-Disposition: form-data; name=\"bob\"")+len("--azaza\r")],
+			wantErr:  ErrTooLarge,
+		},
+
+		{
+			name:     "unhappy text field size",
+			u:        UploadLimits{MaxFieldSize: 4},
+			body:     body,
+			wantBody: body[:strings.Index(body, "12345")+4],
+			wantErr:  ErrTooLarge,
+		},
+
+		{
+			name:     "unhappy file size, text field is not a file",
+			u:        UploadLimits{MaxFileSize: 9},
+			body:     body,
+			wantBody: body[:strings.Index(body, "1234567890")+9],
+			wantErr:  ErrTooLarge,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			got, err := io.ReadAll(v.u.Watch(v.u.LimitBody(iotest.HalfReader(strings.NewReader(v.body))), bou))
+			s.Equal(v.wantBody, string(got))
+			if v.wantErr != nil {
+				s.True(errors.Is(err, v.wantErr), err)
+				return
+			}
+			s.NoError(err)
+		})
+	}
+}
+
+func (s *uploadOpsSuite) TestIsFile() {
+	tt := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{
+			name:   "text field",
+			header: "\r\nContent-Disposition: form-data; name=\"alice\"\r\n\r\n",
+		},
+
+		{
+			name:   "file",
+			header: "\r\ncontent-disposition: form-data; name=\"bob\"; filename=\"short.txt\"\r\n\r\n",
+			want:   true,
+		},
+
+		{
+			name:   "extended filename",
+			header: "\r\nContent-Disposition: form-data; name=\"bob\"; filename*=UTF-8''%D1%84.txt\r\n\r\n",
+			want:   true,
+		},
+
+		{
+			name:   "malformed disposition having filename",
+			header: "\r\nContent-Disposition: form-data; name=bob\"; filename=\"short.txt\r\n\r\n",
+			want:   true,
+		},
+	}
+	for _, v := range tt {
+		s.Run(v.name, func() {
+			s.Equal(v.want, isFile([]byte(v.header)))
+		})
+	}
+}